@@ -1,14 +1,24 @@
 package main
 
 import (
+	"context"
 	"log"
+	"strings"
 
 	"dongwai_backend/internal/config"
 	"dongwai_backend/internal/handler"
 	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/ai"
 	"dongwai_backend/internal/pkg/auth"
 	"dongwai_backend/internal/pkg/cache"
+	"dongwai_backend/internal/pkg/disambig"
+	"dongwai_backend/internal/pkg/enrich"
+	"dongwai_backend/internal/pkg/gloss"
+	"dongwai_backend/internal/pkg/grammar"
 	"dongwai_backend/internal/pkg/middleware"
+	"dongwai_backend/internal/pkg/prompt"
+	"dongwai_backend/internal/pkg/textnorm"
+	"dongwai_backend/internal/pkg/usage"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/postgres"
@@ -32,13 +42,38 @@ func main() {
 		&model.Vocab{},
 		&model.VocabSense{},
 		&model.SenseExample{},
-		&model.Vocabulary{},     // 词书表
-		&model.VocabularyWord{}, // 词书-单词关联表
+		&model.Vocabulary{},          // 词书表
+		&model.VocabularyWord{},      // 词书-单词关联表
+		&model.VocabSection{},        // 词书分组 (章节 / 课)
+		&model.DisambigResult{},      // AI 消歧结果缓存
+		&model.DisambigResultSense{}, // 消歧缓存-候选释义关联表
+		&model.AIUsage{},             // AI 调用用量记录
+		&model.AIQuota{},             // 用户每日 token 配额
+		&model.AIDailyUsage{},        // 用户每日已用 token (配额预留)
+		&model.EnrichJob{},           // 批量补全任务
+		&model.EnrichItem{},          // 批量补全队列
+		&model.WordDraft{},           // 待审核的单词草稿
+		&model.SentenceGloss{},       // 逐句翻译缓存
+		&model.GrammarPattern{},      // 语法点
+		&model.GrammarExample{},      // 语法点例句
+		&model.Article{},             // 文章库
+		&model.ArticleAnalysis{},     // 文章分析快照
+		&model.ArticleOverride{},     // 教师修正
 	)
 	if err != nil {
 		log.Fatal("表结构迁移失败: ", err)
 	}
 
+	// 文本规范化选项 (词典索引与文章分析共用，必须在加载词典前设置)
+	textnorm.SetDefault(textnorm.ParseOptions(config.AppConfig.TEXT_NORMALIZE))
+
+	// 单词搜索用的规范化词面 (旧数据补齐；规范化选项变更后重算)
+	if n, err := handler.SyncKanjiNorm(db); err != nil {
+		log.Fatal("规范化词面更新失败: ", err)
+	} else if n > 0 {
+		log.Printf("已更新 %d 个单词的规范化词面", n)
+	}
+
 	// 初始化词典缓存
 	log.Println("正在加载词典缓存...")
 	if err := cache.InitDictCache(db); err != nil {
@@ -46,13 +81,68 @@ func main() {
 	}
 	log.Println("✅ 词典缓存加载完毕")
 
+	// 消歧结果缓存 (内存 LRU + 数据库)
+	disambig.Init(db)
+
+	// 逐句翻译缓存
+	gloss.Init(db)
+
+	// 语法点匹配器
+	if err := grammar.Reload(db); err != nil {
+		log.Fatal("语法点加载失败: ", err)
+	}
+
+	// 提示词模板 (内置默认 + 覆盖目录)
+	prompts, err := prompt.Load(config.AppConfig.PROMPT_DIR, prompt.ParsePins(config.AppConfig.PROMPT_VERSIONS))
+	if err != nil {
+		log.Fatal("提示词加载失败: ", err)
+	}
+	prompt.SetDefault(prompts)
+
+	// AI 服务 (未配置 Key 时为 nil，相关功能降级)
+	var provider ai.Provider
+	if config.AppConfig.DEEPSEEK_API_KEY != "" {
+		client := ai.NewOpenAI(ai.OpenAIConfig{
+			BaseURL: config.AppConfig.DEEPSEEK_BASE_URL,
+			APIKey:  config.AppConfig.DEEPSEEK_API_KEY,
+			Model:   config.AppConfig.DEEPSEEK_MODEL,
+		})
+		// 每次实际请求都记账并检查配额，外层负责重试 + 退避 + 熔断
+		metered := usage.NewMeter(client, usage.NewDBStore(db), int64(config.AppConfig.AI_DAILY_TOKEN_QUOTA))
+		provider = ai.NewResilient(metered, ai.ResilienceConfig{})
+	}
+	pricing := usage.Pricing{
+		PromptPerM:     config.AppConfig.AI_PRICE_PROMPT_PER_M,
+		CompletionPerM: config.AppConfig.AI_PRICE_COMPLETION_PER_M,
+	}
+	chunkOpts := ai.ChunkOptions{
+		ChunkSize:   config.AppConfig.AI_CHUNK_SIZE,
+		Concurrency: config.AppConfig.AI_CONCURRENCY,
+	}
+
+	// 批量补全队列 (未配置 AI 时不启动 worker，任务也无法创建)
+	var enrichRunner *enrich.Runner
+	if provider != nil {
+		enrichRunner = enrich.NewRunner(db, provider, enrich.Config{
+			Workers:     config.AppConfig.ENRICH_WORKERS,
+			MaxAttempts: config.AppConfig.ENRICH_MAX_ATTEMPTS,
+		})
+		enrichRunner.Start(context.Background())
+	}
+
 	// 配置路由
 	r := gin.Default()
+	// 匿名访问按客户端 IP 记账与限额，只采信来自已配置代理的 X-Forwarded-For
+	proxies := strings.FieldsFunc(config.AppConfig.TRUSTED_PROXIES, func(r rune) bool { return r == ',' || r == ' ' })
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		log.Fatal("TRUSTED_PROXIES 配置有误: ", err)
+	}
 
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, PATCH") // 增加了 PATCH
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Last-Event-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Stream-ID")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -61,34 +151,112 @@ func main() {
 	})
 
 	r.POST("/login", handler.Login(db))
+	r.GET("/health", handler.Health(provider))
 
 	api := r.Group("/api")
 	{
-		api.POST("/analyze", handler.AnalyzeArticle(db))
+		// 分析接口允许匿名访问；携带 Token 时用量记在该用户名下
+		api.POST("/analyze", middleware.OptionalJWT(), handler.AnalyzeArticle(db, provider, chunkOpts))
+		api.GET("/analyze/stream/:id", handler.ResumeAnalyzeStream()) // 断线续传
+		api.POST("/analyze/profile", handler.AnalyzeProfile(db))      // 难度画像 (不调用 AI)
 
 		authorized := api.Group("/")
 		authorized.Use(middleware.JWTAuth())
 		{
 			// === 单词管理 ===
-			authorized.POST("/word/generate", handler.GenerateWordInfoHandler()) // ✅ 新增 AI 生成接口
+			authorized.POST("/word/generate", handler.GenerateWordInfoHandler(provider)) // ✅ 新增 AI 生成接口
 			authorized.POST("/word", handler.CreateWord(db))
 			authorized.PUT("/word", handler.UpdateWord(db))
 			authorized.DELETE("/word/:id", handler.DeleteWord(db))
 			authorized.POST("/word/list", handler.ListWords(db))
 			authorized.POST("/word/detail", handler.GetWordDetail(db))
 
+			// === 语法点管理 ===
+			authorized.POST("/grammar", handler.CreateGrammar(db))
+			authorized.PUT("/grammar", handler.UpdateGrammar(db))
+			authorized.DELETE("/grammar/:id", handler.DeleteGrammar(db))
+			authorized.POST("/grammar/list", handler.ListGrammar(db))
+			authorized.POST("/grammar/detail", handler.GetGrammarDetail(db))
+
+			// === 文章库 ===
+			authorized.POST("/articles", handler.CreateArticle(db, provider, chunkOpts))
+			authorized.GET("/articles", handler.ListArticles(db))
+			authorized.GET("/articles/:id", handler.GetArticle(db))
+			authorized.PUT("/articles/:id", handler.UpdateArticle(db))
+			authorized.DELETE("/articles/:id", handler.DeleteArticle(db))
+			authorized.POST("/articles/:id/analyze", handler.ReanalyzeArticle(db, provider, chunkOpts))
+			authorized.GET("/articles/:id/analyses", handler.ListArticleAnalyses(db))
+
+			// 教师修正单词释义 (仅文章作者或管理员；重新分析后原文未变的位置继续生效，修改正文时随改动平移)
+			teacher := authorized.Group("/articles/:id/overrides")
+			teacher.Use(middleware.RequireRole(auth.Teacher, auth.Admin, auth.SuperAdmin))
+			{
+				teacher.PUT("", handler.SetArticleOverride(db))
+				teacher.DELETE("/:start", handler.DeleteArticleOverride(db))
+			}
+
 			// === ✅ 词书管理 ===
-			// 创建自定义词书 (导入逗号分隔的字符串)
-			authorized.POST("/vocab-book", handler.CreateCustomVocabulary(db))
+			// 创建自定义词书 (导入逗号分隔的字符串，返回逐词的导入结果，可为缺失的单词生成草稿)
+			authorized.POST("/vocab-book", handler.CreateCustomVocabulary(db, enrichRunner))
+
+			// 从分析结果 (文章或未保存的分析) 创建词书，预填选中的释义与原句
+			authorized.POST("/vocab-book/from-analysis", handler.CreateVocabularyFromAnalysis(db))
 
 			// 获取所有词书列表
 			authorized.GET("/vocab-book", handler.GetVocabBookList(db))
 
-			// 获取词书详情 (优先显示多义词)
+			// 获取词书详情 (游标分页；有分组时按分组与顺序显示，否则优先显示多义词)
 			authorized.GET("/vocab-book/:id", handler.GetVocabBookDetail(db))
 
+			// 按分组与顺序导出词书 (json / csv)
+			authorized.GET("/vocab-book/:id/export", handler.ExportVocabBook(db))
+
 			// 更新词书中某个单词选中的释义 (勾选操作)
 			authorized.PUT("/vocab-book/:id/word", handler.UpdateBookWordSense(db))
+
+			// 单词在本词书中的自定义释义、备注与例句 (不修改词库)
+			authorized.PUT("/vocab-book/:id/words/:vocab_id/custom", handler.UpdateBookWordCustom(db))
+
+			// 修改 / 删除词书
+			authorized.PATCH("/vocab-book/:id", handler.UpdateVocabBook(db))
+			authorized.DELETE("/vocab-book/:id", handler.DeleteVocabBook(db))
+
+			// 追加 / 移除单词 (按单词原文或 vocab_id)
+			authorized.POST("/vocab-book/:id/words", handler.AddBookWords(db))
+			authorized.DELETE("/vocab-book/:id/words", handler.RemoveBookWords(db))
+
+			// 词书分组 (章节 / 课) 与单词顺序
+			authorized.POST("/vocab-book/:id/sections", handler.CreateSection(db))
+			authorized.PUT("/vocab-book/:id/sections/order", handler.ReorderSections(db))
+			authorized.PATCH("/vocab-book/:id/sections/:sid", handler.UpdateSection(db))
+			authorized.DELETE("/vocab-book/:id/sections/:sid", handler.DeleteSection(db))
+			authorized.PUT("/vocab-book/:id/words/order", handler.ReorderWords(db))
+			authorized.POST("/vocab-book/:id/words/move", handler.MoveWords(db))
+
+			// === AI 草稿审核 ===
+			authorized.GET("/drafts", handler.ListDrafts(db))
+			authorized.GET("/drafts/:id", handler.GetDraft(db))
+			authorized.POST("/drafts/:id/approve", handler.ApproveDraft(db))
+			authorized.POST("/drafts/:id/reject", handler.RejectDraft(db))
+
+			// === 管理员 ===
+			admin := authorized.Group("/admin")
+			admin.Use(middleware.RequireRole(auth.Admin, auth.SuperAdmin))
+			{
+				// AI 用量报表 (按天/用户/功能汇总)
+				admin.GET("/ai-usage", handler.AIUsageReport(db, pricing))
+
+				// 提示词模板
+				admin.GET("/prompts", handler.ListPrompts())
+				admin.POST("/prompts/reload", handler.ReloadPrompts())
+
+				// 批量 AI 补全任务
+				admin.POST("/enrich/jobs", handler.CreateEnrichJob(db, provider, enrichRunner))
+				admin.GET("/enrich/jobs", handler.ListEnrichJobs(db))
+				admin.GET("/enrich/jobs/:id", handler.GetEnrichJob(db))
+				admin.GET("/enrich/jobs/:id/errors", handler.ListEnrichJobErrors(db))
+				admin.POST("/enrich/jobs/:id/cancel", handler.CancelEnrichJob(db))
+			}
 		}
 	}
 
@@ -0,0 +1,193 @@
+// prompt-eval 离线评估提示词：把固定的用例集回放给 AI 服务并打分
+//
+// 用法:
+//
+//	go run ./cmd/prompt-eval -fixtures cmd/prompt-eval/fixtures.example.json
+//	go run ./cmd/prompt-eval -prompts ./prompts -versions disambiguate=v2 -provider live
+//
+// provider=replay 时使用用例中录制的 reply 作答 (不访问网络，适合 CI)；
+// provider=live 时按 .env 配置调用真实接口。
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"dongwai_backend/internal/config"
+	"dongwai_backend/internal/pkg/ai"
+	"dongwai_backend/internal/pkg/prompt"
+)
+
+// Fixtures 用例集
+type Fixtures struct {
+	Disambiguate []DisambiguateCase `json:"disambiguate"`
+	GenerateWord []GenerateWordCase `json:"generate_word"`
+}
+
+// DisambiguateCase 消歧用例：expected 为正确释义在 options 中的下标
+type DisambiguateCase struct {
+	Word     string   `json:"word"`
+	Context  string   `json:"context"`
+	Options  []string `json:"options"`
+	Expected int      `json:"expected"`
+	Reply    string   `json:"reply,omitempty"` // 录制的 AI 回复 (replay 模式使用)
+}
+
+// GenerateWordCase 单词补全用例：结果通过校验即视为合格，reading 非空时还要求某个释义的读音与之一致
+type GenerateWordCase struct {
+	Word    string `json:"word"`
+	Reading string `json:"reading,omitempty"`
+	Reply   string `json:"reply,omitempty"`
+}
+
+// caseID 用例中的 WordID
+const caseID = "1"
+
+func main() {
+	fixturesPath := flag.String("fixtures", "cmd/prompt-eval/fixtures.example.json", "用例集 JSON 文件")
+	promptDir := flag.String("prompts", "", "提示词覆盖目录 (可选)")
+	versions := flag.String("versions", "", "固定提示词版本，如 disambiguate=v2 (可选)")
+	mode := flag.String("provider", "replay", "replay (使用录制的回复) 或 live (调用真实接口)")
+	minAccuracy := flag.Float64("min", 0, "消歧准确率低于该值时以非零状态退出 (0-1)")
+	verbose := flag.Bool("v", false, "打印每个未通过的用例")
+	flag.Parse()
+
+	raw, err := os.ReadFile(*fixturesPath)
+	if err != nil {
+		log.Fatalf("❌ 读取用例失败: %v", err)
+	}
+	var fx Fixtures
+	if err := json.Unmarshal(raw, &fx); err != nil {
+		log.Fatalf("❌ 解析用例失败: %v", err)
+	}
+
+	lib, err := prompt.Load(*promptDir, prompt.ParsePins(*versions))
+	if err != nil {
+		log.Fatalf("❌ 加载提示词失败: %v", err)
+	}
+	prompt.SetDefault(lib)
+
+	var live ai.Provider
+	switch *mode {
+	case "replay":
+	case "live":
+		config.LoadConfig()
+		if config.AppConfig.DEEPSEEK_API_KEY == "" {
+			log.Fatal("❌ live 模式需要配置 DEEPSEEK_API_KEY")
+		}
+		live = ai.NewResilient(ai.NewOpenAI(ai.OpenAIConfig{
+			BaseURL: config.AppConfig.DEEPSEEK_BASE_URL,
+			APIKey:  config.AppConfig.DEEPSEEK_API_KEY,
+			Model:   config.AppConfig.DEEPSEEK_MODEL,
+		}), ai.ResilienceConfig{})
+	default:
+		log.Fatalf("❌ 未知的 provider: %s", *mode)
+	}
+
+	// 每个用例使用独立的 provider：replay 模式下只应答该用例录制的回复
+	providerFor := func(reply string) ai.Provider {
+		if live != nil {
+			return live
+		}
+		return ai.NewFake(ai.FakeReply{Content: reply})
+	}
+
+	ctx := context.Background()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	// 1. 消歧
+	correct, failed := 0, 0
+	version := ""
+	for _, tc := range fx.Disambiguate {
+		choices, v, err := ai.BatchDisambiguate(ctx, providerFor(tc.Reply), []ai.Candidate{{
+			WordID:   caseID,
+			WordText: tc.Word,
+			Context:  tc.Context,
+			Options:  tc.Options,
+		}})
+		version = v
+		switch got, ok := choices[caseID]; {
+		case err != nil:
+			failed++
+			if *verbose {
+				fmt.Fprintf(w, "✗ 消歧\t%s\t错误: %v\n", tc.Word, err)
+			}
+		case ok && got == tc.Expected:
+			correct++
+		case *verbose:
+			fmt.Fprintf(w, "✗ 消歧\t%s\t期望 %d，得到 %s\n", tc.Word, tc.Expected, describeChoice(got, ok))
+		}
+	}
+
+	// 2. 单词补全
+	valid, readingOK, genFailed := 0, 0, 0
+	genVersion := ""
+	for _, tc := range fx.GenerateWord {
+		data, warnings, err := ai.GenerateWordInfo(ctx, providerFor(tc.Reply), tc.Word)
+		if err != nil {
+			genFailed++
+			if *verbose {
+				fmt.Fprintf(w, "✗ 补全\t%s\t错误: %v\n", tc.Word, err)
+			}
+			continue
+		}
+		genVersion = data.PromptVersion
+		if len(warnings) == 0 {
+			valid++
+		} else if *verbose {
+			codes := make([]string, 0, len(warnings))
+			for _, warn := range warnings {
+				codes = append(codes, warn.Field+":"+warn.Code)
+			}
+			fmt.Fprintf(w, "✗ 补全\t%s\t校验未通过: %s\n", tc.Word, strings.Join(codes, ", "))
+		}
+		if tc.Reading == "" || hasReading(data, tc.Reading) {
+			readingOK++
+		} else if *verbose {
+			fmt.Fprintf(w, "✗ 补全\t%s\t缺少读音 %s\n", tc.Word, tc.Reading)
+		}
+	}
+	w.Flush()
+
+	fmt.Println()
+	fmt.Fprintln(w, "功能\t提示词版本\t用例\t通过\t出错\t得分")
+	accuracy := ratio(correct, len(fx.Disambiguate))
+	fmt.Fprintf(w, "disambiguate\t%s\t%d\t%d\t%d\t%.1f%%\n", version, len(fx.Disambiguate), correct, failed, accuracy*100)
+	fmt.Fprintf(w, "generate_word (校验)\t%s\t%d\t%d\t%d\t%.1f%%\n", genVersion, len(fx.GenerateWord), valid, genFailed, ratio(valid, len(fx.GenerateWord))*100)
+	fmt.Fprintf(w, "generate_word (读音)\t%s\t%d\t%d\t%d\t%.1f%%\n", genVersion, len(fx.GenerateWord), readingOK, genFailed, ratio(readingOK, len(fx.GenerateWord))*100)
+	w.Flush()
+
+	if len(fx.Disambiguate) > 0 && accuracy < *minAccuracy {
+		fmt.Printf("❌ 消歧准确率 %.1f%% 低于要求的 %.1f%%\n", accuracy*100, *minAccuracy*100)
+		os.Exit(1)
+	}
+}
+
+func describeChoice(got int, ok bool) string {
+	if !ok {
+		return "无结果"
+	}
+	return fmt.Sprint(got)
+}
+
+func hasReading(data *ai.GeneratedWordData, reading string) bool {
+	for _, s := range data.Senses {
+		if s.Reading == reading {
+			return true
+		}
+	}
+	return false
+}
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
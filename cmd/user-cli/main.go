@@ -10,6 +10,7 @@ import (
 
 	"dongwai_backend/internal/config"
 	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/usage"
 	"dongwai_backend/internal/pkg/utils"
 
 	"github.com/google/uuid"
@@ -38,6 +39,8 @@ func main() {
 	listCmd := flag.NewFlagSet("list", flag.ExitOnError)
 	pwdCmd := flag.NewFlagSet("pwd", flag.ExitOnError)
 	delCmd := flag.NewFlagSet("del", flag.ExitOnError)
+	usageCmd := flag.NewFlagSet("usage", flag.ExitOnError)
+	quotaCmd := flag.NewFlagSet("quota", flag.ExitOnError)
 
 	// add 子命令参数
 	addName := addCmd.String("u", "", "用户名 (必须)")
@@ -51,6 +54,18 @@ func main() {
 	// del 子命令参数
 	delName := delCmd.String("u", "", "要删除的用户名 (必须)")
 
+	// usage 子命令参数
+	usageFrom := usageCmd.String("from", time.Now().AddDate(0, 0, -6).Format(time.DateOnly), "开始日期 (YYYY-MM-DD)")
+	usageTo := usageCmd.String("to", time.Now().Format(time.DateOnly), "结束日期 (YYYY-MM-DD，包含)")
+	usageBy := usageCmd.String("by", "day", "分组维度 (day,user,feature 任意组合)")
+	usageName := usageCmd.String("u", "", "只看某个用户 (可选)")
+	usageFeature := usageCmd.String("f", "", "只看某个功能 (可选: disambiguate/generate_word/translate)")
+
+	// quota 子命令参数
+	quotaName := quotaCmd.String("u", "", "用户名 (必须)")
+	quotaTokens := quotaCmd.Int64("t", -1, "每日 token 配额 (0 表示不限)")
+	quotaClear := quotaCmd.Bool("clear", false, "删除单独配额，恢复使用全局默认值")
+
 	if len(os.Args) < 2 {
 		printUsage()
 		os.Exit(1)
@@ -90,6 +105,19 @@ func main() {
 		}
 		handleDelete(*delName)
 
+	case "usage":
+		usageCmd.Parse(os.Args[2:])
+		handleUsage(*usageFrom, *usageTo, *usageBy, *usageName, *usageFeature)
+
+	case "quota":
+		quotaCmd.Parse(os.Args[2:])
+		if *quotaName == "" || (*quotaTokens < 0 && !*quotaClear) {
+			fmt.Println("❌ 错误: 必须提供用户名 (-u) 以及配额 (-t) 或 -clear")
+			quotaCmd.PrintDefaults()
+			os.Exit(1)
+		}
+		handleQuota(*quotaName, *quotaTokens, *quotaClear)
+
 	default:
 		printUsage()
 		os.Exit(1)
@@ -102,6 +130,8 @@ func printUsage() {
 	fmt.Println("  list  - 列出所有用户")
 	fmt.Println("  pwd   - 重置用户密码 (例如: user-cli pwd -u admin -p newpass)")
 	fmt.Println("  del   - 删除用户 (例如: user-cli del -u admin)")
+	fmt.Println("  usage - AI 用量报表 (例如: user-cli usage -from 2024-05-01 -by day,user)")
+	fmt.Println("  quota - 设置用户每日 token 配额 (例如: user-cli quota -u admin -t 200000)")
 }
 
 // --- 处理函数 ---
@@ -169,3 +199,81 @@ func handleDelete(username string) {
 	}
 	fmt.Printf("🗑️  用户 '%s' 已删除\n", username)
 }
+
+func handleUsage(fromStr, toStr, by, username, feature string) {
+	from, err := time.ParseInLocation(time.DateOnly, fromStr, time.Local)
+	if err != nil {
+		log.Fatalf("❌ 开始日期格式错误: %v", err)
+	}
+	to, err := time.ParseInLocation(time.DateOnly, toStr, time.Local)
+	if err != nil {
+		log.Fatalf("❌ 结束日期格式错误: %v", err)
+	}
+	groupBy, err := usage.ParseGroupBy(by)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	query := usage.ReportQuery{From: from, To: to.AddDate(0, 0, 1), GroupBy: groupBy, Feature: feature}
+	if username != "" {
+		query.UserID = lookupUserID(username)
+	}
+
+	pricing := usage.Pricing{
+		PromptPerM:     config.AppConfig.AI_PRICE_PROMPT_PER_M,
+		CompletionPerM: config.AppConfig.AI_PRICE_COMPLETION_PER_M,
+	}
+	rows, err := usage.Report(db, query, pricing)
+	if err != nil {
+		log.Fatalf("查询失败: %v", err)
+	}
+
+	fmt.Printf("\n📊 AI 用量 (%s ~ %s):\n", fromStr, toStr)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "日期\t用户\t功能\t调用\t失败\t拒绝\t输入\t输出\t合计\t平均耗时(ms)\t费用")
+	var total usage.ReportRow
+	for _, r := range rows {
+		user := r.Username
+		if user == "" {
+			user = r.UserID
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%.0f\t%.4f\n",
+			r.Day, user, r.Feature, r.Calls, r.Errors, r.Rejected,
+			r.PromptTokens, r.CompletionTokens, r.TotalTokens, r.AvgLatencyMs, r.Cost)
+		total.Calls += r.Calls
+		total.TotalTokens += r.TotalTokens
+		total.Cost += r.Cost
+	}
+	w.Flush()
+	fmt.Printf("\n合计: %d 次调用, %d tokens, 费用 %.4f\n\n", total.Calls, total.TotalTokens, total.Cost)
+}
+
+func handleQuota(username string, tokens int64, clear bool) {
+	userID := lookupUserID(username)
+	if clear {
+		if err := usage.ClearQuota(db, userID); err != nil {
+			log.Fatalf("删除失败: %v", err)
+		}
+		fmt.Printf("✅ 用户 '%s' 已恢复默认配额 (%d)\n", username, config.AppConfig.AI_DAILY_TOKEN_QUOTA)
+		return
+	}
+
+	if err := usage.SetQuota(db, userID, tokens); err != nil {
+		log.Fatalf("设置失败: %v", err)
+	}
+	if tokens == 0 {
+		fmt.Printf("✅ 用户 '%s' 的每日配额已设为不限\n", username)
+		return
+	}
+	fmt.Printf("✅ 用户 '%s' 的每日配额已设为 %d tokens\n", username, tokens)
+}
+
+// lookupUserID 用户名 -> ID，不存在时退出
+func lookupUserID(username string) string {
+	var user model.UserRole
+	if err := db.Select("id").Where("username = ?", username).First(&user).Error; err != nil {
+		fmt.Printf("❌ 未找到用户 '%s'\n", username)
+		os.Exit(1)
+	}
+	return user.ID
+}
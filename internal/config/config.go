@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -11,8 +12,23 @@ type Config struct {
 	DB_DSN            string
 	JWT_SECRET        string
 	PORT              string
+	TRUSTED_PROXIES   string // 信任的反向代理 (逗号分隔的 IP / CIDR)，只有来自它们的 X-Forwarded-For 才会被采信
 	DEEPSEEK_API_KEY  string // 新增
 	DEEPSEEK_BASE_URL string // 新增
+	DEEPSEEK_MODEL    string // 模型名称 (OpenAI 兼容接口)
+	TEXT_NORMALIZE    string // 文本规范化选项: all / none / width,iteration,variant,longvowel
+	AI_CHUNK_SIZE     int    // 消歧时每个请求最多的候选词数
+	AI_CONCURRENCY    int    // 消歧时同时进行的请求数
+
+	PROMPT_DIR      string // 提示词覆盖目录 (可选)，文件名形如 disambiguate.v2.tmpl
+	PROMPT_VERSIONS string // 固定提示词版本 (可选)，如 disambiguate=v1,generate_word=v2
+
+	AI_DAILY_TOKEN_QUOTA      int     // 每个用户每日 token 配额的默认值，0 表示不限
+	AI_PRICE_PROMPT_PER_M     float64 // 每百万输入 token 的价格 (用于估算费用)
+	AI_PRICE_COMPLETION_PER_M float64 // 每百万输出 token 的价格
+
+	ENRICH_WORKERS      int // 批量补全的 worker 数
+	ENRICH_MAX_ATTEMPTS int // 批量补全时每个单词最多尝试次数
 }
 
 var AppConfig *Config
@@ -24,8 +40,23 @@ func LoadConfig() {
 		DB_DSN:            getEnv("DB_DSN", "host=localhost user=postgres password=postgres dbname=postgres port=5432 sslmode=disable TimeZone=Asia/Shanghai"),
 		JWT_SECRET:        getEnv("JWT_SECRET", "default_secret"),
 		PORT:              getEnv("PORT", "8080"),
+		TRUSTED_PROXIES:   getEnv("TRUSTED_PROXIES", ""),
 		DEEPSEEK_API_KEY:  getEnv("DEEPSEEK_API_KEY", ""),
 		DEEPSEEK_BASE_URL: getEnv("DEEPSEEK_BASE_URL", "https://api.deepseek.com"), // 默认官方地址
+		DEEPSEEK_MODEL:    getEnv("DEEPSEEK_MODEL", "deepseek-chat"),
+		TEXT_NORMALIZE:    getEnv("TEXT_NORMALIZE", "all"),
+		AI_CHUNK_SIZE:     getEnvInt("AI_CHUNK_SIZE", 20),
+		AI_CONCURRENCY:    getEnvInt("AI_CONCURRENCY", 4),
+
+		PROMPT_DIR:      getEnv("PROMPT_DIR", ""),
+		PROMPT_VERSIONS: getEnv("PROMPT_VERSIONS", ""),
+
+		AI_DAILY_TOKEN_QUOTA:      getEnvInt("AI_DAILY_TOKEN_QUOTA", 0),
+		AI_PRICE_PROMPT_PER_M:     getEnvFloat("AI_PRICE_PROMPT_PER_M", 0),
+		AI_PRICE_COMPLETION_PER_M: getEnvFloat("AI_PRICE_COMPLETION_PER_M", 0),
+
+		ENRICH_WORKERS:      getEnvInt("ENRICH_WORKERS", 2),
+		ENRICH_MAX_ATTEMPTS: getEnvInt("ENRICH_MAX_ATTEMPTS", 3),
 	}
 
 	if AppConfig.DEEPSEEK_API_KEY == "" {
@@ -39,3 +70,29 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("⚠️ %s=%q 不是非负整数，使用默认值 %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		log.Printf("⚠️ %s=%q 不是非负数，使用默认值 %g", key, value, fallback)
+		return fallback
+	}
+	return f
+}
@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"dongwai_backend/internal/model"
+)
+
+// ========================================
+// 语法点相关 DTO
+// ========================================
+
+// GrammarDTO 完整语法点信息
+type GrammarDTO struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Pattern     string       `json:"pattern"`
+	Level       string       `json:"level"`
+	Meaning     string       `json:"meaning"`
+	Explanation string       `json:"explanation"`
+	Examples    []ExampleDTO `json:"examples"`
+}
+
+// ToGrammarDTO 将 model.GrammarPattern 转换为 GrammarDTO
+func ToGrammarDTO(g model.GrammarPattern) GrammarDTO {
+	examples := make([]ExampleDTO, 0, len(g.Examples))
+	for _, ex := range g.Examples {
+		examples = append(examples, ExampleDTO{
+			Kanji:    ex.Kanji,
+			Def:      ex.Def,
+			Furigana: ex.Furigana,
+		})
+	}
+
+	return GrammarDTO{
+		ID:          g.ID,
+		Name:        g.Name,
+		Pattern:     g.Pattern,
+		Level:       g.Level,
+		Meaning:     g.Meaning,
+		Explanation: g.Explanation,
+		Examples:    examples,
+	}
+}
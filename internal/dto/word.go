@@ -1,6 +1,8 @@
 package dto
 
 import (
+	"encoding/json"
+
 	"dongwai_backend/internal/model"
 )
 
@@ -34,6 +36,8 @@ type SenseDTO struct {
 	Pitch    string       `json:"pitch"`
 	Furigana interface{}  `json:"furigana"`
 	Examples []ExampleDTO `json:"examples"`
+	// 被词书自定义释义覆盖前的词库释义 (仅词书详情中出现)
+	OriginalDef string `json:"original_def,omitempty"`
 }
 
 // ExampleDTO 例句信息
@@ -42,6 +46,7 @@ type ExampleDTO struct {
 	Def      string      `json:"def"`
 	Furigana interface{} `json:"furigana"`
 	Audio    string      `json:"audio,omitempty"`
+	Custom   bool        `json:"custom,omitempty"` // 词书中的自定义例句
 }
 
 // ========================================
@@ -129,6 +134,26 @@ type VocabBookWordDTO struct {
 	VocabID         string  `json:"vocab_id"`
 	SelectedSenseID string  `json:"selected_sense_id"` // 用户选中的义项 ID
 	Word            WordDTO `json:"word"`
+	// 从文章生成时单词所在的原句 (kanji 为原句，def 为译文)
+	Context         *ExampleDTO `json:"context,omitempty"`
+	SourceArticleID string      `json:"source_article_id,omitempty"`
+	// 所属分组 (为空表示未分组) 与在分组内的顺序
+	SectionID string `json:"section_id"`
+	Position  int    `json:"position"`
+	// 本词书中的自定义内容 (原样返回，同时已合并到 Word 中对应的释义上)
+	CustomDef      string       `json:"custom_def,omitempty"`
+	Note           string       `json:"note,omitempty"`
+	CustomExamples []ExampleDTO `json:"custom_examples,omitempty"`
+	// 自定义释义、例句所针对的释义已不是当前覆盖的释义 (被删除或单词新增了释义)，未合并到 Word 中
+	CustomDetached bool `json:"custom_detached,omitempty"`
+}
+
+// VocabSectionDTO 词书分组
+type VocabSectionDTO struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+	Count    int    `json:"count"` // 分组内的单词数
 }
 
 // ToVocabBookDTO 将 model.Vocabulary 转换为 VocabBookDTO
@@ -142,10 +167,96 @@ func ToVocabBookDTO(vocab model.Vocabulary) VocabBookDTO {
 }
 
 // ToVocabBookWordDTO 将 model.VocabularyWord 转换为 VocabBookWordDTO
+// 自定义释义替换目标释义的 def (原释义保留在 original_def)，自定义例句排在该释义的例句之前；
+// 只有目标释义正是自定义内容所针对的释义时才合并，否则标记为 custom_detached
 func ToVocabBookWordDTO(relation model.VocabularyWord) VocabBookWordDTO {
-	return VocabBookWordDTO{
+	result := VocabBookWordDTO{
 		VocabID:         relation.VocabID,
 		SelectedSenseID: relation.SenseID,
 		Word:            ToWordDTO(relation.Vocab),
+		SourceArticleID: relation.SourceArticleID,
+		SectionID:       relation.SectionID,
+		Position:        relation.Position,
+		CustomDef:       relation.CustomDef,
+		Note:            relation.Note,
+		CustomExamples:  toCustomExamples(relation.CustomExamples),
+	}
+	if relation.ContextSentence != "" {
+		result.Context = &ExampleDTO{
+			Kanji: relation.ContextSentence,
+			Def:   relation.ContextTranslation,
+		}
+	}
+
+	if !HasSenseOverride(relation) {
+		return result
+	}
+	i := BookOverrideTarget(relation.Vocab, relation.SenseID)
+	if i < 0 || relation.Vocab.Senses[i].ID != relation.CustomSenseID {
+		result.CustomDetached = true
+		return result
+	}
+	sense := &result.Word.Senses[i]
+	if relation.CustomDef != "" {
+		sense.OriginalDef = sense.Def
+		sense.Def = relation.CustomDef
+	}
+	if len(result.CustomExamples) > 0 {
+		sense.Examples = append(append([]ExampleDTO{}, result.CustomExamples...), sense.Examples...)
+	}
+	return result
+}
+
+// BookOverrideTarget 词书自定义内容覆盖的释义下标：选中的释义；未选择时只有一个释义的单词取该释义，否则为 -1
+func BookOverrideTarget(vocab model.Vocab, senseID string) int {
+	if senseID == "" {
+		if len(vocab.Senses) == 1 {
+			return 0
+		}
+		return -1
+	}
+	for i, s := range vocab.Senses {
+		if s.ID == senseID {
+			return i
+		}
+	}
+	return -1
+}
+
+// BookOverrideSense 词书自定义内容覆盖的释义 ID (见 BookOverrideTarget)，没有时为空
+func BookOverrideSense(vocab model.Vocab, senseID string) string {
+	if i := BookOverrideTarget(vocab, senseID); i >= 0 {
+		return vocab.Senses[i].ID
+	}
+	return ""
+}
+
+// HasSenseOverride 是否有需要覆盖在释义上的自定义内容 (自定义释义或例句，备注不算)
+func HasSenseOverride(relation model.VocabularyWord) bool {
+	return relation.CustomDef != "" || len(toCustomExamples(relation.CustomExamples)) > 0
+}
+
+// toCustomExamples 解析词书中的自定义例句
+func toCustomExamples(raw []byte) []ExampleDTO {
+	if len(raw) == 0 {
+		return nil
+	}
+	var examples []ExampleDTO
+	if err := json.Unmarshal(raw, &examples); err != nil {
+		return nil
+	}
+	for i := range examples {
+		examples[i].Custom = true
+	}
+	return examples
+}
+
+// ToVocabSectionDTO 将 model.VocabSection 转换为 VocabSectionDTO
+func ToVocabSectionDTO(section model.VocabSection, count int) VocabSectionDTO {
+	return VocabSectionDTO{
+		ID:       section.ID,
+		Name:     section.Name,
+		Position: section.Position,
+		Count:    count,
 	}
 }
@@ -1,13 +1,26 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"dongwai_backend/internal/model"
 	"dongwai_backend/internal/pkg/ai"
 	"dongwai_backend/internal/pkg/cache" // 引入缓存包
+	"dongwai_backend/internal/pkg/disambig"
+	"dongwai_backend/internal/pkg/grammar"
+	"dongwai_backend/internal/pkg/profile"
+	"dongwai_backend/internal/pkg/segment"
+	"dongwai_backend/internal/pkg/stream"
+	"dongwai_backend/internal/pkg/textnorm"
+	"dongwai_backend/internal/pkg/usage"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -36,15 +49,72 @@ type WordDetail struct {
 }
 
 type Token struct {
-	Text       string       `json:"text"`
-	IsWord     bool         `json:"is_word"`
-	Detail     *WordDetail  `json:"detail"`
-	Candidates []WordDetail `json:"candidates"`
+	Text        string       `json:"text"`
+	IsWord      bool         `json:"is_word"`
+	Base        string       `json:"base,omitempty"`         // 命中的词典原形 (活用形会被还原)
+	Inflection  []string     `json:"inflection,omitempty"`   // 识别出的活用链，从原形向外排列
+	MatchSource string       `json:"match_source,omitempty"` // 命中来源: surface (词面) / reading (读音)
+	Start       int          `json:"start"`                  // 原文中的 rune 起始下标
+	End         int          `json:"end"`                    // 原文中的 rune 结束下标 (不含)
+	ByteStart   int          `json:"byte_start"`             // 原文中的 UTF-8 字节起始偏移
+	ByteEnd     int          `json:"byte_end"`               // 原文中的 UTF-8 字节结束偏移 (不含)
+	Sentence    int          `json:"sentence"`               // 所在句子下标 (对应 AnalyzeResp.Sentences)
+	Paragraph   int          `json:"paragraph"`              // 所在段落下标
+	Detail      *WordDetail  `json:"detail"`
+	Candidates  []WordDetail `json:"candidates"`
+	// 多义词的消歧状态: pending / ai / cache / fallback / teacher，单义词为空
+	Disambiguation string `json:"disambiguation,omitempty"`
+}
+
+// 多义词的消歧状态 (Token.Disambiguation)
+const (
+	DisambigPending  = "pending"  // 等待 AI 结果 (流式 initial 事件)
+	DisambigAI       = "ai"       // AI 选择
+	DisambigCache    = "cache"    // 命中消歧缓存
+	DisambigFallback = "fallback" // AI 不可用或失败，退回第一个释义
+	DisambigTeacher  = "teacher"  // 教师修正 (文章库)
+)
+
+// 同步模式下 AI 阶段的状态 (AnalyzeResp.AIStatus 与 TranslateStatus)
+const (
+	StageApplied = "applied" // 全部请求成功，结果已应用
+	StageSkipped = "skipped" // 没有需要请求的内容 (没有多义词 / 没有句子)，未调用 AI
+	StagePartial = "partial" // 出错，但部分结果 (成功的分块或命中的缓存) 已应用，原因见对应的 error 字段
+	StageFailed  = "failed"  // 出错且没有应用任何结果 (含 AI 未配置)，原因见对应的 error 字段
+)
+
+// Sentence 句子信息，供前端展示逐句翻译与上下文
+type Sentence struct {
+	Index      int    `json:"index"`
+	Paragraph  int    `json:"paragraph"`
+	Text       string `json:"text"`
+	Start      int    `json:"start"`       // 原文 rune 起始下标
+	End        int    `json:"end"`         // 原文 rune 结束下标 (不含)
+	ByteStart  int    `json:"byte_start"`  // 原文字节起始偏移
+	ByteEnd    int    `json:"byte_end"`    // 原文字节结束偏移 (不含)
+	TokenStart int    `json:"token_start"` // 第一个 Token 下标
+	TokenEnd   int    `json:"token_end"`   // 最后一个 Token 下标 + 1
+	// 逐句翻译与语法说明 (请求 translate 时，仅同步模式填充；流式模式见 translation 事件)
+	Translation string `json:"translation,omitempty"`
+	GrammarNote string `json:"grammar_note,omitempty"`
 }
 
 type AnalyzeResp struct {
 	Tokens    []Token      `json:"tokens"`
+	Sentences []Sentence   `json:"sentences"`
 	VocabList []WordResult `json:"vocab_list"`
+	// 命中的语法点 (按句子与位置排序)，由选中释义的词性参与匹配
+	Grammar []grammar.Match `json:"grammar"`
+	// 难度画像 (等级取自选中的释义)
+	Profile profile.Profile `json:"profile"`
+	// 仅同步模式返回: AI 消歧状态 applied / skipped / partial / failed (见 StageApplied 等)，
+	// partial 与 failed 时 AIError 为失败原因
+	AIStatus string `json:"ai_status,omitempty"`
+	AIError  string `json:"ai_error,omitempty"`
+	// 仅同步模式且请求 translate 时返回: 逐句翻译状态，取值与含义同 AIStatus，
+	// partial 与 failed 时 TranslateError 为失败原因
+	TranslateStatus string `json:"translate_status,omitempty"`
+	TranslateError  string `json:"translate_error,omitempty"`
 }
 
 type WordResult struct {
@@ -58,184 +128,529 @@ type senseOptionRef struct {
 	Sense   model.VocabSense
 }
 
+// analysis 一次文章分析的中间结果 (分词 + 查库 + AI 候选集)，SSE 与同步模式共用
+type analysis struct {
+	tokens           []Token
+	sentences        []Sentence
+	tokenVocabIDsMap map[int][]string
+	vocabObjMap      map[string]model.Vocab
+	aiCandidates     []ai.Candidate
+	// WordID -> 消歧缓存条目 (键与候选释义 ID)
+	cacheEntries map[string]disambig.Entry
+	// WordID -> 结果来源 (DisambigAI / DisambigCache)
+	sources map[string]string
+
+	provider  ai.Provider // 为 nil 时不做 AI 消歧
+	chunkOpts ai.ChunkOptions
+	translate bool // 消歧后追加逐句翻译阶段
+}
+
+// analyzeHub 进行中与刚结束的分析事件流 (结束后保留 10 分钟供续传，无人订阅 30 秒后取消 AI)
+var analyzeHub = stream.NewHub(10*time.Minute, 30*time.Second)
+
 // AnalyzeArticle 分析文章接口
-func AnalyzeArticle(db *gorm.DB) gin.HandlerFunc {
+//
+// 同步模式: 请求头 Accept: application/json 或 ?mode=sync，等待 AI 消歧完成后一次性返回最终的 AnalyzeResp。
+//
+// 响应中的 grammar 为命中的语法点，profile 为难度画像，initial 事件中即已给出 (按默认释义计算)。
+//
+// 流式模式 (默认，SSE)，事件按顺序为:
+//
+//	initial    AnalyzeResp，未经 AI 消歧 (多义词默认选第一个释义，disambiguation = pending)
+//	progress   ProgressEvent，阶段进度 (stage = disambiguate / translate，done/total 为已完成/总分块数)
+//	ai_update  map[WordID]index，每个成功的分块一条；WordID 形如 token_<下标>，index 为候选释义下标
+//	error      ErrorEvent，某阶段 (或某个分块) 失败；已发送的结果仍然有效
+//	ai_fallback FallbackEvent，这些多义词不会再有 AI 结果，保持第一个释义
+//	profile    profile.Profile，有多义词时在消歧结束后按最终释义重新计算的难度画像
+//	translation TranslationEvent，请求 translate 时在消歧结束后发送，每个缓存命中集合或成功的分块一条
+//	done       DoneEvent，流结束，之后不会再有事件
+//
+// 每个事件都带有 id 字段 (<analysis_id>:<序号>)。断线后携带 Last-Event-ID 头重新 POST，
+// 或 GET /api/analyze/stream/:id，即可从断点续传；流结束后保留 10 分钟。
+func AnalyzeArticle(db *gorm.DB, provider ai.Provider, chunkOpts ai.ChunkOptions) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// 续传：Last-Event-ID 指向仍在保留期内的流时，直接回放
+		if streamID, seq, ok := stream.ParseEventID(c.GetHeader("Last-Event-ID")); ok {
+			if s, exists := analyzeHub.Get(streamID); exists {
+				stream.Serve(c, s, seq)
+				return
+			}
+		}
+
 		var req struct {
 			Content string `json:"content"`
+			// 分词策略: forward / backward / bidirectional / lattice (默认)
+			Strategy string `json:"strategy"`
+			// 是否追加逐句翻译与语法说明 (需要 AI)
+			Translate bool `json:"translate"`
 		}
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "请提供文章内容"})
 			return
 		}
 
-		// ==========================================
-		// 1. 使用内存缓存 (性能优化 ✅)
-		// ==========================================
-		// 不再查库，直接从 GlobalDict 获取
-		maxLen := cache.GlobalDict.MaxLen()
+		segmenter, ok := segment.ByName(req.Strategy)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的分词策略: " + req.Strategy})
+			return
+		}
+
+		a, err := newAnalysis(db, req.Content, segmenter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询词库失败"})
+			return
+		}
+		a.provider, a.chunkOpts, a.translate = provider, chunkOpts, req.Translate
 
 		// ==========================================
-		// 2. FMM (正向最大匹配) 分词
+		// 同步模式：等待 AI 完成后一次性返回
 		// ==========================================
-		runes := []rune(req.Content)
-		length := len(runes)
-		var tokens []Token
-
-		tokenVocabIDsMap := make(map[int][]string)
-		allFoundIDs := make(map[string]bool)
-
-		for i := 0; i < length; {
-			matched := false
-			limit := i + maxLen
-			if limit > length {
-				limit = length
-			}
-
-			for j := limit; j > i; j-- {
-				word := string(runes[i:j])
-				// ⚡️ 从缓存查询
-				if ids, exists := cache.GlobalDict.Get(word); exists {
-					tokenVocabIDsMap[len(tokens)] = ids
-					for _, id := range ids {
-						allFoundIDs[id] = true
-					}
-
-					tokens = append(tokens, Token{Text: word, IsWord: true})
-					i = j
-					matched = true
-					break
-				}
-			}
+		// 用量记在当前用户名下 (匿名访问按客户端 IP)
+		userID := usageUser(c)
 
-			if !matched {
-				tokens = append(tokens, Token{Text: string(runes[i : i+1]), IsWord: false})
-				i++
-			}
+		if isSyncMode(c) {
+			c.JSON(http.StatusOK, a.run(usage.WithUser(c.Request.Context(), userID)))
+			return
 		}
 
 		// ==========================================
-		// 3. 批量查询详情 (查库只查命中部分)
+		// 流式模式：后台执行，事件写入可续传的流
 		// ==========================================
-		if len(allFoundIDs) == 0 {
-			// ✅ 没有找到单词,但仍然返回 SSE 格式
-			c.Writer.Header().Set("Content-Type", "text/event-stream")
-			c.Writer.Header().Set("Cache-Control", "no-cache")
-			c.Writer.Header().Set("Connection", "keep-alive")
-			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-
-			emptyResp := AnalyzeResp{Tokens: tokens, VocabList: []WordResult{}}
-			c.SSEvent("initial", emptyResp)
-			c.Writer.Flush()
+		s, ctx := analyzeHub.Create(usage.WithUser(context.Background(), userID))
+		go runAnalysisStream(ctx, s, a)
+		stream.Serve(c, s, 0)
+	}
+}
+
+// usageUser AI 用量记账与配额使用的用户：登录用户为其 ID，匿名访问按客户端 IP 分开计算
+func usageUser(c *gin.Context) string {
+	if id := c.GetString("userID"); id != "" {
+		return id
+	}
+	return usage.AnonymousUser(c.ClientIP())
+}
+
+// run 同步执行全部阶段 (消歧与可选的逐句翻译)，返回最终结果
+func (a *analysis) run(ctx context.Context) AnalyzeResp {
+	aiResult, err := a.disambiguate(ctx)
+	resp := a.response(aiResult, true)
+	resp.AIStatus, resp.AIError = aiStatus(a, aiResult, err)
+	if a.translate {
+		glosses, total, err := a.glossSentences(ctx, resp)
+		applyGlosses(resp.Sentences, glosses)
+		resp.TranslateStatus, resp.TranslateError = translateStatus(total, glosses, err)
+	}
+	return resp
+}
+
+// ResumeAnalyzeStream 续传分析事件流
+// 序号取自 Last-Event-ID 头或 last_event_id 查询参数 (可以是完整事件 ID，也可以只是序号)
+func ResumeAnalyzeStream() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s, ok := analyzeHub.Get(c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "分析任务不存在或已过期"})
 			return
 		}
 
-		var ids []string
-		for id := range allFoundIDs {
-			ids = append(ids, id)
+		lastID := c.GetHeader("Last-Event-ID")
+		if lastID == "" {
+			lastID = c.Query("last_event_id")
+		}
+		seq := 0
+		if _, n, ok := stream.ParseEventID(lastID); ok {
+			seq = n
+		} else if n, err := strconv.Atoi(lastID); err == nil {
+			seq = n
 		}
 
-		var vocabsFull []model.Vocab
-		db.Preload("Senses").Preload("Senses.Examples").Where("id IN ?", ids).Find(&vocabsFull)
+		stream.Serve(c, s, seq)
+	}
+}
+
+// ProgressEvent 阶段进度
+type ProgressEvent struct {
+	Stage string `json:"stage"`
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+}
+
+// ErrorEvent 阶段失败
+type ErrorEvent struct {
+	Stage   string `json:"stage"`
+	Message string `json:"message"`
+	// 失败的分块下标及其包含的 WordID / 句子下标 (仅分块阶段)
+	Chunk     *int     `json:"chunk,omitempty"`
+	Words     []string `json:"words,omitempty"`
+	Sentences []int    `json:"sentences,omitempty"`
+}
+
+// FallbackEvent 消歧降级：这些 WordID 保持第一个释义
+type FallbackEvent struct {
+	Words  []string `json:"words"`
+	Reason string   `json:"reason"`
+}
+
+// DoneEvent 流结束
+type DoneEvent struct {
+	AnalysisID string `json:"analysis_id"`
+}
+
+// 分析阶段
+const (
+	stageDisambiguate = "disambiguate"
+	stageTranslate    = "translate"
+)
+
+// runAnalysisStream 在后台依次执行各阶段并发布事件
+func runAnalysisStream(ctx context.Context, s *stream.Stream, a *analysis) {
+	defer s.Close()
+	defer s.Publish("done", DoneEvent{AnalysisID: s.ID})
+
+	// 构建初始响应（不带 AI 结果，默认选第一个）
+	s.Publish("initial", a.response(nil, false))
+
+	// 后台执行 AI 消歧并推送更新
+	aiResult := streamDisambiguation(ctx, s, a)
+	final := a.response(aiResult, true)
+	if len(a.aiCandidates) > 0 {
+		s.Publish("profile", final.Profile)
+	}
+	if a.translate {
+		streamTranslation(ctx, s, a, final)
+	}
+}
+
+// streamDisambiguation 消歧阶段：先推送缓存结果，再逐块推送 AI 结果；返回合并后的全部结果
+func streamDisambiguation(ctx context.Context, s *stream.Stream, a *analysis) map[string]int {
+	merged := make(map[string]int)
+	if len(a.aiCandidates) == 0 {
+		return merged
+	}
+
+	// 命中消歧缓存的结果先推送，只有未命中的候选需要请求 AI
+	cached := a.cachedChoices(ctx)
+	pending := a.pendingCandidates(cached)
+	total := len(ai.ChunkCandidates(pending, a.chunkOpts.ChunkSize))
+	s.Publish("progress", ProgressEvent{Stage: stageDisambiguate, Done: 0, Total: total})
 
-		vocabObjMap := make(map[string]model.Vocab)
-		for _, v := range vocabsFull {
-			vocabObjMap[v.ID] = v
+	if len(cached) > 0 {
+		s.Publish("ai_update", cached)
+	}
+	for k, v := range cached {
+		merged[k] = v
+	}
+	if len(pending) == 0 {
+		return merged
+	}
+	if a.provider == nil {
+		s.Publish("error", ErrorEvent{Stage: stageDisambiguate, Message: ai.ErrNotConfigured.Error()})
+		s.Publish("ai_fallback", FallbackEvent{Words: wordIDs(pending), Reason: ai.ErrNotConfigured.Error()})
+		return merged
+	}
+
+	// ✅ 传递上下文，客户端放弃后取消耗时的 AI 操作；每完成一块推送一次
+	done := 0
+	result, _ := a.disambiguateCandidates(ctx, pending, func(res ai.ChunkResult) {
+		done++
+		if res.Err != nil {
+			idx := res.Index
+			s.Publish("error", ErrorEvent{Stage: stageDisambiguate, Message: res.Err.Error(), Chunk: &idx, Words: res.Words})
+			s.Publish("ai_fallback", FallbackEvent{Words: res.Words, Reason: res.Err.Error()})
+		} else {
+			s.Publish("ai_update", res.Result)
 		}
+		s.Publish("progress", ProgressEvent{Stage: stageDisambiguate, Done: done, Total: res.Total})
+	})
+	for k, v := range result {
+		merged[k] = v
+	}
+	return merged
+}
 
-		// ==========================================
-		// 4. 准备 AI 消歧候选集 (逻辑不变)
-		// ==========================================
-		var aiCandidates []ai.Candidate
+// wordIDs 提取候选的 WordID
+func wordIDs(candidates []ai.Candidate) []string {
+	ids := make([]string, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.WordID
+	}
+	return ids
+}
 
-		for idx, t := range tokens {
-			if !t.IsWord {
-				continue
-			}
-			candidateIDs := tokenVocabIDsMap[idx]
-			if len(candidateIDs) == 0 {
-				continue
-			}
+// isSyncMode 是否以同步 JSON 方式返回
+func isSyncMode(c *gin.Context) bool {
+	if c.Query("mode") == "sync" {
+		return true
+	}
+	accept := c.GetHeader("Accept")
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/event-stream")
+}
 
-			var currentOptions []senseOptionRef
-			var optionsText []string
+// aiStatus 同步模式下 AI 阶段的状态说明 (部分分块失败时为 partial)
+func aiStatus(a *analysis, aiResult map[string]int, err error) (string, string) {
+	switch {
+	case len(a.aiCandidates) == 0:
+		return StageSkipped, ""
+	case err != nil && len(aiResult) > 0:
+		return StagePartial, err.Error()
+	case err != nil:
+		return StageFailed, err.Error()
+	default:
+		return StageApplied, ""
+	}
+}
 
-			for _, vid := range candidateIDs {
-				v, ok := vocabObjMap[vid]
-				if !ok {
-					continue
-				}
-				rawWordLabel := fmt.Sprintf("[%s]", v.Kanji)
+// newAnalysis 分词、查库并准备 AI 候选集
+func newAnalysis(db *gorm.DB, content string, segmenter segment.Segmenter) (*analysis, error) {
+	// ==========================================
+	// 1. 使用内存缓存 (性能优化 ✅)
+	// ==========================================
+	// 不再查库，直接从 GlobalDict 获取
+	lexicon := segment.NewLexicon(cache.GlobalDict)
+
+	// ==========================================
+	// 2. 分词 (策略可选，默认最小代价词图)
+	// ==========================================
+	// 在规范化后的文本上分词，Token 文本按偏移映射取回原文，保证与用户粘贴的内容一致
+	original := []rune(content)
+	norm := textnorm.Normalize(content)
+	runes := norm.Runes
+	var tokens []Token
+
+	tokenVocabIDsMap := make(map[int][]string)
+	allFoundIDs := make(map[string]bool)
+
+	for _, p := range segmenter.Segment(lexicon, runes) {
+		start, end := norm.Span(p.Start, p.End)
+		text := string(original[start:end])
+		if !p.IsWord() {
+			tokens = append(tokens, Token{Text: text, IsWord: false, Start: start, End: end})
+			continue
+		}
 
-				for _, s := range v.Senses {
-					currentOptions = append(currentOptions, senseOptionRef{VocabID: vid, Sense: s})
-					defShort := s.Def
-					if len(defShort) > 50 {
-						defShort = defShort[:50] + "..."
-					}
-					optStr := fmt.Sprintf("%s [%s] %s - %s", rawWordLabel, s.Level, s.Pos, defShort)
-					optionsText = append(optionsText, optStr)
+		tokenVocabIDsMap[len(tokens)] = p.Match.IDs
+		for _, id := range p.Match.IDs {
+			allFoundIDs[id] = true
+		}
+		tokens = append(tokens, Token{
+			Text:        text,
+			IsWord:      true,
+			Base:        p.Match.Base,
+			Inflection:  p.Match.Inflection,
+			MatchSource: p.Match.Source,
+			Start:       start,
+			End:         end,
+		})
+	}
+
+	a := &analysis{
+		tokens:           tokens,
+		sentences:        locateTokens(original, tokens),
+		tokenVocabIDsMap: tokenVocabIDsMap,
+		vocabObjMap:      make(map[string]model.Vocab),
+		cacheEntries:     make(map[string]disambig.Entry),
+		sources:          make(map[string]string),
+	}
+
+	// ==========================================
+	// 3. 批量查询详情 (查库只查命中部分)
+	// ==========================================
+	if len(allFoundIDs) == 0 {
+		return a, nil
+	}
+
+	var ids []string
+	for id := range allFoundIDs {
+		ids = append(ids, id)
+	}
+
+	var vocabsFull []model.Vocab
+	if err := db.Preload("Senses").Preload("Senses.Examples").Where("id IN ?", ids).Find(&vocabsFull).Error; err != nil {
+		return nil, err
+	}
+
+	for _, v := range vocabsFull {
+		a.vocabObjMap[v.ID] = v
+	}
+
+	// ==========================================
+	// 4. 准备 AI 消歧候选集
+	// ==========================================
+	for idx, t := range tokens {
+		if !t.IsWord {
+			continue
+		}
+		candidateIDs := tokenVocabIDsMap[idx]
+		if len(candidateIDs) == 0 {
+			continue
+		}
+
+		var currentOptions []senseOptionRef
+		var optionsText []string
+
+		for _, vid := range candidateIDs {
+			v, ok := a.vocabObjMap[vid]
+			if !ok {
+				continue
+			}
+			rawWordLabel := fmt.Sprintf("[%s]", v.Kanji)
+
+			for _, s := range v.Senses {
+				currentOptions = append(currentOptions, senseOptionRef{VocabID: vid, Sense: s})
+				defShort := s.Def
+				if len(defShort) > 50 {
+					defShort = defShort[:50] + "..."
 				}
+				optStr := fmt.Sprintf("%s [%s] %s - %s", rawWordLabel, s.Level, s.Pos, defShort)
+				optionsText = append(optionsText, optStr)
 			}
+		}
 
-			if len(currentOptions) > 1 {
-				uniqueKey := fmt.Sprintf("token_%d", idx)
-				contextStr := getContext(tokens, idx, 15)
-				aiCandidates = append(aiCandidates, ai.Candidate{
-					WordID:   uniqueKey,
-					WordText: t.Text,
-					Context:  contextStr,
-					Options:  optionsText,
-				})
+		if len(currentOptions) > 1 {
+			uniqueKey := fmt.Sprintf("token_%d", idx)
+			contextStr := getContext(tokens, idx, 15)
+			a.aiCandidates = append(a.aiCandidates, ai.Candidate{
+				WordID:   uniqueKey,
+				WordText: t.Text,
+				Context:  contextStr,
+				Options:  optionsText,
+				Sentence: t.Sentence,
+			})
+
+			senseIDs := make([]string, len(currentOptions))
+			for k, o := range currentOptions {
+				senseIDs[k] = o.Sense.ID
+			}
+			a.cacheEntries[uniqueKey] = disambig.Entry{
+				Key:      disambig.Key(t.Text, contextStr, senseIDs),
+				SenseIDs: senseIDs,
 			}
 		}
+	}
 
-		// ==========================================
-		// 5. 设置 SSE Header 并发送初始结果 (纯逻辑)
-		// ==========================================
-		c.Writer.Header().Set("Content-Type", "text/event-stream")
-		c.Writer.Header().Set("Cache-Control", "no-cache")
-		c.Writer.Header().Set("Connection", "keep-alive")
-		// ❌ 移除重复的 Access-Control-Allow-Origin (中间件已设置)
-		// c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+	return a, nil
+}
 
-		// 构建初始响应（不带 AI 结果，默认选第一个）
-		emptyAIResult := make(map[string]int)
-		initialResp := buildAnalyzeResp(tokens, tokenVocabIDsMap, vocabObjMap, emptyAIResult)
+// cachedChoices 从消歧缓存中取出已知结果 (WordID -> 释义下标)
+func (a *analysis) cachedChoices(ctx context.Context) map[string]int {
+	result := make(map[string]int)
+	if disambig.Default == nil || len(a.cacheEntries) == 0 {
+		return result
+	}
 
-		c.SSEvent("initial", initialResp)
-		c.Writer.Flush()
+	keys := make([]string, 0, len(a.cacheEntries))
+	for _, e := range a.cacheEntries {
+		keys = append(keys, e.Key)
+	}
+	hits, err := disambig.Default.Get(ctx, keys)
+	if err != nil {
+		log.Printf("读取消歧缓存失败: %v", err)
+	}
 
-		// ==========================================
-		// 6. 后台执行 AI 消歧并推送更新
-		// ==========================================
-		if len(aiCandidates) > 0 {
-			// ✅ 检查客户端是否断开连接
-			select {
-			case <-c.Request.Context().Done():
-				// 客户端已断开，不再执行耗时的 AI 操作
-				return
-			default:
-				// 继续执行
-			}
+	for _, c := range a.aiCandidates {
+		if hit, ok := hits[a.cacheEntries[c.WordID].Key]; ok && hit.Choice >= 0 && hit.Choice < len(c.Options) {
+			result[c.WordID] = hit.Choice
+			a.sources[c.WordID] = DisambigCache
+		}
+	}
+	return result
+}
 
-			// ✅ 传递上下文，支持取消
-			aiResult := ai.BatchDisambiguate(c.Request.Context(), aiCandidates)
+// pendingCandidates 缓存未命中、仍需请求 AI 的候选
+func (a *analysis) pendingCandidates(cached map[string]int) []ai.Candidate {
+	var pending []ai.Candidate
+	for _, c := range a.aiCandidates {
+		if _, ok := cached[c.WordID]; !ok {
+			pending = append(pending, c)
+		}
+	}
+	return pending
+}
 
-			// 再次检查连接状态，防止写入 closed pipe
-			select {
-			case <-c.Request.Context().Done():
-				return
-			default:
-				c.SSEvent("ai_update", aiResult)
-				c.Writer.Flush()
+// disambiguate 先查消歧缓存，未命中的候选再请求 AI，合并所有结果
+func (a *analysis) disambiguate(ctx context.Context) (map[string]int, error) {
+	merged := a.cachedChoices(ctx)
+	pending := a.pendingCandidates(merged)
+	if len(pending) == 0 {
+		return merged, nil
+	}
+	if a.provider == nil {
+		return merged, ai.ErrNotConfigured
+	}
+
+	result, err := a.disambiguateCandidates(ctx, pending, nil)
+	for k, v := range result {
+		merged[k] = v
+	}
+	return merged, err
+}
+
+// disambiguateCandidates 分块并发请求 AI，成功的结果写入消歧缓存
+// onChunk 非空时每完成一块回调一次；返回的 error 汇总了失败分块的原因
+func (a *analysis) disambiguateCandidates(ctx context.Context, candidates []ai.Candidate, onChunk func(ai.ChunkResult)) (map[string]int, error) {
+	merged := make(map[string]int)
+	var errs []error
+	ai.DisambiguateChunks(ctx, a.provider, candidates, a.chunkOpts, func(res ai.ChunkResult) {
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("分块 %d/%d: %w", res.Index+1, res.Total, res.Err))
+		} else {
+			a.storeChoices(ctx, res.Result, res.PromptVersion)
+		}
+		for k, v := range res.Result {
+			merged[k] = v
+			a.sources[k] = DisambigAI
+		}
+		if onChunk != nil {
+			onChunk(res)
+		}
+	})
+	return merged, errors.Join(errs...)
+}
+
+// storeChoices 把 AI 结果写入消歧缓存 (忽略越界的下标)，同时记录提示词版本
+func (a *analysis) storeChoices(ctx context.Context, choices map[string]int, promptVersion string) {
+	if disambig.Default == nil {
+		return
+	}
+
+	var entries []disambig.Entry
+	for wordID, choice := range choices {
+		e, ok := a.cacheEntries[wordID]
+		if !ok || choice < 0 || choice >= len(e.SenseIDs) {
+			continue
+		}
+		e.Choice = choice
+		e.PromptVersion = promptVersion
+		entries = append(entries, e)
+	}
+	if err := disambig.Default.Put(ctx, entries); err != nil {
+		log.Printf("写入消歧缓存失败: %v", err)
+	}
+}
+
+// response 按 AI 结果构建响应 (aiResult 为空时多义词默认选第一个释义)
+// final 表示消歧已结束：没有结果的多义词标记为 fallback，否则标记为 pending
+func (a *analysis) response(aiResult map[string]int, final bool) AnalyzeResp {
+	if aiResult == nil {
+		aiResult = make(map[string]int)
+	}
+	status := func(wordID string) string {
+		if _, ok := aiResult[wordID]; ok {
+			if src, ok := a.sources[wordID]; ok {
+				return src
 			}
 		}
+		if final {
+			return DisambigFallback
+		}
+		return DisambigPending
 	}
+	return buildAnalyzeResp(a.tokens, a.sentences, a.tokenVocabIDsMap, a.vocabObjMap, aiResult, status)
 }
 
 // buildAnalyzeResp 构建响应数据 (提取为独立函数以便复用逻辑)
-func buildAnalyzeResp(tokens []Token, tokenVocabIDsMap map[int][]string, vocabObjMap map[string]model.Vocab, aiResult map[string]int) AnalyzeResp {
+// status 返回多义词 (以 WordID 标识) 的消歧状态
+func buildAnalyzeResp(tokens []Token, sentences []Sentence, tokenVocabIDsMap map[int][]string, vocabObjMap map[string]model.Vocab, aiResult map[string]int, status func(wordID string) string) AnalyzeResp {
 	var resultVocabList []WordResult
 	vocabListSet := make(map[string]bool)
 
@@ -270,9 +685,13 @@ func buildAnalyzeResp(tokens []Token, tokenVocabIDsMap map[int][]string, vocabOb
 
 		selectedIndex := 0
 		uniqueKey := fmt.Sprintf("token_%d", idx)
+		// 下标越界的结果没有被采用，按 fallback 处理
+		rejected := false
 		if aiIdx, ok := aiResult[uniqueKey]; ok {
 			if aiIdx >= 0 && aiIdx < len(allOptions) {
 				selectedIndex = aiIdx
+			} else {
+				rejected = true
 			}
 		}
 
@@ -316,6 +735,13 @@ func buildAnalyzeResp(tokens []Token, tokenVocabIDsMap map[int][]string, vocabOb
 
 		finalTokens[idx].Detail = bestDetail
 		finalTokens[idx].Candidates = candidates
+		if len(allOptions) > 1 {
+			if rejected {
+				finalTokens[idx].Disambiguation = DisambigFallback
+			} else {
+				finalTokens[idx].Disambiguation = status(uniqueKey)
+			}
+		}
 
 		// 侧边栏
 		if bestDetail != nil {
@@ -333,8 +759,89 @@ func buildAnalyzeResp(tokens []Token, tokenVocabIDsMap map[int][]string, vocabOb
 
 	return AnalyzeResp{
 		Tokens:    finalTokens,
+		Sentences: sentences,
 		VocabList: resultVocabList,
+		Grammar:   matchGrammar(finalTokens),
+		Profile:   buildProfile(finalTokens),
+	}
+}
+
+// buildProfile 根据选中的释义计算文章难度画像
+func buildProfile(tokens []Token) profile.Profile {
+	input := make([]profile.Token, len(tokens))
+	for i, t := range tokens {
+		input[i] = profile.Token{Text: t.Text, IsWord: t.IsWord, Sentence: t.Sentence}
+		if t.Detail != nil {
+			input[i].Level = t.Detail.Level
+		}
+	}
+	return profile.Build(input)
+}
+
+// matchGrammar 用当前的语法点匹配器扫描 Token 序列
+func matchGrammar(tokens []Token) []grammar.Match {
+	input := make([]grammar.Token, len(tokens))
+	for i, t := range tokens {
+		input[i] = grammar.Token{Text: t.Text, IsWord: t.IsWord, Sentence: t.Sentence}
+		if t.Detail != nil {
+			input[i].Pos = t.Detail.Pos
+		}
+	}
+	matches := grammar.Default().Match(input)
+	if matches == nil {
+		matches = []grammar.Match{}
+	}
+	return matches
+}
+
+// locateTokens 填充 Token 的字节偏移、句子与段落下标，并返回句子列表
+// 句间空白 (换行等) 归入前一个句子
+func locateTokens(original []rune, tokens []Token) []Sentence {
+	byteOffsets := make([]int, len(original)+1)
+	for i, r := range original {
+		byteOffsets[i+1] = byteOffsets[i] + utf8.RuneLen(r)
+	}
+
+	spans := segment.SplitSentences(original)
+	sentences := make([]Sentence, 0, len(spans))
+	for i, sp := range spans {
+		sentences = append(sentences, Sentence{
+			Index:      i,
+			Paragraph:  sp.Paragraph,
+			Text:       string(original[sp.Start:sp.End]),
+			Start:      sp.Start,
+			End:        sp.End,
+			ByteStart:  byteOffsets[sp.Start],
+			ByteEnd:    byteOffsets[sp.End],
+			TokenStart: -1,
+		})
+	}
+
+	cur := 0
+	for i := range tokens {
+		t := &tokens[i]
+		t.ByteStart = byteOffsets[t.Start]
+		t.ByteEnd = byteOffsets[t.End]
+
+		for cur+1 < len(sentences) && t.Start >= sentences[cur+1].Start {
+			cur++
+		}
+		if len(sentences) == 0 {
+			continue
+		}
+		t.Sentence = cur
+		t.Paragraph = sentences[cur].Paragraph
+		if sentences[cur].TokenStart < 0 {
+			sentences[cur].TokenStart = i
+		}
+		sentences[cur].TokenEnd = i + 1
+	}
+	for i := range sentences {
+		if sentences[i].TokenStart < 0 {
+			sentences[i].TokenStart = sentences[i].TokenEnd
+		}
 	}
+	return sentences
 }
 
 // getContext 保持不变
@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/ai"
+	"dongwai_backend/internal/pkg/cache"
+	"dongwai_backend/internal/pkg/grammar"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// setupAnalyze 准备词典：勉強 (两个释义) 与 日本語 (一个释义)
+func setupAnalyze(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	vocabs := []model.Vocab{
+		{ID: "v_benkyou", Kanji: "勉強", Senses: []model.VocabSense{
+			{ID: "s_benkyou_1", VocabID: "v_benkyou", Level: "N5", Reading: "べんきょう", Def: "学习"},
+			{ID: "s_benkyou_2", VocabID: "v_benkyou", Level: "N5", Reading: "べんきょう", Def: "便宜，让价"},
+		}},
+		{ID: "v_nihongo", Kanji: "日本語", Senses: []model.VocabSense{
+			{ID: "s_nihongo_1", VocabID: "v_nihongo", Level: "N5", Reading: "にほんご", Def: "日语"},
+		}},
+	}
+
+	cache.GlobalDict = cache.NewDictCache()
+	vocabRows := fakeTable{columns: []string{"id", "kanji", "is_multi"}}
+	senseRows := fakeTable{columns: []string{"id", "vocab_id", "level", "reading", "def"}}
+	for _, v := range vocabs {
+		cache.GlobalDict.AddOrUpdate(v)
+		vocabRows.rows = append(vocabRows.rows, []driver.Value{v.ID, v.Kanji, len(v.Senses) > 1})
+		for _, s := range v.Senses {
+			senseRows.rows = append(senseRows.rows, []driver.Value{s.ID, s.VocabID, s.Level, s.Reading, s.Def})
+		}
+	}
+
+	return newTestDB(t, map[string]fakeTable{
+		"vocabs":       vocabRows,
+		"vocab_senses": senseRows,
+	})
+}
+
+var wordIDPattern = regexp.MustCompile(`WordID: (\S+)`)
+
+// pickSecond 对提示词中的每个 WordID 都选择下标 1
+func pickSecond(req ai.ChatRequest) ai.FakeReply {
+	result := map[string]int{}
+	for _, m := range wordIDPattern.FindAllStringSubmatch(req.Messages[len(req.Messages)-1].Content, -1) {
+		result[m[1]] = 1
+	}
+	data, _ := json.Marshal(result)
+	return ai.FakeReply{Content: string(data)}
+}
+
+func postAnalyze(t *testing.T, db *gorm.DB, provider ai.Provider, query, content string) *httptest.ResponseRecorder {
+	t.Helper()
+	return postAnalyzeJSON(t, db, provider, query, fmt.Sprintf(`{"content": %q}`, content))
+}
+
+func postAnalyzeJSON(t *testing.T, db *gorm.DB, provider ai.Provider, query, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := gin.New()
+	r.POST("/api/analyze", AnalyzeArticle(db, provider, ai.ChunkOptions{}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func findToken(resp AnalyzeResp, vocabID string) *Token {
+	for i := range resp.Tokens {
+		if d := resp.Tokens[i].Detail; d != nil && d.VocabID == vocabID {
+			return &resp.Tokens[i]
+		}
+	}
+	return nil
+}
+
+func TestAnalyzeArticleSync(t *testing.T) {
+	cases := []struct {
+		name      string
+		provider  ai.Provider
+		wantSense string
+		wantAI    string
+		wantState string
+	}{
+		{"ai applied", &ai.FakeProvider{Handler: pickSecond}, "s_benkyou_2", "applied", DisambigAI},
+		// 越界的下标 (以及多出的 WordID) 不采用，多义词退回第一个释义
+		{"ai out of range", ai.NewFake(ai.FakeReply{Content: `{"token_0": 7, "token_2": 7, "token_99": 1}`}), "s_benkyou_1", "applied", DisambigFallback},
+		{"ai failed", ai.NewFake(ai.FakeReply{Err: fmt.Errorf("boom")}), "s_benkyou_1", "failed", DisambigFallback},
+		{"not configured", nil, "s_benkyou_1", "failed", DisambigFallback},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := setupAnalyze(t)
+			w := postAnalyze(t, db, tc.provider, "?mode=sync", "日本語を勉強する。")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+			}
+
+			var resp AnalyzeResp
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.AIStatus != tc.wantAI {
+				t.Errorf("ai_status = %q (%s), want %q", resp.AIStatus, resp.AIError, tc.wantAI)
+			}
+
+			tok := findToken(resp, "v_benkyou")
+			if tok == nil {
+				t.Fatalf("勉強 not found in %+v", resp.Tokens)
+			}
+			if tok.Detail.SenseID != tc.wantSense {
+				t.Errorf("selected sense = %s, want %s", tok.Detail.SenseID, tc.wantSense)
+			}
+			if tok.Disambiguation != tc.wantState {
+				t.Errorf("disambiguation = %q, want %q", tok.Disambiguation, tc.wantState)
+			}
+			if len(tok.Candidates) != 2 {
+				t.Errorf("candidates = %d, want 2", len(tok.Candidates))
+			}
+			if findToken(resp, "v_nihongo") == nil {
+				t.Error("日本語 not found")
+			}
+		})
+	}
+}
+
+func TestAnalyzeArticleStream(t *testing.T) {
+	db := setupAnalyze(t)
+	w := postAnalyze(t, db, &ai.FakeProvider{Handler: pickSecond}, "", "日本語を勉強する。")
+
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	if w.Header().Get("X-Stream-ID") == "" {
+		t.Error("missing X-Stream-ID")
+	}
+
+	var names []string
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if name, ok := strings.CutPrefix(line, "event:"); ok {
+			names = append(names, name)
+		}
+	}
+	want := []string{"initial", "progress", "ai_update", "progress", "profile", "done"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", names, want)
+	}
+}
+
+func TestAnalyzeArticleStreamFallback(t *testing.T) {
+	db := setupAnalyze(t)
+	w := postAnalyze(t, db, ai.NewFake(ai.FakeReply{Err: fmt.Errorf("boom")}), "", "日本語を勉強する。")
+
+	body := w.Body.String()
+	for _, want := range []string{"event:error", "event:ai_fallback", `"disambiguation":"pending"`, "event:done"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("stream missing %s:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "event:ai_update") {
+		t.Error("unexpected ai_update")
+	}
+}
+
+// translateOrPick 翻译请求返回固定译文，消歧请求选择下标 1
+func translateOrPick(prompts *[]string) func(req ai.ChatRequest) ai.FakeReply {
+	return func(req ai.ChatRequest) ai.FakeReply {
+		if req.Feature != ai.FeatureTranslate {
+			return pickSecond(req)
+		}
+		*prompts = append(*prompts, req.Messages[len(req.Messages)-1].Content)
+		return ai.FakeReply{Content: `{"0": {"translation": "学日语。", "grammar": "を 表示动作的对象"}, "7": {"translation": "不存在的句子"}}`}
+	}
+}
+
+func TestAnalyzeArticleTranslateSync(t *testing.T) {
+	db := setupAnalyze(t)
+	var prompts []string
+	w := postAnalyzeJSON(t, db, &ai.FakeProvider{Handler: translateOrPick(&prompts)}, "?mode=sync",
+		`{"content": "日本語を勉強する。", "translate": true}`)
+
+	var resp AnalyzeResp
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.TranslateStatus != "applied" {
+		t.Fatalf("translate_status = %q (%s)", resp.TranslateStatus, resp.TranslateError)
+	}
+	if len(resp.Sentences) != 1 || resp.Sentences[0].Translation != "学日语。" || resp.Sentences[0].GrammarNote == "" {
+		t.Errorf("sentences = %+v", resp.Sentences)
+	}
+
+	// 消歧结果 (第二个释义) 作为词义提示传给翻译
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "勉強（べんきょう）: 便宜，让价") {
+		t.Errorf("translate prompt missing sense hint:\n%v", prompts)
+	}
+}
+
+func TestAnalyzeArticleTranslateStream(t *testing.T) {
+	db := setupAnalyze(t)
+	var prompts []string
+	w := postAnalyzeJSON(t, db, &ai.FakeProvider{Handler: translateOrPick(&prompts)}, "",
+		`{"content": "日本語を勉強する。", "translate": true}`)
+
+	var names []string
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if name, ok := strings.CutPrefix(line, "event:"); ok {
+			names = append(names, name)
+		}
+	}
+	want := []string{"initial", "progress", "ai_update", "progress", "profile", "progress", "translation", "progress", "done"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", names, want)
+	}
+	if !strings.Contains(w.Body.String(), `"stage":"translate"`) {
+		t.Error("missing translate progress")
+	}
+}
+
+func TestAnalyzeArticleGrammar(t *testing.T) {
+	db := setupAnalyze(t)
+	p, err := grammar.Compile("を 〜 する")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grammar.SetDefault(grammar.NewMatcher([]grammar.Entry{{ID: "g_1", Name: "をする", Pattern: p}}))
+	t.Cleanup(func() { grammar.SetDefault(grammar.NewMatcher(nil)) })
+
+	w := postAnalyze(t, db, &ai.FakeProvider{Handler: pickSecond}, "?mode=sync", "日本語を勉強する。")
+	var resp AnalyzeResp
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Grammar) != 1 {
+		t.Fatalf("grammar = %+v", resp.Grammar)
+	}
+	m := resp.Grammar[0]
+	if m.PatternID != "g_1" || m.Sentence != 0 || len(m.Spans) != 2 {
+		t.Errorf("match = %+v", m)
+	}
+	if got := resp.Tokens[m.Spans[0].TokenStart].Text; got != "を" {
+		t.Errorf("first span starts at %q", got)
+	}
+}
+
+func TestUsageUser(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/analyze", nil)
+	c.Request.RemoteAddr = "203.0.113.7:51234"
+	if got := usageUser(c); got != "ip:203.0.113.7" {
+		t.Errorf("anonymous = %q", got)
+	}
+	c.Set("userID", "u1")
+	if got := usageUser(c); got != "u1" {
+		t.Errorf("logged in = %q", got)
+	}
+}
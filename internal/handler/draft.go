@@ -0,0 +1,312 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dongwai_backend/internal/dto"
+	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/cache"
+	"dongwai_backend/internal/pkg/enrich"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DraftResp 待审核的单词草稿
+type DraftResp struct {
+	ID            string          `json:"id"`
+	VocabID       string          `json:"vocab_id"` // 为空表示新词 (审核通过后新建)
+	Kanji         string          `json:"kanji"`
+	VocabularyID  string          `json:"vocabulary_id,omitempty"` // 审核通过后加入的词书
+	JobID         string          `json:"job_id"`
+	Status        string          `json:"status"`
+	Word          json.RawMessage `json:"word"`     // 审核通过后写入词库的完整单词 (dto.WordDTO)
+	Changes       json.RawMessage `json:"changes"`  // 补全的字段
+	Warnings      json.RawMessage `json:"warnings"` // 未通过的校验项
+	PromptVersion string          `json:"prompt_version"`
+	CreatedAt     time.Time       `json:"created_at"`
+	ReviewedBy    string          `json:"reviewed_by,omitempty"`
+	ReviewedAt    *time.Time      `json:"reviewed_at,omitempty"`
+}
+
+// ApproveDraftReq 审核通过；word 不为空时以审核人修改后的内容为准
+type ApproveDraftReq struct {
+	Word *dto.WordDTO `json:"word"`
+}
+
+var (
+	errDraftReviewed   = errors.New("草稿已审核")
+	errWordChanged     = errors.New("单词在生成草稿后已被修改，请重新生成")
+	errDraftWordExists = errors.New("词库中已有同名单词，请对照已有单词修改后再保存")
+)
+
+func toDraftResp(d model.WordDraft) DraftResp {
+	return DraftResp{
+		ID:            d.ID,
+		VocabID:       d.VocabID,
+		Kanji:         d.Kanji,
+		VocabularyID:  d.VocabularyID,
+		JobID:         d.JobID,
+		Status:        d.Status,
+		Word:          rawOr(d.Data, "{}"),
+		Changes:       rawOr(d.Changes, "[]"),
+		Warnings:      rawOr(d.Warnings, "[]"),
+		PromptVersion: d.PromptVersion,
+		CreatedAt:     d.CreatedAt,
+		ReviewedBy:    d.ReviewedBy,
+		ReviewedAt:    d.ReviewedAt,
+	}
+}
+
+func rawOr(data []byte, empty string) json.RawMessage {
+	if len(data) == 0 {
+		return json.RawMessage(empty)
+	}
+	return json.RawMessage(data)
+}
+
+// ListDrafts 草稿列表
+// 查询参数: status (默认 pending)，job_id，page，page_size
+func ListDrafts(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+		if page < 1 {
+			page = 1
+		}
+		if pageSize < 1 || pageSize > 100 {
+			pageSize = 20
+		}
+
+		query := db.Model(&model.WordDraft{}).Where("status = ?", c.DefaultQuery("status", enrich.DraftPending))
+		if jobID := c.Query("job_id"); jobID != "" {
+			query = query.Where("job_id = ?", jobID)
+		}
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+			return
+		}
+
+		var drafts []model.WordDraft
+		if err := query.Order("created_at").Offset((page - 1) * pageSize).Limit(pageSize).Find(&drafts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+			return
+		}
+
+		list := make([]DraftResp, 0, len(drafts))
+		for _, d := range drafts {
+			list = append(list, toDraftResp(d))
+		}
+		c.JSON(http.StatusOK, gin.H{"total": total, "list": list})
+	}
+}
+
+// GetDraft 草稿详情
+func GetDraft(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var draft model.WordDraft
+		if err := db.First(&draft, "id = ?", c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "草稿不存在"})
+			return
+		}
+		c.JSON(http.StatusOK, toDraftResp(draft))
+	}
+}
+
+// ApproveDraft 审核通过：把草稿写入词库
+// 单词在生成草稿之后被修改过时拒绝写入 (409)，避免覆盖他人的修改
+func ApproveDraft(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ApproveDraftReq
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		var draft model.WordDraft
+		if err := db.First(&draft, "id = ?", c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "草稿不存在"})
+			return
+		}
+
+		word := req.Word
+		if word == nil {
+			word = &dto.WordDTO{}
+			if err := json.Unmarshal(draft.Data, word); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "草稿内容损坏"})
+				return
+			}
+		}
+		update := wordReqFromDTO(draft.VocabID, *word)
+		if update.Kanji == "" {
+			update.Kanji = draft.Kanji
+		}
+
+		if draft.VocabID == "" {
+			approveNewWord(c, db, draft, update)
+			return
+		}
+
+		var vocab model.Vocab
+		if err := db.Select("id, kanji, updata_at").First(&vocab, "id = ?", draft.VocabID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "单词不存在"})
+			return
+		}
+
+		var senseIDs []string
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := markDraftApproved(tx, draft.ID, c.GetString("userID")); err != nil {
+				return err
+			}
+
+			// 加锁后再比较修改时间
+			var current model.Vocab
+			if err := tx.Select("updata_at").Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, "id = ?", draft.VocabID).Error; err != nil {
+				return err
+			}
+			if !current.UpdataAt.Equal(draft.BaseUpdatedAt) {
+				return errWordChanged
+			}
+
+			var err error
+			senseIDs, err = saveWordUpdate(tx, update)
+			return err
+		})
+		switch {
+		case errors.Is(err, errDraftReviewed), errors.Is(err, errWordChanged):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存失败: " + err.Error()})
+			return
+		}
+
+		refreshWordCache(c.Request.Context(), vocab.Kanji, update, senseIDs)
+		c.JSON(http.StatusOK, gin.H{"message": "已写入词库", "id": draft.VocabID})
+	}
+}
+
+// approveNewWord 审核通过新词草稿：新建单词并加入草稿指定的词书
+// 审核前词库中已有同名单词时 (例如同一个词在多本词书导入时各生成了一份草稿) 返回 409 与已有单词的 ID，
+// 草稿保持待审核，不丢弃审核人确认过的内容
+func approveNewWord(c *gin.Context, db *gorm.DB, draft model.WordDraft, update UpdateWordReq) {
+	var created model.Vocab
+	existingID := ""
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 返回错误时事务回滚，草稿仍是待审核状态
+		if err := markDraftApproved(tx, draft.ID, c.GetString("userID")); err != nil {
+			return err
+		}
+
+		var existing []model.Vocab
+		if err := tx.Select("id").Where("kanji = ? OR kanji_norm = ?", update.Kanji, cache.NormalizeKey(update.Kanji)).
+			Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			existingID = existing[0].ID
+			return errDraftWordExists
+		}
+
+		var err error
+		if created, err = createWord(tx, update.CreateWordReq); err != nil {
+			return err
+		}
+		if err := tx.Model(&model.WordDraft{}).Where("id = ?", draft.ID).Update("vocab_id", created.ID).Error; err != nil {
+			return err
+		}
+		if draft.VocabularyID == "" {
+			return nil
+		}
+		return addWordToBook(tx, draft.VocabularyID, created.ID)
+	})
+	switch {
+	case errors.Is(err, errDraftReviewed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errDraftWordExists):
+		// 审核人可以对照已有单词合并内容
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "existing_id": existingID})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存失败: " + err.Error()})
+		return
+	}
+
+	cache.GlobalDict.AddOrUpdate(created)
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "已写入词库",
+		"id":            created.ID,
+		"created":       true,
+		"vocabulary_id": draft.VocabularyID,
+	})
+}
+
+// markDraftApproved 把待审核的草稿标记为通过，草稿已被他人审核时返回 errDraftReviewed
+func markDraftApproved(tx *gorm.DB, draftID, userID string) error {
+	res := tx.Model(&model.WordDraft{}).
+		Where("id = ? AND status = ?", draftID, enrich.DraftPending).
+		Updates(map[string]any{"status": enrich.DraftApproved, "reviewed_by": userID, "reviewed_at": time.Now()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errDraftReviewed
+	}
+	return nil
+}
+
+// RejectDraft 审核不通过：丢弃草稿
+func RejectDraft(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res := db.Model(&model.WordDraft{}).
+			Where("id = ? AND status = ?", c.Param("id"), enrich.DraftPending).
+			Updates(map[string]any{"status": enrich.DraftRejected, "reviewed_by": c.GetString("userID"), "reviewed_at": time.Now()})
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败"})
+			return
+		}
+		if res.RowsAffected == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "草稿不存在或已审核"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "已拒绝"})
+	}
+}
+
+// wordReqFromDTO 把草稿中的单词转换为更新请求 (is_multi 按释义数重新计算)
+func wordReqFromDTO(vocabID string, w dto.WordDTO) UpdateWordReq {
+	req := UpdateWordReq{ID: vocabID}
+	req.Kanji = w.Kanji
+	for _, s := range w.Senses {
+		sense := WordSenseReq{
+			ID:       s.ID,
+			Level:    s.Level,
+			Reading:  s.Reading,
+			Def:      s.Def,
+			Pos:      s.Pos,
+			Pitch:    s.Pitch,
+			Furigana: s.Furigana,
+		}
+		for _, ex := range s.Examples {
+			sense.Examples = append(sense.Examples, WordExampleReq{
+				Kanji:    ex.Kanji,
+				Def:      ex.Def,
+				Audio:    ex.Audio,
+				Furigana: ex.Furigana,
+			})
+		}
+		req.Senses = append(req.Senses, sense)
+	}
+	req.IsMulti = len(req.Senses) > 1
+	return req
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dongwai_backend/internal/pkg/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestApproveDraftStaleBase(t *testing.T) {
+	base := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	db, fake := newFakeDB(t, map[string]fakeTable{
+		"word_drafts": {
+			columns: []string{"id", "vocab_id", "kanji", "status", "data", "base_updated_at"},
+			rows:    [][]driver.Value{{"d1", "v_benkyou", "勉強", "pending", []byte(`{"kanji": "勉強"}`), base}},
+		},
+		// 生成草稿之后单词又被人修改过
+		"vocabs": {
+			columns: []string{"id", "kanji", "updata_at"},
+			rows:    [][]driver.Value{{"v_benkyou", "勉強", base.Add(time.Hour)}},
+		},
+	})
+	r := gin.New()
+	r.POST("/drafts/:id/approve", ApproveDraft(db))
+	approve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/drafts/d1/approve", nil))
+		return w
+	}
+
+	// 草稿已被他人审核
+	if w := approve(); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), errDraftReviewed.Error()) {
+		t.Errorf("reviewed: status = %d: %s", w.Code, w.Body)
+	}
+
+	fake.onExec(`UPDATE "word_drafts"`, 1)
+	w := approve()
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), errWordChanged.Error()) {
+		t.Fatalf("stale base: status = %d: %s", w.Code, w.Body)
+	}
+	// 比较修改时间前先锁住单词，且没有写入任何内容
+	if locked := fake.stmts("FOR UPDATE"); len(locked) != 1 || !strings.Contains(locked[0].query, `FROM "vocabs"`) {
+		t.Errorf("locked = %+v", locked)
+	}
+	if n := len(fake.stmts(`UPDATE "vocabs"`)); n != 0 {
+		t.Errorf("stale draft wrote the word %d times", n)
+	}
+}
+
+func TestApproveNewWordDraftExisting(t *testing.T) {
+	db, fake := newFakeDB(t, map[string]fakeTable{
+		"word_drafts": {
+			columns: []string{"id", "vocab_id", "kanji", "vocabulary_id", "status", "data"},
+			rows:    [][]driver.Value{{"d1", "", "勉強", "b1", "pending", []byte(`{"kanji": "勉強", "senses": [{"def": "学习"}]}`)}},
+		},
+		// 草稿生成之后有人先把同一个词加进了词库
+		"vocabs": {columns: []string{"id"}, rows: [][]driver.Value{{"v_benkyou"}}},
+	})
+	fake.onExec(`UPDATE "word_drafts"`, 1)
+	r := gin.New()
+	r.POST("/drafts/:id/approve", ApproveDraft(db))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/drafts/d1/approve", nil))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var out map[string]string
+	json.Unmarshal(w.Body.Bytes(), &out)
+	if out["existing_id"] != "v_benkyou" || out["error"] != errDraftWordExists.Error() {
+		t.Errorf("body = %v", out)
+	}
+	// 不新建单词，也不把已有单词加入词书
+	for _, table := range []string{`INSERT INTO "vocabs"`, `INSERT INTO "vocabulary_words"`, `"vocab_id"=`} {
+		if n := len(fake.stmts(table)); n != 0 {
+			t.Errorf("%s executed %d times", table, n)
+		}
+	}
+}
+
+func TestApproveDraft(t *testing.T) {
+	cache.GlobalDict = cache.NewDictCache()
+	base := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	db, fake := newFakeDB(t, map[string]fakeTable{
+		"word_drafts": {
+			columns: []string{"id", "vocab_id", "kanji", "status", "data", "base_updated_at"},
+			rows: [][]driver.Value{{"d1", "v_benkyou", "勉強", "pending",
+				[]byte(`{"kanji": "勉強", "senses": [{"id": "s_benkyou_1", "level": "N5", "reading": "べんきょう", "def": "学习"}]}`), base}},
+		},
+		"vocabs": {
+			columns: []string{"id", "kanji", "updata_at"},
+			rows:    [][]driver.Value{{"v_benkyou", "勉強", base}},
+		},
+	})
+	fake.onExec(`UPDATE "word_drafts"`, 1)
+	r := gin.New()
+	r.POST("/drafts/:id/approve", ApproveDraft(db))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/drafts/d1/approve", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	if n := len(fake.stmts(`UPDATE "vocabs"`)); n == 0 {
+		t.Error("word not written")
+	}
+	// 写入后词典缓存立即可查
+	if ids, _ := cache.GlobalDict.Get("勉強"); len(ids) != 1 || ids[0] != "v_benkyou" {
+		t.Errorf("dict = %v", ids)
+	}
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/ai"
+	"dongwai_backend/internal/pkg/enrich"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// EnrichJobResp 补全任务及其进度
+type EnrichJobResp struct {
+	ID         string          `json:"id"`
+	Status     string          `json:"status"`
+	Filter     json.RawMessage `json:"filter"`
+	Total      int             `json:"total"`
+	Processed  int             `json:"processed"`
+	Succeeded  int             `json:"succeeded"` // 生成了草稿
+	Skipped    int             `json:"skipped"`   // 没有可补全的内容
+	Failed     int             `json:"failed"`
+	Progress   float64         `json:"progress"` // 0-100
+	CreatedBy  string          `json:"created_by"`
+	CreatedAt  time.Time       `json:"created_at"`
+	StartedAt  *time.Time      `json:"started_at"`
+	FinishedAt *time.Time      `json:"finished_at"`
+}
+
+// EnrichItemError 失败 (或等待重试) 的单词
+type EnrichItemError struct {
+	VocabID   string    `json:"vocab_id"`
+	Kanji     string    `json:"kanji"`
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error"`
+	NextRunAt time.Time `json:"next_run_at"`
+}
+
+func toEnrichJobResp(job model.EnrichJob) EnrichJobResp {
+	resp := EnrichJobResp{
+		ID:         job.ID,
+		Status:     job.Status,
+		Filter:     json.RawMessage(job.Filter),
+		Total:      job.Total,
+		Processed:  job.Processed,
+		Succeeded:  job.Succeeded,
+		Skipped:    job.Skipped,
+		Failed:     job.Failed,
+		CreatedBy:  job.CreatedBy,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	if job.Total > 0 {
+		resp.Progress = float64(job.Processed) * 100 / float64(job.Total)
+	}
+	if len(resp.Filter) == 0 {
+		resp.Filter = json.RawMessage("{}")
+	}
+	return resp
+}
+
+// CreateEnrichJob 按条件挑选单词，创建批量补全任务 (管理员)
+// 请求体为 enrich.Filter；结果以待审核草稿的形式保存，见 /api/drafts
+func CreateEnrichJob(db *gorm.DB, provider ai.Provider, runner *enrich.Runner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if provider == nil {
+			c.JSON(aiErrorStatus(ai.ErrNotConfigured), gin.H{"error": ai.ErrNotConfigured.Error()})
+			return
+		}
+
+		var filter enrich.Filter
+		if err := c.ShouldBindJSON(&filter); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := filter.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		job, err := enrich.CreateJob(db, filter, c.GetString("userID"))
+		if err != nil {
+			if errors.Is(err, enrich.ErrNoMatch) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建任务失败"})
+			return
+		}
+		runner.Notify()
+
+		c.JSON(http.StatusOK, toEnrichJobResp(*job))
+	}
+}
+
+// ListEnrichJobs 最近的补全任务 (管理员)
+func ListEnrichJobs(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var jobs []model.EnrichJob
+		if err := db.Order("created_at DESC").Limit(50).Find(&jobs).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务失败"})
+			return
+		}
+
+		list := make([]EnrichJobResp, 0, len(jobs))
+		for _, job := range jobs {
+			list = append(list, toEnrichJobResp(job))
+		}
+		c.JSON(http.StatusOK, gin.H{"list": list})
+	}
+}
+
+// GetEnrichJob 任务状态与进度 (管理员)
+func GetEnrichJob(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var job model.EnrichJob
+		if err := db.First(&job, "id = ?", c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+			return
+		}
+		c.JSON(http.StatusOK, toEnrichJobResp(job))
+	}
+}
+
+// ListEnrichJobErrors 任务中失败的单词，以及出过错、正在等待重试的单词 (管理员)
+func ListEnrichJobErrors(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var items []model.EnrichItem
+		err := db.Where("job_id = ? AND (status = ? OR (status = ? AND error <> ''))",
+			c.Param("id"), enrich.ItemFailed, enrich.ItemPending).
+			Order("kanji").
+			Find(&items).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+			return
+		}
+
+		list := make([]EnrichItemError, 0, len(items))
+		for _, it := range items {
+			list = append(list, EnrichItemError{
+				VocabID:   it.VocabID,
+				Kanji:     it.Kanji,
+				Status:    it.Status,
+				Attempts:  it.Attempts,
+				Error:     it.Error,
+				NextRunAt: it.NextRunAt,
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"list": list})
+	}
+}
+
+// CancelEnrichJob 取消任务 (管理员)
+func CancelEnrichJob(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := enrich.Cancel(db, c.Param("id")); err != nil {
+			if errors.Is(err, enrich.ErrJobFinished) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "取消失败"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "任务已取消"})
+	}
+}
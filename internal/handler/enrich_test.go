@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dongwai_backend/internal/pkg/ai"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetEnrichJobProgress(t *testing.T) {
+	db := newTestDB(t, map[string]fakeTable{
+		"enrich_jobs": {
+			columns: []string{"id", "status", "total", "processed", "succeeded", "skipped", "failed"},
+			rows:    [][]driver.Value{{"job1", "running", int64(8), int64(2), int64(1), int64(0), int64(1)}},
+		},
+	})
+
+	r := gin.New()
+	r.GET("/enrich/jobs/:id", GetEnrichJob(db))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/enrich/jobs/job1", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+	}
+	var resp EnrichJobResp
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Progress != 25 || resp.Failed != 1 || string(resp.Filter) != "{}" {
+		t.Errorf("unexpected job: %+v", resp)
+	}
+}
+
+func TestCreateEnrichJobValidation(t *testing.T) {
+	db := newTestDB(t, nil)
+	cases := []struct {
+		name     string
+		provider ai.Provider
+		body     string
+		want     int
+	}{
+		{"not configured", nil, `{"levels":["N1"]}`, http.StatusServiceUnavailable},
+		{"empty filter", ai.NewFake(), `{}`, http.StatusBadRequest},
+		{"unknown field", ai.NewFake(), `{"missing":["audio"]}`, http.StatusBadRequest},
+		{"no match", ai.NewFake(), `{"levels":["N1"]}`, http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			r.POST("/enrich/jobs", CreateEnrichJob(db, tc.provider, nil))
+
+			req := httptest.NewRequest(http.MethodPost, "/enrich/jobs", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.want {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tc.want, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"dongwai_backend/internal/pkg/ai"
+	"dongwai_backend/internal/pkg/gloss"
+	"dongwai_backend/internal/pkg/stream"
+)
+
+// SentenceTranslation 一个句子的翻译与语法说明
+type SentenceTranslation struct {
+	Sentence    int    `json:"sentence"` // 句子下标 (对应 AnalyzeResp.Sentences)
+	Translation string `json:"translation"`
+	GrammarNote string `json:"grammar_note"`
+	Source      string `json:"source"` // ai / cache
+}
+
+// TranslationEvent 一批句子的翻译
+type TranslationEvent struct {
+	Sentences []SentenceTranslation `json:"sentences"`
+}
+
+// maxHintDef 词义提示中释义的最大字符数
+const maxHintDef = 30
+
+// sentenceInputs 需要翻译的句子 (跳过空白句)，附带句中多义词已确定的词义作为提示
+func sentenceInputs(resp AnalyzeResp) []ai.SentenceInput {
+	hints := make(map[int][]string)
+	seen := make(map[string]bool)
+	for _, t := range resp.Tokens {
+		if t.Disambiguation == "" || t.Detail == nil {
+			continue
+		}
+		word := t.Base
+		if word == "" {
+			word = t.Text
+		}
+		def := []rune(t.Detail.Def)
+		if len(def) > maxHintDef {
+			def = append(def[:maxHintDef], '…')
+		}
+		hint := fmt.Sprintf("%s（%s）: %s", word, t.Detail.Reading, string(def))
+		if key := fmt.Sprint(t.Sentence, hint); !seen[key] {
+			seen[key] = true
+			hints[t.Sentence] = append(hints[t.Sentence], hint)
+		}
+	}
+
+	var inputs []ai.SentenceInput
+	for _, s := range resp.Sentences {
+		if strings.TrimSpace(s.Text) == "" {
+			continue
+		}
+		inputs = append(inputs, ai.SentenceInput{Index: s.Index, Text: s.Text, Hints: hints[s.Index]})
+	}
+	return inputs
+}
+
+// cachedGlosses 从翻译缓存中取出已知结果，返回命中的翻译与仍需请求 AI 的句子
+func cachedGlosses(ctx context.Context, inputs []ai.SentenceInput) (map[int]SentenceTranslation, []ai.SentenceInput) {
+	result := make(map[int]SentenceTranslation)
+	if gloss.Default == nil {
+		return result, inputs
+	}
+
+	hashes := make([]string, len(inputs))
+	for i, in := range inputs {
+		hashes[i] = gloss.Hash(in.Text)
+	}
+	hits, err := gloss.Default.Get(ctx, hashes)
+	if err != nil {
+		log.Printf("读取翻译缓存失败: %v", err)
+	}
+
+	var pending []ai.SentenceInput
+	for i, in := range inputs {
+		if hit, ok := hits[hashes[i]]; ok {
+			result[in.Index] = SentenceTranslation{Sentence: in.Index, Translation: hit.Translation, GrammarNote: hit.Grammar, Source: DisambigCache}
+		} else {
+			pending = append(pending, in)
+		}
+	}
+	return result, pending
+}
+
+// translateSentences 分批请求 AI 翻译，成功的结果写入翻译缓存
+// onChunk 非空时每完成一批回调一次 (附带本批的翻译)；返回的 error 汇总了失败批次的原因
+func (a *analysis) translateSentences(ctx context.Context, inputs []ai.SentenceInput, onChunk func(ai.TranslateResult, []SentenceTranslation)) (map[int]SentenceTranslation, error) {
+	texts := make(map[int]string, len(inputs))
+	for _, in := range inputs {
+		texts[in.Index] = in.Text
+	}
+
+	merged := make(map[int]SentenceTranslation)
+	var errs []error
+	ai.TranslateChunks(ctx, a.provider, inputs, a.chunkOpts, func(res ai.TranslateResult) {
+		var batch []SentenceTranslation
+		var entries []gloss.Entry
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("分块 %d/%d: %w", res.Index+1, res.Total, res.Err))
+		}
+		for idx, g := range res.Result {
+			t := SentenceTranslation{Sentence: idx, Translation: g.Translation, GrammarNote: g.Grammar, Source: DisambigAI}
+			merged[idx] = t
+			batch = append(batch, t)
+			entries = append(entries, gloss.Entry{
+				Hash:          gloss.Hash(texts[idx]),
+				Text:          texts[idx],
+				Translation:   g.Translation,
+				Grammar:       g.Grammar,
+				PromptVersion: res.PromptVersion,
+			})
+		}
+		if gloss.Default != nil && len(entries) > 0 {
+			if err := gloss.Default.Put(ctx, entries); err != nil {
+				log.Printf("写入翻译缓存失败: %v", err)
+			}
+		}
+		if onChunk != nil {
+			sortTranslations(batch)
+			onChunk(res, batch)
+		}
+	})
+	return merged, errors.Join(errs...)
+}
+
+// glossSentences 同步模式的翻译阶段：先查缓存，未命中的句子再请求 AI
+func (a *analysis) glossSentences(ctx context.Context, resp AnalyzeResp) (map[int]SentenceTranslation, int, error) {
+	inputs := sentenceInputs(resp)
+	merged, pending := cachedGlosses(ctx, inputs)
+	if len(pending) == 0 {
+		return merged, len(inputs), nil
+	}
+	if a.provider == nil {
+		return merged, len(inputs), ai.ErrNotConfigured
+	}
+
+	result, err := a.translateSentences(ctx, pending, nil)
+	for k, v := range result {
+		merged[k] = v
+	}
+	return merged, len(inputs), err
+}
+
+// streamTranslation 流式模式的翻译阶段：缓存命中的句子先推送，之后每完成一批推送一次
+func streamTranslation(ctx context.Context, s *stream.Stream, a *analysis, resp AnalyzeResp) {
+	inputs := sentenceInputs(resp)
+	if len(inputs) == 0 {
+		return
+	}
+	cached, pending := cachedGlosses(ctx, inputs)
+	total := (len(pending) + ai.DefaultTranslateBatch - 1) / ai.DefaultTranslateBatch
+	s.Publish("progress", ProgressEvent{Stage: stageTranslate, Done: 0, Total: total})
+
+	if len(cached) > 0 {
+		s.Publish("translation", TranslationEvent{Sentences: translationList(cached)})
+	}
+	if len(pending) == 0 {
+		return
+	}
+	if a.provider == nil {
+		s.Publish("error", ErrorEvent{Stage: stageTranslate, Message: ai.ErrNotConfigured.Error(), Sentences: sentenceIndexes(pending)})
+		return
+	}
+
+	done := 0
+	a.translateSentences(ctx, pending, func(res ai.TranslateResult, batch []SentenceTranslation) {
+		done++
+		if res.Err != nil {
+			idx := res.Index
+			s.Publish("error", ErrorEvent{Stage: stageTranslate, Message: res.Err.Error(), Chunk: &idx, Sentences: res.Sentences})
+		} else {
+			s.Publish("translation", TranslationEvent{Sentences: batch})
+		}
+		s.Publish("progress", ProgressEvent{Stage: stageTranslate, Done: done, Total: res.Total})
+	})
+}
+
+// applyGlosses 把翻译填入句子列表 (同步模式)
+func applyGlosses(sentences []Sentence, glosses map[int]SentenceTranslation) {
+	for i := range sentences {
+		if g, ok := glosses[sentences[i].Index]; ok {
+			sentences[i].Translation = g.Translation
+			sentences[i].GrammarNote = g.GrammarNote
+		}
+	}
+}
+
+// translateStatus 同步模式下翻译阶段的状态说明 (取值同 aiStatus)
+func translateStatus(total int, glosses map[int]SentenceTranslation, err error) (string, string) {
+	switch {
+	case total == 0:
+		return StageSkipped, ""
+	case err != nil && len(glosses) > 0:
+		return StagePartial, err.Error()
+	case err != nil:
+		return StageFailed, err.Error()
+	default:
+		return StageApplied, ""
+	}
+}
+
+func translationList(m map[int]SentenceTranslation) []SentenceTranslation {
+	list := make([]SentenceTranslation, 0, len(m))
+	for _, t := range m {
+		list = append(list, t)
+	}
+	sortTranslations(list)
+	return list
+}
+
+func sortTranslations(list []SentenceTranslation) {
+	sort.Slice(list, func(i, j int) bool { return list[i].Sentence < list[j].Sentence })
+}
+
+func sentenceIndexes(inputs []ai.SentenceInput) []int {
+	out := make([]int, len(inputs))
+	for i, in := range inputs {
+		out[i] = in.Index
+	}
+	return out
+}
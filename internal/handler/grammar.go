@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"dongwai_backend/internal/dto"
+	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/grammar"
+	"dongwai_backend/internal/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// --- DTO ---
+
+type GrammarExampleReq struct {
+	Kanji    string `json:"kanji"`
+	Def      string `json:"def"`
+	Furigana any    `json:"furigana"`
+}
+
+type CreateGrammarReq struct {
+	Name        string              `json:"name" binding:"required"`
+	Pattern     string              `json:"pattern" binding:"required"` // 匹配规则，见 internal/pkg/grammar
+	Level       string              `json:"level"`
+	Meaning     string              `json:"meaning"`
+	Explanation string              `json:"explanation"`
+	Examples    []GrammarExampleReq `json:"examples"`
+}
+
+type UpdateGrammarReq struct {
+	ID string `json:"id" binding:"required"`
+	CreateGrammarReq
+}
+
+type ListGrammarReq struct {
+	Page     int    `json:"page"`
+	PageSize int    `json:"page_size"`
+	Keyword  string `json:"keyword"`
+	Level    string `json:"level"`
+}
+
+// --- Handler ---
+
+// CreateGrammar 创建语法点
+func CreateGrammar(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateGrammarReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, err := grammar.Compile(req.Pattern); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "匹配规则无效: " + err.Error()})
+			return
+		}
+
+		id := utils.GenerateID("g_", req.Name, uuid.New().String())
+		pattern := model.GrammarPattern{
+			ID:          id,
+			Name:        req.Name,
+			Pattern:     req.Pattern,
+			Level:       req.Level,
+			Meaning:     req.Meaning,
+			Explanation: req.Explanation,
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&pattern).Error; err != nil {
+				return err
+			}
+			return createGrammarExamples(tx, id, req.Examples)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存失败: " + err.Error()})
+			return
+		}
+
+		reloadGrammar(db)
+		c.JSON(http.StatusOK, gin.H{"id": id, "message": "创建成功", "data": req})
+	}
+}
+
+// UpdateGrammar 修改语法点 (例句整体替换)
+func UpdateGrammar(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req UpdateGrammarReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, err := grammar.Compile(req.Pattern); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "匹配规则无效: " + err.Error()})
+			return
+		}
+
+		var existing model.GrammarPattern
+		if err := db.Select("id").First(&existing, "id = ?", req.ID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "语法点不存在"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&model.GrammarPattern{}).Where("id = ?", req.ID).Updates(map[string]interface{}{
+				"name":        req.Name,
+				"pattern":     req.Pattern,
+				"level":       req.Level,
+				"meaning":     req.Meaning,
+				"explanation": req.Explanation,
+			}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("pattern_id = ?", req.ID).Delete(&model.GrammarExample{}).Error; err != nil {
+				return err
+			}
+			return createGrammarExamples(tx, req.ID, req.Examples)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败: " + err.Error()})
+			return
+		}
+
+		reloadGrammar(db)
+		c.JSON(http.StatusOK, gin.H{"message": "更新成功"})
+	}
+}
+
+// DeleteGrammar 删除语法点
+func DeleteGrammar(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		var existing model.GrammarPattern
+		if err := db.Select("id").First(&existing, "id = ?", id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "语法点不存在"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("pattern_id = ?", id).Delete(&model.GrammarExample{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&model.GrammarPattern{}, "id = ?", id).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败"})
+			return
+		}
+
+		reloadGrammar(db)
+		c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+	}
+}
+
+// ListGrammar 分页获取语法点列表 (可按名称关键词与等级筛选)
+func ListGrammar(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ListGrammarReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			req.Page = 1
+			req.PageSize = 20
+		}
+		if req.Page < 1 {
+			req.Page = 1
+		}
+		if req.PageSize < 1 {
+			req.PageSize = 20
+		}
+
+		query := db.Model(&model.GrammarPattern{})
+		if req.Keyword != "" {
+			query = query.Where("name LIKE ? OR meaning LIKE ?", "%"+req.Keyword+"%", "%"+req.Keyword+"%")
+		}
+		if req.Level != "" {
+			query = query.Where("level = ?", req.Level)
+		}
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+			return
+		}
+
+		var patterns []model.GrammarPattern
+		err := query.
+			Order("level DESC, name ASC").
+			Offset((req.Page - 1) * req.PageSize).
+			Limit(req.PageSize).
+			Find(&patterns).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取列表失败"})
+			return
+		}
+
+		list := make([]dto.GrammarDTO, 0, len(patterns))
+		for _, p := range patterns {
+			list = append(list, dto.ToGrammarDTO(p))
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"total": total,
+			"list":  list,
+		})
+	}
+}
+
+// GetGrammarDetail 获取语法点详情 (含例句)
+func GetGrammarDetail(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req WordDetailReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误，需要 id"})
+			return
+		}
+
+		var pattern model.GrammarPattern
+		if err := db.Preload("Examples").First(&pattern, "id = ?", req.ID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "语法点不存在"})
+			return
+		}
+
+		c.JSON(http.StatusOK, dto.ToGrammarDTO(pattern))
+	}
+}
+
+func createGrammarExamples(tx *gorm.DB, patternID string, reqs []GrammarExampleReq) error {
+	if len(reqs) == 0 {
+		return nil
+	}
+	examples := make([]model.GrammarExample, 0, len(reqs))
+	for _, ex := range reqs {
+		examples = append(examples, model.GrammarExample{
+			ID:        utils.GenerateID("ge_", patternID, uuid.New().String()),
+			PatternID: patternID,
+			Kanji:     ex.Kanji,
+			Def:       ex.Def,
+			Furigana:  datatypes.JSON(utils.ToJSON(ex.Furigana)),
+		})
+	}
+	return tx.Create(&examples).Error
+}
+
+// reloadGrammar 语法点变更后刷新文章分析使用的匹配器
+func reloadGrammar(db *gorm.DB) {
+	if err := grammar.Reload(db); err != nil {
+		log.Printf("刷新语法点失败: %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// --- 测试用的内存数据库驱动 ---
+// 只支持查询：按 SQL 中的 FROM "表名" 返回预置的全部行 (忽略 WHERE)，
+// 足以覆盖 Preload 之类的只读路径；写操作一律返回成功 (默认影响 0 行)。
+// 需要时可按 SQL 片段指定查询结果与影响行数 (onQuery / onExec)，并检查执行过的语句 (stmts)。
+
+type fakeTable struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+// fakeDB 一个测试用例的数据库
+type fakeDB struct {
+	tables map[string]fakeTable
+
+	mu       sync.Mutex
+	queries  []fakeRule // 按 SQL 片段匹配的查询结果，后登记的优先 (便于分阶段改变结果)，优先于 tables
+	affected []fakeRule // 按 SQL 片段匹配的影响行数，后登记的优先
+	log      []fakeStmtLog
+}
+
+type fakeRule struct {
+	match string
+	table fakeTable
+	rows  int64
+}
+
+// fakeStmtLog 执行过的语句及参数
+type fakeStmtLog struct {
+	query string
+	args  []driver.Value
+}
+
+var (
+	fakeDatasetsMu sync.Mutex
+	fakeDatasets   = map[string]*fakeDB{}
+	registerOnce   sync.Once
+	fromTable      = regexp.MustCompile(`FROM "([a-z_]+)"`)
+)
+
+// newTestDB 用给定的表数据创建 gorm 连接
+func newTestDB(t *testing.T, tables map[string]fakeTable) *gorm.DB {
+	t.Helper()
+	db, _ := newFakeDB(t, tables)
+	return db
+}
+
+// newFakeDB 同 newTestDB，同时返回可设定结果、检查语句的 fakeDB
+func newFakeDB(t *testing.T, tables map[string]fakeTable) (*gorm.DB, *fakeDB) {
+	t.Helper()
+	registerOnce.Do(func() { sql.Register("handler_fakedb", fakeDriver{}) })
+
+	fake := &fakeDB{tables: tables}
+	fakeDatasetsMu.Lock()
+	fakeDatasets[t.Name()] = fake
+	fakeDatasetsMu.Unlock()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DriverName: "handler_fakedb", DSN: t.Name()}), &gorm.Config{
+		Logger:                 logger.Discard,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("open fake db: %v", err)
+	}
+	return db, fake
+}
+
+// onQuery SQL 中包含 match 的查询返回 table
+func (f *fakeDB) onQuery(match string, table fakeTable) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = append(f.queries, fakeRule{match: match, table: table})
+}
+
+// onExec SQL 中包含 match 的写操作影响 rows 行
+func (f *fakeDB) onExec(match string, rows int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.affected = append(f.affected, fakeRule{match: match, rows: rows})
+}
+
+// stmts 执行过的、SQL 中包含 match 的语句
+func (f *fakeDB) stmts(match string) []fakeStmtLog {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []fakeStmtLog
+	for _, l := range f.log {
+		if strings.Contains(l.query, match) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func (f *fakeDB) record(query string, args []driver.NamedValue) {
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+	f.mu.Lock()
+	f.log = append(f.log, fakeStmtLog{query: query, args: values})
+	f.mu.Unlock()
+}
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	fakeDatasetsMu.Lock()
+	defer fakeDatasetsMu.Unlock()
+	return &fakeConn{db: fakeDatasets[dsn]}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.record(query, args)
+	return c.query(query), nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.record(query, args)
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	for i := len(c.db.affected) - 1; i >= 0; i-- {
+		if r := c.db.affected[i]; strings.Contains(query, r.match) {
+			return driver.RowsAffected(r.rows), nil
+		}
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeConn) query(query string) driver.Rows {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	for i := len(c.db.queries) - 1; i >= 0; i-- {
+		if r := c.db.queries[i]; strings.Contains(query, r.match) {
+			return &fakeRows{table: r.table}
+		}
+	}
+	var table fakeTable
+	if m := fromTable.FindStringSubmatch(query); m != nil {
+		table = c.db.tables[m[1]]
+	}
+	return &fakeRows{table: table}
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) { return s.conn.query(s.query), nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	table fakeTable
+	pos   int
+}
+
+func (r *fakeRows) Columns() []string { return r.table.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.table.rows) {
+		return io.EOF
+	}
+	copy(dest, r.table.rows[r.pos])
+	r.pos++
+	return nil
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"dongwai_backend/internal/pkg/ai"
+	"dongwai_backend/internal/pkg/usage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// healthReporter 能报告自身状态的 Provider (例如 ai.ResilientProvider)
+type healthReporter interface {
+	Health() ai.Health
+}
+
+// Health 健康检查：服务存活，并展示 AI 客户端层的熔断与重试状态
+// AI 不可用不影响本接口的状态码，只把 status 标记为 degraded
+func Health(provider ai.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := "ok"
+		aiInfo := gin.H{"configured": provider != nil}
+
+		if r, ok := provider.(healthReporter); ok {
+			h := r.Health()
+			aiInfo["client"] = h
+			if h.Circuit != ai.CircuitClosed {
+				status = "degraded"
+			}
+		}
+		if provider == nil {
+			status = "degraded"
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": status, "ai": aiInfo})
+	}
+}
+
+// aiErrorStatus AI 调用失败时返回给前端的状态码
+func aiErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ai.ErrNotConfigured), errors.Is(err, ai.ErrCircuitOpen):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, usage.ErrQuotaExceeded):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusBadGateway
+	}
+}
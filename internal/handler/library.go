@@ -0,0 +1,718 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/ai"
+	"dongwai_backend/internal/pkg/auth"
+	"dongwai_backend/internal/pkg/segment"
+	"dongwai_backend/internal/pkg/usage"
+	"dongwai_backend/internal/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ========================================
+// 文章库：保存的文章、分析快照与教师修正
+// ========================================
+
+// --- DTO ---
+
+type CreateArticleReq struct {
+	Title    string   `json:"title" binding:"required"`
+	Source   string   `json:"source"`
+	Content  string   `json:"content" binding:"required"`
+	Tags     []string `json:"tags"`
+	Strategy string   `json:"strategy"` // 首次分析使用的分词策略
+}
+
+// UpdateArticleReq 修改文章，未提供的字段保持不变
+// 修改正文不会自动重新分析，此时快照标记为 stale
+type UpdateArticleReq struct {
+	Title   *string   `json:"title"`
+	Source  *string   `json:"source"`
+	Content *string   `json:"content"`
+	Tags    *[]string `json:"tags"`
+}
+
+type ReanalyzeArticleReq struct {
+	Strategy  string `json:"strategy"`
+	Translate bool   `json:"translate"`
+}
+
+type SetOverrideReq struct {
+	Start   int    `json:"start"` // Token 的 rune 起始下标 (对应 Token.Start)
+	SenseID string `json:"sense_id" binding:"required"`
+}
+
+type ArticleResp struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Source    string    `json:"source"`
+	Content   string    `json:"content,omitempty"` // 列表中不返回
+	OwnerID   string    `json:"owner_id"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// 最新的分析结果 (已应用教师修正)，列表中不返回
+	Analysis  *ArticleAnalysisResp  `json:"analysis,omitempty"`
+	Overrides []ArticleOverrideResp `json:"overrides,omitempty"`
+}
+
+type ArticleAnalysisResp struct {
+	ID        string       `json:"id"`
+	Strategy  string       `json:"strategy"`
+	CreatedBy string       `json:"created_by"`
+	CreatedAt time.Time    `json:"created_at"`
+	Stale     bool         `json:"stale"` // 分析后正文已被修改
+	Result    *AnalyzeResp `json:"result,omitempty"`
+}
+
+type ArticleOverrideResp struct {
+	ID        string    `json:"id"`
+	Start     int       `json:"start"`
+	End       int       `json:"end"`
+	Text      string    `json:"text"`
+	VocabID   string    `json:"vocab_id"`
+	SenseID   string    `json:"sense_id"`
+	CreatedBy string    `json:"created_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// 是否在当前快照中生效 (该位置的原文变化或释义不再是候选时失效，但仍保留)
+	Applied bool `json:"applied"`
+}
+
+// --- Handler ---
+
+// CreateArticle 保存文章并立即分析一次 (同步，含 AI 消歧)
+func CreateArticle(db *gorm.DB, provider ai.Provider, chunkOpts ai.ChunkOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateArticleReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		segmenter, ok := segment.ByName(req.Strategy)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的分词策略: " + req.Strategy})
+			return
+		}
+
+		userID := c.GetString("userID")
+		article := model.Article{
+			ID:      uuid.New().String(),
+			Title:   req.Title,
+			Source:  req.Source,
+			Content: req.Content,
+			OwnerID: userID,
+			Tags:    datatypes.JSON(utils.ToJSON(normalizeTags(req.Tags))),
+		}
+
+		snapshot, resp, err := analyzeArticle(c, db, article, segmenter, provider, chunkOpts, false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询词库失败"})
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&article).Error; err != nil {
+				return err
+			}
+			return tx.Create(&snapshot).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存失败: " + err.Error()})
+			return
+		}
+
+		out := toArticleResp(article, true)
+		out.Analysis = toAnalysisResp(snapshot, article, &resp)
+		out.Overrides = []ArticleOverrideResp{}
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+// ListArticles 文章列表 (不含正文与分析结果)
+// 查询参数: page，page_size，keyword (标题)，tag，mine=1 只看自己的文章
+func ListArticles(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+		if page < 1 {
+			page = 1
+		}
+		if pageSize < 1 || pageSize > 100 {
+			pageSize = 20
+		}
+
+		query := db.Model(&model.Article{})
+		if keyword := c.Query("keyword"); keyword != "" {
+			query = query.Where("title LIKE ?", "%"+keyword+"%")
+		}
+		if tag := c.Query("tag"); tag != "" {
+			query = query.Where("tags @> ?", string(utils.ToJSON([]string{tag})))
+		}
+		if c.Query("mine") == "1" {
+			query = query.Where("owner_id = ?", c.GetString("userID"))
+		}
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+			return
+		}
+
+		var articles []model.Article
+		err := query.Omit("content").Order("updated_at DESC").
+			Offset((page - 1) * pageSize).Limit(pageSize).Find(&articles).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+			return
+		}
+
+		list := make([]ArticleResp, 0, len(articles))
+		for _, a := range articles {
+			list = append(list, toArticleResp(a, false))
+		}
+		c.JSON(http.StatusOK, gin.H{"total": total, "list": list})
+	}
+}
+
+// GetArticle 文章详情：正文、最新的分析快照 (已应用教师修正) 与全部修正
+func GetArticle(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var article model.Article
+		if err := db.First(&article, "id = ?", c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "文章不存在"})
+			return
+		}
+
+		out, err := articleDetail(db, article)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+// UpdateArticle 修改文章 (仅作者或管理员)
+// 正文有变化时按改动平移教师修正的位置 (见 shiftOverrides)
+func UpdateArticle(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req UpdateArticleReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		article, ok := loadManagedArticle(c, db)
+		if !ok {
+			return
+		}
+
+		updates := map[string]interface{}{}
+		if req.Title != nil {
+			if strings.TrimSpace(*req.Title) == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "标题不能为空"})
+				return
+			}
+			updates["title"] = *req.Title
+		}
+		if req.Source != nil {
+			updates["source"] = *req.Source
+		}
+		if req.Content != nil {
+			updates["content"] = *req.Content
+		}
+		if req.Tags != nil {
+			updates["tags"] = datatypes.JSON(utils.ToJSON(normalizeTags(*req.Tags)))
+		}
+		if len(updates) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要修改的字段"})
+			return
+		}
+
+		// Updates 会把新值写回 article，先判断正文是否有变化
+		contentChanged := req.Content != nil && *req.Content != article.Content
+		oldContent := article.Content
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&article).Updates(updates).Error; err != nil {
+				return err
+			}
+			if !contentChanged {
+				return nil
+			}
+			return shiftOverrides(tx, article.ID, oldContent, *req.Content)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "更新成功"})
+	}
+}
+
+// shiftOverrides 正文修改后调整教师修正的位置
+// 按公共前缀、后缀找出改动的区域：之前的修正不变，之后的修正按长度变化平移，
+// 与改动区域重叠的修正留在原处，由 applyOverrides 按原文判断是否还生效
+func shiftOverrides(tx *gorm.DB, articleID, oldContent, newContent string) error {
+	old, cur := []rune(oldContent), []rune(newContent)
+	prefix := 0
+	for prefix < len(old) && prefix < len(cur) && old[prefix] == cur[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(cur)-prefix && old[len(old)-1-suffix] == cur[len(cur)-1-suffix] {
+		suffix++
+	}
+	delta := len(cur) - len(old)
+	if delta == 0 {
+		return nil
+	}
+
+	// 改动区域变短时，平移后的修正可能落在改动处原有修正的位置上 (同一位置只能有一条修正)，
+	// 这些修正指向的原文已被改掉，先删除
+	oldEnd := len(old) - suffix
+	if delta < 0 {
+		err := tx.Where("article_id = ? AND start >= ? AND start < ?", articleID, oldEnd+delta, oldEnd).
+			Delete(&model.ArticleOverride{}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	// 逐条平移：变长时从后往前、变短时从前往后，避免途中与相邻修正的位置冲突
+	order := "start"
+	if delta > 0 {
+		order = "start DESC"
+	}
+	var overrides []model.ArticleOverride
+	if err := tx.Where("article_id = ? AND start >= ?", articleID, oldEnd).Order(order).Find(&overrides).Error; err != nil {
+		return err
+	}
+	for _, o := range overrides {
+		err := tx.Model(&model.ArticleOverride{}).Where("id = ?", o.ID).
+			Updates(map[string]interface{}{"start": o.Start + delta, "end": o.End + delta}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteArticle 删除文章及其分析快照与修正 (仅作者或管理员)
+func DeleteArticle(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		article, ok := loadManagedArticle(c, db)
+		if !ok {
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("article_id = ?", article.ID).Delete(&model.ArticleOverride{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("article_id = ?", article.ID).Delete(&model.ArticleAnalysis{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&model.Article{}, "id = ?", article.ID).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+	}
+}
+
+// ReanalyzeArticle 按当前正文重新分析并保存新的快照 (仅作者或管理员)
+// 教师修正不受影响：该位置 Token 的起止与原文未变的修正在新快照上继续生效
+func ReanalyzeArticle(db *gorm.DB, provider ai.Provider, chunkOpts ai.ChunkOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ReanalyzeArticleReq
+		// 请求体可以为空
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		segmenter, ok := segment.ByName(req.Strategy)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的分词策略: " + req.Strategy})
+			return
+		}
+
+		article, ok := loadManagedArticle(c, db)
+		if !ok {
+			return
+		}
+
+		snapshot, _, err := analyzeArticle(c, db, article, segmenter, provider, chunkOpts, req.Translate)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询词库失败"})
+			return
+		}
+		if err := db.Create(&snapshot).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存失败: " + err.Error()})
+			return
+		}
+
+		out, err := articleDetail(db, article)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+// ListArticleAnalyses 文章的分析快照列表 (不含结果，按时间倒序)
+func ListArticleAnalyses(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var article model.Article
+		if err := db.First(&article, "id = ?", c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "文章不存在"})
+			return
+		}
+
+		var snapshots []model.ArticleAnalysis
+		err := db.Omit("result").Where("article_id = ?", article.ID).
+			Order("created_at DESC").Find(&snapshots).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+			return
+		}
+
+		list := make([]ArticleAnalysisResp, 0, len(snapshots))
+		for _, s := range snapshots {
+			list = append(list, *toAnalysisResp(s, article, nil))
+		}
+		c.JSON(http.StatusOK, gin.H{"list": list})
+	}
+}
+
+// SetArticleOverride 教师修正某个 Token 的选中释义 (仅作者或管理员，按最新快照校验位置与候选释义)
+// 同时记录该位置的原文，应用时原文不一致的修正不会生效
+func SetArticleOverride(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req SetOverrideReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		article, ok := loadManagedArticle(c, db)
+		if !ok {
+			return
+		}
+		snapshot, resp, err := latestSnapshot(db, article.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+			return
+		}
+		if snapshot == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "文章尚未分析"})
+			return
+		}
+		// 快照早于正文的修改时，其中的位置对应的是旧正文
+		if snapshot.ContentHash != contentHash(article.Content) {
+			c.JSON(http.StatusConflict, gin.H{"error": "文章已修改，请先重新分析"})
+			return
+		}
+
+		token := tokenAt(resp, req.Start)
+		if token == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "该位置没有单词"})
+			return
+		}
+		var sense *WordDetail
+		for i := range token.Candidates {
+			if token.Candidates[i].SenseID == req.SenseID {
+				sense = &token.Candidates[i]
+				break
+			}
+		}
+		if sense == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "该释义不是此处单词的候选释义"})
+			return
+		}
+
+		override := model.ArticleOverride{
+			ID:        uuid.New().String(),
+			ArticleID: article.ID,
+			Start:     token.Start,
+			End:       token.End,
+			Text:      token.Text,
+			VocabID:   sense.VocabID,
+			SenseID:   sense.SenseID,
+			CreatedBy: c.GetString("userID"),
+		}
+		// 同一位置只保留最新的修正
+		err = db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "article_id"}, {Name: "start"}},
+			DoUpdates: clause.AssignmentColumns([]string{"end", "text", "vocab_id", "sense_id", "created_by", "updated_at"}),
+		}).Create(&override).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存失败: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "修正成功", "override": toOverrideResp(override, true)})
+	}
+}
+
+// DeleteArticleOverride 撤销某个位置的教师修正，恢复分析结果的原始选择 (仅作者或管理员)
+func DeleteArticleOverride(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start, err := strconv.Atoi(c.Param("start"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "位置参数错误"})
+			return
+		}
+		article, ok := loadManagedArticle(c, db)
+		if !ok {
+			return
+		}
+
+		result := db.Where("article_id = ? AND start = ?", article.ID, start).Delete(&model.ArticleOverride{})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败"})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "该位置没有修正"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "已撤销修正"})
+	}
+}
+
+// --- 内部逻辑 ---
+
+// analyzeArticle 同步分析文章正文，返回待保存的快照与分析结果
+func analyzeArticle(c *gin.Context, db *gorm.DB, article model.Article, segmenter segment.Segmenter, provider ai.Provider, chunkOpts ai.ChunkOptions, translate bool) (model.ArticleAnalysis, AnalyzeResp, error) {
+	a, err := newAnalysis(db, article.Content, segmenter)
+	if err != nil {
+		return model.ArticleAnalysis{}, AnalyzeResp{}, err
+	}
+	a.provider, a.chunkOpts, a.translate = provider, chunkOpts, translate
+
+	userID := c.GetString("userID")
+	resp := a.run(usage.WithUser(c.Request.Context(), userID))
+	snapshot := model.ArticleAnalysis{
+		ID:          uuid.New().String(),
+		ArticleID:   article.ID,
+		Strategy:    segmenter.Name(),
+		ContentHash: contentHash(article.Content),
+		Result:      datatypes.JSON(utils.ToJSON(resp)),
+		CreatedBy:   userID,
+	}
+	return snapshot, resp, nil
+}
+
+// articleDetail 组装文章详情：最新快照应用全部修正后返回
+func articleDetail(db *gorm.DB, article model.Article) (ArticleResp, error) {
+	out := toArticleResp(article, true)
+
+	var overrides []model.ArticleOverride
+	if err := db.Where("article_id = ?", article.ID).Order("start").Find(&overrides).Error; err != nil {
+		return out, err
+	}
+
+	snapshot, resp, err := latestSnapshot(db, article.ID)
+	if err != nil {
+		return out, err
+	}
+
+	applied := map[string]bool{}
+	if snapshot != nil {
+		applied = applyOverrides(&resp, overrides)
+		out.Analysis = toAnalysisResp(*snapshot, article, &resp)
+	}
+
+	out.Overrides = make([]ArticleOverrideResp, 0, len(overrides))
+	for _, o := range overrides {
+		out.Overrides = append(out.Overrides, toOverrideResp(o, applied[o.ID]))
+	}
+	return out, nil
+}
+
+// latestSnapshot 文章最新的分析快照，没有时返回 nil
+func latestSnapshot(db *gorm.DB, articleID string) (*model.ArticleAnalysis, AnalyzeResp, error) {
+	var snapshots []model.ArticleAnalysis
+	if err := db.Where("article_id = ?", articleID).Order("created_at DESC").Limit(1).Find(&snapshots).Error; err != nil {
+		return nil, AnalyzeResp{}, err
+	}
+	if len(snapshots) == 0 {
+		return nil, AnalyzeResp{}, nil
+	}
+
+	var resp AnalyzeResp
+	if err := json.Unmarshal(snapshots[0].Result, &resp); err != nil {
+		return nil, AnalyzeResp{}, err
+	}
+	return &snapshots[0], resp, nil
+}
+
+// applyOverrides 在分析结果上应用教师修正，返回生效的修正 ID
+// 修正只在该位置的 Token 起止与原文都未变、且释义仍在候选中时生效；
+// 生效后重新计算语法点与难度画像，侧边栏中对应的单词一并更新
+func applyOverrides(resp *AnalyzeResp, overrides []model.ArticleOverride) map[string]bool {
+	applied := make(map[string]bool)
+	for _, o := range overrides {
+		token := tokenAt(*resp, o.Start)
+		if token == nil || token.End != o.End || token.Text != o.Text {
+			continue
+		}
+		candidates, selected := selectCandidate(token.Candidates, o.SenseID)
+		if selected == nil {
+			continue
+		}
+
+		if token.Detail != nil {
+			for i, w := range resp.VocabList {
+				if w.Detail.SenseID != token.Detail.SenseID {
+					continue
+				}
+				if list, d := selectCandidate(w.Candidates, o.SenseID); d != nil {
+					resp.VocabList[i].Candidates, resp.VocabList[i].Detail = list, *d
+				}
+			}
+		}
+
+		token.Candidates, token.Detail = candidates, selected
+		if len(candidates) > 1 {
+			token.Disambiguation = DisambigTeacher
+		}
+		applied[o.ID] = true
+	}
+
+	if len(applied) > 0 {
+		resp.Grammar = matchGrammar(resp.Tokens)
+		resp.Profile = buildProfile(resp.Tokens)
+	}
+	return applied
+}
+
+// selectCandidate 返回选中 senseID 后的候选列表 (新 slice) 与选中项，不在候选中时返回 nil
+func selectCandidate(candidates []WordDetail, senseID string) ([]WordDetail, *WordDetail) {
+	var selected *WordDetail
+	list := make([]WordDetail, len(candidates))
+	for i, d := range candidates {
+		d.Selected = d.SenseID == senseID
+		list[i] = d
+		if d.Selected {
+			s := d
+			selected = &s
+		}
+	}
+	if selected == nil {
+		return nil, nil
+	}
+	return list, selected
+}
+
+// tokenAt 找到起始于 start 的单词 Token
+func tokenAt(resp AnalyzeResp, start int) *Token {
+	for i := range resp.Tokens {
+		if t := &resp.Tokens[i]; t.IsWord && t.Start == start {
+			return t
+		}
+	}
+	return nil
+}
+
+// loadManagedArticle 读取路径中的文章并校验当前用户可以管理 (作者或管理员)
+// 失败时已写入响应
+func loadManagedArticle(c *gin.Context, db *gorm.DB) (model.Article, bool) {
+	var article model.Article
+	if err := db.First(&article, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文章不存在"})
+		return article, false
+	}
+
+	role, _ := c.Get("role")
+	if article.OwnerID != c.GetString("userID") && role != auth.Admin && role != auth.SuperAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有作者或管理员可以修改文章"})
+		return article, false
+	}
+	return article, true
+}
+
+func toArticleResp(a model.Article, withContent bool) ArticleResp {
+	tags := []string{}
+	if len(a.Tags) > 0 {
+		_ = json.Unmarshal(a.Tags, &tags)
+	}
+	out := ArticleResp{
+		ID:        a.ID,
+		Title:     a.Title,
+		Source:    a.Source,
+		OwnerID:   a.OwnerID,
+		Tags:      tags,
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+	}
+	if withContent {
+		out.Content = a.Content
+	}
+	return out
+}
+
+func toAnalysisResp(s model.ArticleAnalysis, article model.Article, result *AnalyzeResp) *ArticleAnalysisResp {
+	return &ArticleAnalysisResp{
+		ID:        s.ID,
+		Strategy:  s.Strategy,
+		CreatedBy: s.CreatedBy,
+		CreatedAt: s.CreatedAt,
+		Stale:     s.ContentHash != contentHash(article.Content),
+		Result:    result,
+	}
+}
+
+func toOverrideResp(o model.ArticleOverride, applied bool) ArticleOverrideResp {
+	return ArticleOverrideResp{
+		ID:        o.ID,
+		Start:     o.Start,
+		End:       o.End,
+		Text:      o.Text,
+		VocabID:   o.VocabID,
+		SenseID:   o.SenseID,
+		CreatedBy: o.CreatedBy,
+		UpdatedAt: o.UpdatedAt,
+		Applied:   applied,
+	}
+}
+
+// normalizeTags 去掉空白与重复的标签
+func normalizeTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	return out
+}
+
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
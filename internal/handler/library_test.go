@@ -0,0 +1,273 @@
+package handler
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/ai"
+	"dongwai_backend/internal/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// analyzedResp 不经 AI 分析 "日本語を勉強する。" (勉強 默认选第一个释义)
+func analyzedResp(t *testing.T) AnalyzeResp {
+	t.Helper()
+	db := setupAnalyze(t)
+	w := postAnalyze(t, db, nil, "?mode=sync", "日本語を勉強する。")
+	var resp AnalyzeResp
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestApplyOverrides(t *testing.T) {
+	resp := analyzedResp(t)
+	tok := findToken(resp, "v_benkyou")
+	if tok == nil || tok.Detail.SenseID != "s_benkyou_1" {
+		t.Fatalf("unexpected token %+v", tok)
+	}
+
+	overrides := []model.ArticleOverride{
+		{ID: "o1", Start: tok.Start, End: tok.End, Text: "勉強", SenseID: "s_benkyou_2"},
+		// 该位置的原文已变化
+		{ID: "o2", Start: 0, End: 3, Text: "英語", SenseID: "s_nihongo_1"},
+		// 不是候选释义
+		{ID: "o3", Start: tok.Start, End: tok.End, Text: "勉強", SenseID: "s_other"},
+	}
+	applied := applyOverrides(&resp, overrides)
+	if !applied["o1"] || applied["o2"] || applied["o3"] {
+		t.Errorf("applied = %v", applied)
+	}
+
+	tok = findToken(resp, "v_benkyou")
+	if tok.Detail.SenseID != "s_benkyou_2" || tok.Disambiguation != DisambigTeacher {
+		t.Errorf("token = %+v", tok.Detail)
+	}
+	for _, c := range tok.Candidates {
+		if c.Selected != (c.SenseID == "s_benkyou_2") {
+			t.Errorf("candidate %s selected = %v", c.SenseID, c.Selected)
+		}
+	}
+	for _, w := range resp.VocabList {
+		if w.Text == "勉強" && w.Detail.SenseID != "s_benkyou_2" {
+			t.Errorf("vocab list not updated: %+v", w.Detail)
+		}
+	}
+}
+
+func TestGetArticleAppliesOverrides(t *testing.T) {
+	resp := analyzedResp(t)
+	tok := findToken(resp, "v_benkyou")
+	content := "日本語を勉強する。"
+	now := time.Now()
+
+	db := newTestDB(t, map[string]fakeTable{
+		"articles": {
+			columns: []string{"id", "title", "content", "owner_id", "tags"},
+			rows:    [][]driver.Value{{"a1", "标题", content, "u1", `["N5"]`}},
+		},
+		"article_analyses": {
+			columns: []string{"id", "article_id", "strategy", "content_hash", "result", "created_at"},
+			rows:    [][]driver.Value{{"s1", "a1", "lattice", contentHash(content), string(mustJSON(t, resp)), now}},
+		},
+		"article_overrides": {
+			columns: []string{"id", "article_id", "start", "end", "text", "sense_id"},
+			rows:    [][]driver.Value{{"o1", "a1", int64(tok.Start), int64(tok.End), "勉強", "s_benkyou_2"}},
+		},
+	})
+
+	r := gin.New()
+	r.GET("/articles/:id", GetArticle(db))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/articles/a1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	var out ArticleResp
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Analysis == nil || out.Analysis.Stale || out.Analysis.Result == nil {
+		t.Fatalf("analysis = %+v", out.Analysis)
+	}
+	if got := findToken(*out.Analysis.Result, "v_benkyou"); got.Detail.SenseID != "s_benkyou_2" {
+		t.Errorf("override not applied: %+v", got.Detail)
+	}
+	if len(out.Overrides) != 1 || !out.Overrides[0].Applied {
+		t.Errorf("overrides = %+v", out.Overrides)
+	}
+	if len(out.Tags) != 1 || out.Tags[0] != "N5" {
+		t.Errorf("tags = %v", out.Tags)
+	}
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestArticleOverridesRequireOwner(t *testing.T) {
+	resp := analyzedResp(t)
+	tok := findToken(resp, "v_benkyou")
+	content := "日本語を勉強する。"
+	tables := map[string]fakeTable{
+		"articles": {
+			columns: []string{"id", "title", "content", "owner_id"},
+			rows:    [][]driver.Value{{"a1", "标题", content, "u1"}},
+		},
+		"article_analyses": {
+			columns: []string{"id", "article_id", "strategy", "content_hash", "result", "created_at"},
+			rows:    [][]driver.Value{{"s1", "a1", "lattice", contentHash(content), string(mustJSON(t, resp)), time.Now()}},
+		},
+	}
+	body := fmt.Sprintf(`{"start": %d, "sense_id": "s_benkyou_2"}`, tok.Start)
+
+	cases := []struct {
+		name   string
+		user   string
+		role   auth.AuthRole
+		tables map[string]fakeTable
+		want   int
+	}{
+		{"other teacher", "u2", auth.Teacher, tables, http.StatusForbidden},
+		{"owner", "u1", auth.Teacher, tables, http.StatusOK},
+		{"admin", "u3", auth.Admin, tables, http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, fake := newFakeDB(t, tc.tables)
+			fake.onExec(`DELETE FROM "article_overrides"`, 1)
+			r := gin.New()
+			r.Use(func(c *gin.Context) { c.Set("userID", tc.user); c.Set("role", tc.role) })
+			r.PUT("/articles/:id/overrides", SetArticleOverride(db))
+			r.DELETE("/articles/:id/overrides/:start", DeleteArticleOverride(db))
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/articles/a1/overrides", strings.NewReader(body)))
+			if w.Code != tc.want {
+				t.Errorf("set: status = %d, want %d: %s", w.Code, tc.want, w.Body)
+			}
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/articles/a1/overrides/%d", tok.Start), nil))
+			if w.Code != tc.want {
+				t.Errorf("delete: status = %d, want %d: %s", w.Code, tc.want, w.Body)
+			}
+			if tc.want == http.StatusForbidden && len(fake.stmts("article_overrides")) != 0 {
+				t.Errorf("non-owner touched overrides: %+v", fake.stmts("article_overrides"))
+			}
+		})
+	}
+}
+
+func TestSetArticleOverrideStaleSnapshot(t *testing.T) {
+	resp := analyzedResp(t)
+	tok := findToken(resp, "v_benkyou")
+	db := newTestDB(t, map[string]fakeTable{
+		"articles": {
+			columns: []string{"id", "title", "content", "owner_id"},
+			rows:    [][]driver.Value{{"a1", "标题", "今日は日本語を勉強する。", "u1"}},
+		},
+		// 快照是修改正文之前的分析
+		"article_analyses": {
+			columns: []string{"id", "article_id", "strategy", "content_hash", "result", "created_at"},
+			rows:    [][]driver.Value{{"s1", "a1", "lattice", contentHash("日本語を勉強する。"), string(mustJSON(t, resp)), time.Now()}},
+		},
+	})
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("userID", "u1") })
+	r.PUT("/articles/:id/overrides", SetArticleOverride(db))
+
+	w := httptest.NewRecorder()
+	body := fmt.Sprintf(`{"start": %d, "sense_id": "s_benkyou_2"}`, tok.Start)
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/articles/a1/overrides", strings.NewReader(body)))
+	if w.Code != http.StatusConflict {
+		t.Errorf("status = %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestUpdateArticleShiftsOverrides(t *testing.T) {
+	cases := []struct {
+		body    string
+		shifted map[string][2]int64 // 修正 ID -> 新的起止
+	}{
+		{`{"title": "新标题"}`, nil},
+		{`{"content": "日本語を勉強する。"}`, nil}, // 正文未变
+		{`{"content": "日本語を勉強した。"}`, nil}, // 改动在末尾，长度不变
+		// 开头插入 3 个字，之后的修正全部平移
+		{`{"content": "今日は日本語を勉強する。"}`, map[string][2]int64{"o1": {3, 6}, "o2": {7, 9}}},
+		// 改动在两条修正之间，只平移后面的
+		{`{"content": "日本語をたくさん勉強する。"}`, map[string][2]int64{"o2": {8, 10}}},
+	}
+	for _, tc := range cases {
+		db, fake := newFakeDB(t, map[string]fakeTable{
+			"articles": {
+				columns: []string{"id", "title", "content", "owner_id"},
+				rows:    [][]driver.Value{{"a1", "标题", "日本語を勉強する。", "u1"}},
+			},
+		})
+		r := gin.New()
+		r.Use(func(c *gin.Context) { c.Set("userID", "u1") })
+		r.PUT("/articles/:id", UpdateArticle(db))
+		// 假数据库忽略 WHERE，按查询条件返回应平移的修正
+		overrides := fakeTable{columns: []string{"id", "article_id", "start", "end", "text"}}
+		for _, o := range [][]driver.Value{{"o2", "a1", int64(4), int64(6), "勉強"}, {"o1", "a1", int64(0), int64(3), "日本語"}} {
+			if _, ok := tc.shifted[o[0].(string)]; ok {
+				overrides.rows = append(overrides.rows, o)
+			}
+		}
+		fake.onQuery(`FROM "article_overrides"`, overrides)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/articles/a1", strings.NewReader(tc.body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", tc.body, w.Code, w.Body)
+		}
+		if n := len(fake.stmts(`DELETE FROM "article_overrides"`)); n != 0 {
+			t.Errorf("%s: overrides deleted", tc.body)
+		}
+
+		got := map[string][2]int64{}
+		for _, st := range fake.stmts(`UPDATE "article_overrides"`) {
+			// SET "end"=$1,"start"=$2,"updated_at"=$3 WHERE id = $4
+			got[st.args[3].(string)] = [2]int64{st.args[1].(int64), st.args[0].(int64)}
+		}
+		if len(got) != len(tc.shifted) || (len(got) > 0 && !reflect.DeepEqual(got, tc.shifted)) {
+			t.Errorf("%s: shifted = %v, want %v", tc.body, got, tc.shifted)
+		}
+	}
+}
+
+func TestReanalyzeArticleBody(t *testing.T) {
+	db := setupAnalyze(t)
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("userID", "u1") })
+	r.POST("/articles/:id/analyze", ReanalyzeArticle(db, nil, ai.ChunkOptions{}))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/articles/a1/analyze", strings.NewReader(body)))
+		return w
+	}
+	if w := post(`{"strategy": `); w.Code != http.StatusBadRequest {
+		t.Errorf("malformed: status = %d: %s", w.Code, w.Body)
+	}
+	// 请求体可以为空 (文章不存在，走到了查文章这一步)
+	if w := post(""); w.Code != http.StatusNotFound {
+		t.Errorf("empty: status = %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+
+	"dongwai_backend/internal/pkg/segment"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AnalyzeProfile 只计算文章难度画像 (profile.Profile)，不调用 AI
+// 多义词优先采用消歧缓存中的结果，未命中时取第一个释义
+func AnalyzeProfile(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Content  string `json:"content"`
+			Strategy string `json:"strategy"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请提供文章内容"})
+			return
+		}
+
+		segmenter, ok := segment.ByName(req.Strategy)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的分词策略: " + req.Strategy})
+			return
+		}
+
+		a, err := newAnalysis(db, req.Content, segmenter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询词库失败"})
+			return
+		}
+
+		resp := a.response(a.cachedChoices(c.Request.Context()), true)
+		c.JSON(http.StatusOK, resp.Profile)
+	}
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dongwai_backend/internal/pkg/profile"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAnalyzeProfile(t *testing.T) {
+	db := setupAnalyze(t)
+	r := gin.New()
+	r.POST("/api/analyze/profile", AnalyzeProfile(db))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/analyze/profile", strings.NewReader(`{"content": "日本語を勉強する。"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var p profile.Profile
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	// 日本語 / 勉強 命中 (N5)，"を" 与 "する" 为未命中片段
+	if p.Characters != 8 || p.Words != 2 || p.UnmatchedSegments != 2 {
+		t.Errorf("profile = %+v", p)
+	}
+	if len(p.Coverage) != 5 || p.Coverage[0].Level != "N5" || p.Coverage[0].Words != 2 {
+		t.Errorf("coverage = %+v", p.Coverage)
+	}
+	if p.EstimatedLevel == "" {
+		t.Error("missing estimated_level")
+	}
+}
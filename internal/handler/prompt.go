@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"net/http"
+
+	"dongwai_backend/internal/pkg/prompt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListPrompts 列出提示词模板及当前生效的版本 (管理员)
+func ListPrompts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": prompt.Default.List()})
+	}
+}
+
+// ReloadPrompts 重新读取提示词覆盖目录，无需重启即可生效 (管理员)
+// 模板有错误时保留原有版本并返回 400
+func ReloadPrompts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := prompt.Default.Reload(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "提示词已重新加载", "data": prompt.Default.List()})
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"dongwai_backend/internal/pkg/usage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AIUsageReport AI 用量报表 (管理员)
+// 查询参数: from / to (YYYY-MM-DD，包含两端，默认最近 7 天)，
+// group_by (day,user,feature 任意组合，默认 day)，user_id，feature
+func AIUsageReport(db *gorm.DB, pricing usage.Pricing) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		y, m, d := time.Now().Date()
+		today := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+		from, err := parseDay(c.Query("from"), today.AddDate(0, 0, -6))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from 格式应为 YYYY-MM-DD"})
+			return
+		}
+		to, err := parseDay(c.Query("to"), today)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to 格式应为 YYYY-MM-DD"})
+			return
+		}
+
+		groupBy, err := usage.ParseGroupBy(c.DefaultQuery("group_by", usage.GroupDay))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		rows, err := usage.Report(db, usage.ReportQuery{
+			From:    from,
+			To:      to.AddDate(0, 0, 1),
+			GroupBy: groupBy,
+			UserID:  c.Query("user_id"),
+			Feature: c.Query("feature"),
+		}, pricing)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询用量失败"})
+			return
+		}
+		if rows == nil {
+			rows = []usage.ReportRow{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"from":     from.Format(time.DateOnly),
+			"to":       to.Format(time.DateOnly),
+			"group_by": groupBy,
+			"rows":     rows,
+		})
+	}
+}
+
+// parseDay 解析 YYYY-MM-DD (本地时区)，为空时返回默认值
+func parseDay(s string, fallback time.Time) (time.Time, error) {
+	if s == "" {
+		return fallback, nil
+	}
+	return time.ParseInLocation(time.DateOnly, s, time.Local)
+}
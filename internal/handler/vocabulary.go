@@ -1,17 +1,23 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
 	"dongwai_backend/internal/dto"
 	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/cache"
+	"dongwai_backend/internal/pkg/enrich"
 	"dongwai_backend/internal/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // --- DTO ---
@@ -20,8 +26,61 @@ type CreateCustomVocabReq struct {
 	Name        string `json:"name" binding:"required"`
 	Descript    string `json:"descript"`
 	WordListStr string `json:"word_list_str"` // 逗号分隔的单词字符串
+	// 词库中找不到的单词交给 AI 生成草稿，审核通过后自动加入词书
+	GenerateMissing bool `json:"generate_missing"`
 }
 
+// 导入结果 (ImportWordReport.Status)
+const (
+	ImportMatched    = "matched"    // 按原文精确匹配到一个单词
+	ImportNormalized = "normalized" // 规范化后 (全角/半角、旧字体、～ 标记等) 才匹配到一个单词
+	ImportAmbiguous  = "ambiguous"  // 匹配到多个同形词，全部加入词书
+	ImportMissing    = "missing"    // 词库中没有
+)
+
+// ImportWordReport 单个输入单词的导入结果
+type ImportWordReport struct {
+	Input      string   `json:"input"`
+	Status     string   `json:"status"`
+	VocabIDs   []string `json:"vocab_ids,omitempty"`
+	Kanji      []string `json:"kanji,omitempty"` // 匹配到的单词原文 (规范化匹配时可能与输入不同)
+	Normalized bool     `json:"normalized,omitempty"`
+	Queued     bool     `json:"queued,omitempty"` // 已排队生成草稿
+}
+
+// ImportSummary 导入结果汇总
+type ImportSummary struct {
+	Matched    int `json:"matched"`
+	Normalized int `json:"normalized"`
+	Ambiguous  int `json:"ambiguous"`
+	Missing    int `json:"missing"`
+	Queued     int `json:"queued"`
+}
+
+// UpdateVocabBookReq 修改词书信息，未提供的字段保持不变
+type UpdateVocabBookReq struct {
+	Name     *string `json:"name"`
+	Descript *string `json:"descript"`
+}
+
+// BookWordsReq 向词书添加或移除单词，三种写法可以混用
+type BookWordsReq struct {
+	Words       []string `json:"words"`         // 单词原文
+	WordListStr string   `json:"word_list_str"` // 逗号分隔的单词字符串
+	VocabIDs    []string `json:"vocab_ids"`
+}
+
+// UpdateBookWordCustomReq 修改单词在本词书中的自定义内容
+// 未提供的字段保持不变，传空字符串 / 空数组表示清除
+type UpdateBookWordCustomReq struct {
+	CustomDef      *string           `json:"custom_def"`
+	Note           *string           `json:"note"`
+	CustomExamples *[]WordExampleReq `json:"custom_examples"`
+}
+
+// maxCustomExamples 每个单词在词书中最多的自定义例句数
+const maxCustomExamples = 10
+
 type UpdateSenseReq struct {
 	VocabID string `json:"vocab_id" binding:"required"`
 	SenseID string `json:"sense_id" binding:"required"` // 用户选中的 SenseID
@@ -30,7 +89,8 @@ type UpdateSenseReq struct {
 // --- Handler ---
 
 // CreateCustomVocabulary 创建自定义词书并导入单词
-func CreateCustomVocabulary(db *gorm.DB) gin.HandlerFunc {
+// 返回逐词的导入结果；请求 generate_missing 时，找不到的单词会排队生成草稿 (runner 为 nil 表示未配置 AI)
+func CreateCustomVocabulary(db *gorm.DB, runner *enrich.Runner) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req CreateCustomVocabReq
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -39,35 +99,43 @@ func CreateCustomVocabulary(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		// 1. 解析单词列表 (支持中文逗号、英文逗号、换行、空格)
-		rawWords := strings.FieldsFunc(req.WordListStr, func(r rune) bool {
-			return r == ',' || r == '，' || r == '\n' || r == ' '
-		})
-
-		// 去重
-		uniqueWords := make(map[string]bool)
-		var searchKeywords []string
-		for _, w := range rawWords {
-			w = strings.TrimSpace(w)
-			if w != "" && !uniqueWords[w] {
-				uniqueWords[w] = true
-				searchKeywords = append(searchKeywords, w)
-			}
-		}
-
+		searchKeywords := parseWordList(req.WordListStr)
 		if len(searchKeywords) == 0 {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "请提供有效的单词列表"})
 			return
 		}
 
 		// 2. 查找存在的单词
-		var foundVocabs []model.Vocab
-		if err := db.Select("id, kanji").Where("kanji IN ?", searchKeywords).Find(&foundVocabs).Error; err != nil {
+		matches, err := resolveWords(db, searchKeywords)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询词库失败"})
 			return
 		}
+		foundVocabs := matchedVocabs(matches)
+		report, summary := importReport(matches)
 
-		if len(foundVocabs) == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "提供的单词在词库中均不存在，请先添加单词"})
+		var missing []string
+		for _, m := range matches {
+			if len(m.Vocabs) == 0 {
+				missing = append(missing, m.Input)
+			}
+		}
+		generate := req.GenerateMissing && len(missing) > 0
+		if generate && runner == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI 服务未配置，无法生成缺失的单词"})
+			return
+		}
+		if generate && len(missing) > enrich.MaxNewWords {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("缺失的单词超过 %d 个，请分批导入", enrich.MaxNewWords)})
+			return
+		}
+
+		if len(foundVocabs) == 0 && !generate {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "提供的单词在词库中均不存在，请先添加单词",
+				"report":  report,
+				"summary": summary,
+			})
 			return
 		}
 
@@ -84,16 +152,17 @@ func CreateCustomVocabulary(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		var relations []model.VocabularyWord
-		for _, v := range foundVocabs {
+		for i, v := range foundVocabs {
 			relations = append(relations, model.VocabularyWord{
 				VocabularyID: vocabBookID,
 				VocabID:      v.ID,
 				SenseID:      "", // 初始为空，由用户后续选择
+				Position:     i,  // 按导入顺序排列
 			})
 		}
 
 		// 4. 事务入库
-		err := db.Transaction(func(tx *gorm.DB) error {
+		err = db.Transaction(func(tx *gorm.DB) error {
 			if err := tx.Create(&newBook).Error; err != nil {
 				return err
 			}
@@ -110,15 +179,65 @@ func CreateCustomVocabulary(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
+		resp := gin.H{
 			"message":      "词书创建成功",
 			"id":           vocabBookID,
 			"total_input":  len(searchKeywords),
 			"valid_import": len(foundVocabs),
-		})
+		}
+
+		// 5. 缺失的单词排队生成草稿 (词书已创建，失败时只在响应中说明)
+		if generate {
+			job, err := enrich.CreateNewWordJob(db, missing, vocabBookID, c.GetString("userID"))
+			if err != nil {
+				resp["generate_error"] = err.Error()
+			} else {
+				runner.Notify()
+				resp["job_id"] = job.ID
+				for i := range report {
+					if report[i].Status == ImportMissing {
+						report[i].Queued = true
+						summary.Queued++
+					}
+				}
+			}
+		}
+
+		resp["report"] = report
+		resp["summary"] = summary
+		c.JSON(http.StatusOK, resp)
 	}
 }
 
+// importReport 逐词的导入结果与汇总
+func importReport(matches []wordMatch) ([]ImportWordReport, ImportSummary) {
+	report := make([]ImportWordReport, 0, len(matches))
+	var summary ImportSummary
+	for _, m := range matches {
+		r := ImportWordReport{Input: m.Input, Normalized: m.Normalized}
+		for _, v := range m.Vocabs {
+			r.VocabIDs = append(r.VocabIDs, v.ID)
+			r.Kanji = append(r.Kanji, v.Kanji)
+		}
+		switch {
+		case len(m.Vocabs) == 0:
+			r.Status = ImportMissing
+			summary.Missing++
+		case len(m.Vocabs) > 1:
+			r.Status = ImportAmbiguous
+			summary.Ambiguous++
+		case m.Normalized:
+			r.Status = ImportNormalized
+			summary.Normalized++
+		default:
+			r.Status = ImportMatched
+			summary.Matched++
+		}
+		report = append(report, r)
+	}
+	return report, summary
+}
+
 // GetVocabBookList 获取词书列表
 func GetVocabBookList(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -138,78 +257,568 @@ func GetVocabBookList(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
-// GetVocabBookDetail 获取词书详情 (核心：多义词优先排序)
-func GetVocabBookDetail(db *gorm.DB) gin.HandlerFunc {
+// UpdateBookWordSense 更新词书中单词选中的释义
+// 已有自定义释义或例句时，只能切换到它们所针对的释义 (需先清除自定义内容再改选其他释义)
+func UpdateBookWordSense(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		bookID := c.Param("id")
+		var req UpdateSenseReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-		// 简单分页
-		page := 1
-		pageSize := 100
+		// 锁住该行后再检查，避免与同时修改自定义内容的请求交错
+		err := db.Transaction(func(tx *gorm.DB) error {
+			rel, err := lockBookWord(tx, bookID, req.VocabID)
+			if err != nil {
+				return err
+			}
+			if dto.BookOverrideTarget(rel.Vocab, req.SenseID) < 0 {
+				return errSenseNotInWord
+			}
+			if dto.HasSenseOverride(rel) && req.SenseID != rel.CustomSenseID {
+				return errCustomOtherSense
+			}
 
-		var relations []model.VocabularyWord
+			err = tx.Model(&model.VocabularyWord{}).
+				Where("vocabulary_id = ? AND vocab_id = ?", bookID, req.VocabID).
+				Update("sense_id", req.SenseID).Error
+			if err != nil {
+				return err
+			}
+			return touchBook(tx, bookID)
+		})
+		switch {
+		case errors.Is(err, errBookWordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, errSenseNotInWord):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, errCustomOtherSense):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "已更新选中释义"})
+	}
+}
+
+// UpdateBookWordCustom 修改单词在本词书中的自定义释义、备注与例句 (不影响词库及其他词书)
+// 自定义释义和例句覆盖在选中的释义上 (记录所针对的释义)，多义词需先选定释义
+func UpdateBookWordCustom(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bookID, vocabID := c.Param("id"), c.Param("vocab_id")
+		var req UpdateBookWordCustomReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.CustomDef == nil && req.Note == nil && req.CustomExamples == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要修改的字段"})
+			return
+		}
+		var examples datatypes.JSON
+		if req.CustomExamples != nil {
+			list := *req.CustomExamples
+			if len(list) > maxCustomExamples {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("自定义例句最多 %d 条", maxCustomExamples)})
+				return
+			}
+			for i := range list {
+				list[i].Kanji = strings.TrimSpace(list[i].Kanji)
+				if list[i].Kanji == "" {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "例句原文不能为空"})
+					return
+				}
+			}
+			if len(list) > 0 {
+				examples = datatypes.JSON(utils.ToJSON(list))
+			}
+		}
+
+		var rel model.VocabularyWord
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			rel, err = lockBookWord(tx, bookID, vocabID)
+			if err != nil {
+				return err
+			}
+
+			updates := map[string]interface{}{}
+			needsTarget := false
+			if req.CustomDef != nil {
+				rel.CustomDef = strings.TrimSpace(*req.CustomDef)
+				updates["custom_def"] = rel.CustomDef
+				needsTarget = rel.CustomDef != ""
+			}
+			if req.Note != nil {
+				rel.Note = strings.TrimSpace(*req.Note)
+				updates["note"] = rel.Note
+			}
+			if req.CustomExamples != nil {
+				rel.CustomExamples = examples
+				updates["custom_examples"] = rel.CustomExamples
+				needsTarget = needsTarget || len(examples) > 0
+			}
+			if needsTarget {
+				target := dto.BookOverrideSense(rel.Vocab, rel.SenseID)
+				if target == "" {
+					return errCustomNeedsSense
+				}
+				// 本次未修改的那部分自定义内容是为其他释义写的，不能跟着挂到新的释义上
+				stale := (req.CustomDef == nil && rel.CustomDef != "") || (req.CustomExamples == nil && len(rel.CustomExamples) > 0)
+				if stale && rel.CustomSenseID != target {
+					return errCustomStale
+				}
+				rel.CustomSenseID = target
+				updates["custom_sense_id"] = target
+			} else if !dto.HasSenseOverride(rel) {
+				rel.CustomSenseID = ""
+				updates["custom_sense_id"] = ""
+			}
+
+			if err := tx.Model(&model.VocabularyWord{}).
+				Where("vocabulary_id = ? AND vocab_id = ?", bookID, vocabID).
+				Updates(updates).Error; err != nil {
+				return err
+			}
+			return touchBook(tx, bookID)
+		})
+		switch {
+		case errors.Is(err, errBookWordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, errCustomNeedsSense):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, errCustomStale):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "已更新", "word": dto.ToVocabBookWordDTO(rel)})
+	}
+}
+
+// UpdateVocabBook 修改词书名称与简介
+func UpdateVocabBook(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req UpdateVocabBookReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		updates := map[string]interface{}{"updata_at": time.Now()}
+		if req.Name != nil {
+			if strings.TrimSpace(*req.Name) == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "词书名称不能为空"})
+				return
+			}
+			updates["name"] = strings.TrimSpace(*req.Name)
+		}
+		if req.Descript != nil {
+			updates["descript"] = *req.Descript
+		}
+		if len(updates) == 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要修改的字段"})
+			return
+		}
+
+		result := db.Model(&model.Vocabulary{}).Where("id = ?", c.Param("id")).Updates(updates)
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败"})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "词书不存在"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "更新成功"})
+	}
+}
+
+// DeleteVocabBook 删除词书及其分组、单词关联 (词库中的单词不受影响)
+func DeleteVocabBook(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bookID := c.Param("id")
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("vocabulary_id = ?", bookID).Delete(&model.VocabularyWord{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("vocabulary_id = ?", bookID).Delete(&model.VocabSection{}).Error; err != nil {
+				return err
+			}
+			result := tx.Delete(&model.Vocabulary{}, "id = ?", bookID)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errBookNotFound
+			}
+			return nil
+		})
+		if errors.Is(err, errBookNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "词书不存在"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+	}
+}
 
-		// 关联查询逻辑:
-		// 1. JOIN vocabs 表:为了获取 is_multi 字段进行排序
-		// 2. Order is_multi DESC:多义词排在前面
-		// 3. Preload Vocab.Senses:加载单词的所有释义,供前端展示和勾选
-		err := db.
-			Joins("JOIN vocabs ON vocabs.id = vocabulary_words.vocab_id").
-			Where("vocabulary_words.vocabulary_id = ?", bookID).
-			Preload("Vocab").
-			Preload("Vocab.Senses").
-			Preload("Vocab.Senses.Examples", func(db *gorm.DB) *gorm.DB {
-				return db.Limit(2) // ✅ 每个 sense 最多 2 个例句
-			}).
-			Order("vocabs.is_multi DESC").             // 🔥 优先级1:多义词靠前
-			Order("vocabulary_words.created_at DESC"). // 优先级2:后加入的靠前
-			Offset((page - 1) * pageSize).
-			Limit(pageSize).
-			Find(&relations).Error
+// AddBookWords 向词书追加单词 (已在词书中的单词保持不变)
+func AddBookWords(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bookID := c.Param("id")
+		var req BookWordsReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
+		vocabs, missing, err := resolveBookWordsReq(db, req)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询详情失败"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询词库失败"})
+			return
+		}
+		if len(vocabs) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "提供的单词在词库中均不存在", "missing": missing})
 			return
 		}
 
-		// ✅ 使用统一 DTO 转换,简化返回结构
-		words := make([]dto.VocabBookWordDTO, 0, len(relations))
-		for _, rel := range relations {
-			words = append(words, dto.ToVocabBookWordDTO(rel))
+		added, count := 0, 0
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := lockBook(tx, bookID); err != nil {
+				return err
+			}
+
+			ids := make([]string, 0, len(vocabs))
+			for _, v := range vocabs {
+				ids = append(ids, v.ID)
+			}
+			var existing []string
+			if err := tx.Model(&model.VocabularyWord{}).
+				Where("vocabulary_id = ? AND vocab_id IN ?", bookID, ids).
+				Pluck("vocab_id", &existing).Error; err != nil {
+				return err
+			}
+			inBook := make(map[string]bool, len(existing))
+			for _, id := range existing {
+				inBook[id] = true
+			}
+
+			// 新单词追加到未分组部分的末尾
+			next, err := nextWordPosition(tx, bookID, "")
+			if err != nil {
+				return err
+			}
+			var relations []model.VocabularyWord
+			for _, v := range vocabs {
+				if !inBook[v.ID] {
+					relations = append(relations, model.VocabularyWord{VocabularyID: bookID, VocabID: v.ID, Position: next + len(relations)})
+				}
+			}
+			if len(relations) > 0 {
+				if err := tx.CreateInBatches(&relations, 100).Error; err != nil {
+					return err
+				}
+			}
+			added = len(relations)
+
+			count, err = refreshBookCount(tx, bookID)
+			return err
+		})
+		if errors.Is(err, errBookNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "词书不存在"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "添加失败: " + err.Error()})
+			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"id":    bookID,
-			"words": words, // ✅ 扁平化结构,前端更易使用
+			"message": "添加成功",
+			"added":   added,
+			"existed": len(vocabs) - added,
+			"missing": missing,
+			"count":   count,
 		})
 	}
 }
 
-// UpdateBookWordSense 更新词书中单词选中的释义
-func UpdateBookWordSense(db *gorm.DB) gin.HandlerFunc {
+// RemoveBookWords 从词书移除单词
+// vocab_ids 直接按 ID 移除，不要求单词仍在词库中 (可清理已删除单词遗留的记录)；missing 只包含词库中找不到的单词原文
+func RemoveBookWords(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		bookID := c.Param("id")
-		var req UpdateSenseReq
+		var req BookWordsReq
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		// 更新关联表中的 SenseID
-		result := db.Model(&model.VocabularyWord{}).
-			Where("vocabulary_id = ? AND vocab_id = ?", bookID, req.VocabID).
-			Update("sense_id", req.SenseID)
-
-		if result.Error != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败"})
+		vocabs, missing, err := resolveBookWordsReq(db, BookWordsReq{Words: req.Words, WordListStr: req.WordListStr})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询词库失败"})
+			return
+		}
+		ids := make([]string, 0, len(vocabs)+len(req.VocabIDs))
+		for _, v := range vocabs {
+			ids = append(ids, v.ID)
+		}
+		ids = uniqueWords(append(ids, req.VocabIDs...))
+		if len(ids) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "提供的单词在词库中均不存在", "missing": missing})
 			return
 		}
 
-		if result.RowsAffected == 0 {
-			c.JSON(http.StatusNotFound, gin.H{"error": "未找到该单词记录，可能不在当前词书中"})
+		var removed int64
+		count := 0
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := lockBook(tx, bookID); err != nil {
+				return err
+			}
+
+			result := tx.Where("vocabulary_id = ? AND vocab_id IN ?", bookID, ids).Delete(&model.VocabularyWord{})
+			if result.Error != nil {
+				return result.Error
+			}
+			removed = result.RowsAffected
+
+			count, err = refreshBookCount(tx, bookID)
+			return err
+		})
+		if errors.Is(err, errBookNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "词书不存在"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "移除失败: " + err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"message": "已更新选中释义"})
+		c.JSON(http.StatusOK, gin.H{
+			"message": "移除成功",
+			"removed": removed,
+			"missing": missing,
+			"count":   count,
+		})
+	}
+}
+
+// --- 内部逻辑 ---
+
+var (
+	errBookNotFound     = errors.New("词书不存在")
+	errBookWordNotFound = errors.New("未找到该单词记录，可能不在当前词书中")
+	errSenseNotInWord   = errors.New("该释义不属于这个单词")
+	errCustomNeedsSense = errors.New("该单词有多个释义，请先选择释义再自定义")
+	errCustomStale      = errors.New("已有的自定义内容针对的释义已变更，请同时修改或清除自定义释义与例句")
+	errCustomOtherSense = errors.New("该单词有针对其他释义的自定义释义或例句，请先清除后再切换释义")
+)
+
+// lockBook 在事务中锁定词书行，同一词书的增删串行执行以保证 Count 准确
+func lockBook(tx *gorm.DB, bookID string) error {
+	var book model.Vocabulary
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&book, "id = ?", bookID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errBookNotFound
+	}
+	return err
+}
+
+// lockBookWord 在事务中锁定词书中的一个单词 (同时加载单词、释义与例句)，不在词书中时返回 errBookWordNotFound
+func lockBookWord(tx *gorm.DB, bookID, vocabID string) (model.VocabularyWord, error) {
+	var rel model.VocabularyWord
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("vocabulary_id = ? AND vocab_id = ?", bookID, vocabID).
+		Preload("Vocab").
+		Preload("Vocab.Senses").
+		Preload("Vocab.Senses.Examples", func(db *gorm.DB) *gorm.DB {
+			return db.Limit(2)
+		}).
+		First(&rel).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return rel, errBookWordNotFound
+	}
+	return rel, err
+}
+
+// addWordToBook 在事务中把单词加入词书 (已在词书中时忽略)，并维护 Count；词书已被删除时什么也不做
+func addWordToBook(tx *gorm.DB, bookID, vocabID string) error {
+	if err := lockBook(tx, bookID); err != nil {
+		if errors.Is(err, errBookNotFound) {
+			return nil
+		}
+		return err
+	}
+	next, err := nextWordPosition(tx, bookID, "")
+	if err != nil {
+		return err
+	}
+	relation := model.VocabularyWord{VocabularyID: bookID, VocabID: vocabID, Position: next}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&relation).Error; err != nil {
+		return err
+	}
+	_, err = refreshBookCount(tx, bookID)
+	return err
+}
+
+// refreshBookCount 按关联表重新统计词书的单词数，并更新修改时间 (需在 lockBook 之后调用)
+func refreshBookCount(tx *gorm.DB, bookID string) (int, error) {
+	var count int64
+	if err := tx.Model(&model.VocabularyWord{}).Where("vocabulary_id = ?", bookID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	err := tx.Model(&model.Vocabulary{}).Where("id = ?", bookID).Updates(map[string]interface{}{
+		"count":     count,
+		"updata_at": time.Now(),
+	}).Error
+	return int(count), err
+}
+
+// parseWordList 解析逗号分隔的单词字符串 (支持中文逗号、英文逗号、换行、空格)，去重并保持顺序
+func parseWordList(str string) []string {
+	rawWords := strings.FieldsFunc(str, func(r rune) bool {
+		return r == ',' || r == '，' || r == '\n' || r == ' '
+	})
+	return uniqueWords(rawWords)
+}
+
+func uniqueWords(words []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, w := range words {
+		w = strings.TrimSpace(w)
+		if w != "" && !seen[w] {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// wordMatch 一个输入单词在词库中的匹配结果
+type wordMatch struct {
+	Input      string
+	Vocabs     []model.Vocab // 只含 id 与 kanji；同形词会有多个
+	Normalized bool          // 精确匹配不到，按规范化后的词典键匹配
+}
+
+// resolveWords 在词库中查找单词：先按原文精确匹配，
+// 匹配不到的再按规范化后的词典键查缓存 (全角/半角、旧字体、～ 标记等)
+func resolveWords(db *gorm.DB, keywords []string) ([]wordMatch, error) {
+	matches := make([]wordMatch, len(keywords))
+	if len(keywords) == 0 {
+		return matches, nil
+	}
+
+	var exact []model.Vocab
+	if err := db.Select("id, kanji").Where("kanji IN ?", keywords).Find(&exact).Error; err != nil {
+		return nil, err
+	}
+	byKanji := make(map[string][]model.Vocab)
+	for _, v := range exact {
+		byKanji[v.Kanji] = append(byKanji[v.Kanji], v)
+	}
+
+	normalizedIDs := make(map[int][]string)
+	var extraIDs []string
+	for i, w := range keywords {
+		matches[i].Input = w
+		if vs, ok := byKanji[w]; ok {
+			matches[i].Vocabs = vs
+			continue
+		}
+		ids, _ := cache.GlobalDict.Get(cache.NormalizeKey(w))
+		if len(ids) > 0 {
+			normalizedIDs[i] = ids
+			extraIDs = append(extraIDs, ids...)
+		}
+	}
+	if len(extraIDs) == 0 {
+		return matches, nil
 	}
+
+	var extra []model.Vocab
+	if err := db.Select("id, kanji").Where("id IN ?", extraIDs).Find(&extra).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]model.Vocab, len(extra))
+	for _, v := range extra {
+		byID[v.ID] = v
+	}
+	for i, ids := range normalizedIDs {
+		for _, id := range ids {
+			if v, ok := byID[id]; ok {
+				matches[i].Vocabs = append(matches[i].Vocabs, v)
+			}
+		}
+		matches[i].Normalized = len(matches[i].Vocabs) > 0
+	}
+	return matches, nil
+}
+
+// matchedVocabs 汇总全部匹配到的单词 (按 ID 去重，保持输入顺序)
+func matchedVocabs(matches []wordMatch) []model.Vocab {
+	seen := make(map[string]bool)
+	var out []model.Vocab
+	for _, m := range matches {
+		for _, v := range m.Vocabs {
+			if !seen[v.ID] {
+				seen[v.ID] = true
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// resolveBookWordsReq 把请求中的单词原文与 vocab_id 解析为词库中的单词，同时返回找不到的输入
+func resolveBookWordsReq(db *gorm.DB, req BookWordsReq) ([]model.Vocab, []string, error) {
+	keywords := uniqueWords(append(append([]string{}, req.Words...), parseWordList(req.WordListStr)...))
+	matches, err := resolveWords(db, keywords)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	missing := []string{}
+	for _, m := range matches {
+		if len(m.Vocabs) == 0 {
+			missing = append(missing, m.Input)
+		}
+	}
+
+	if ids := uniqueWords(req.VocabIDs); len(ids) > 0 {
+		var byID []model.Vocab
+		if err := db.Select("id, kanji").Where("id IN ?", ids).Find(&byID).Error; err != nil {
+			return nil, nil, err
+		}
+		found := make(map[string]bool, len(byID))
+		for _, v := range byID {
+			found[v.ID] = true
+		}
+		for _, id := range ids {
+			if !found[id] {
+				missing = append(missing, id)
+			}
+		}
+		matches = append(matches, wordMatch{Vocabs: byID})
+	}
+
+	return matchedVocabs(matches), missing, nil
 }
@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// --- DTO ---
+
+// CreateBookFromAnalysisReq 从分析结果创建词书
+// 来源三选一: analysis_id (文章分析快照)、article_id (文章最新快照)、analysis (/api/analyze 返回的未保存结果)
+type CreateBookFromAnalysisReq struct {
+	Name     string `json:"name" binding:"required"`
+	Descript string `json:"descript"`
+
+	AnalysisID string       `json:"analysis_id"`
+	ArticleID  string       `json:"article_id"`
+	Analysis   *AnalyzeResp `json:"analysis"`
+
+	BookWordFilter
+}
+
+// BookWordFilter 选词条件 (按选中的释义判断)，均为空时收录全部单词
+type BookWordFilter struct {
+	Levels           []string `json:"levels"`            // 只收录这些等级，如 ["N3","N2"]
+	Pos              []string `json:"pos"`               // 只收录词性包含其一的单词
+	ExcludeParticles bool     `json:"exclude_particles"` // 排除助词
+}
+
+var errAnalysisNotFound = errors.New("分析结果不存在")
+
+// bookEntry 词书中的一个单词及其来源句
+type bookEntry struct {
+	VocabID     string
+	SenseID     string
+	Sentence    string
+	Translation string
+}
+
+// --- Handler ---
+
+// CreateVocabularyFromAnalysis 从分析结果创建词书
+// 每个单词取第一次出现时选中的释义 (文章来源会先应用教师修正)，并保存所在句子作为语境例句
+func CreateVocabularyFromAnalysis(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateBookFromAnalysisReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		sources := 0
+		for _, set := range []bool{req.AnalysisID != "", req.ArticleID != "", req.Analysis != nil} {
+			if set {
+				sources++
+			}
+		}
+		if sources != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "analysis_id、article_id 与 analysis 必须且只能提供一个"})
+			return
+		}
+
+		resp, articleID, status, err := loadAnalysisSource(db, req)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		entries, filtered := bookEntriesFromAnalysis(resp, req.BookWordFilter)
+		entries, err = existingBookEntries(db, entries)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询词库失败"})
+			return
+		}
+		if len(entries) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "没有符合条件的单词"})
+			return
+		}
+
+		vocabBookID := utils.GenerateID("vb_", req.Name, uuid.New().String())
+		newBook := model.Vocabulary{
+			ID:       vocabBookID,
+			Name:     req.Name,
+			Descript: req.Descript,
+			Count:    len(entries),
+			CreateAt: time.Now(),
+			UpdataAt: time.Now(),
+		}
+
+		relations := make([]model.VocabularyWord, 0, len(entries))
+		for i, e := range entries {
+			relations = append(relations, model.VocabularyWord{
+				VocabularyID:       vocabBookID,
+				VocabID:            e.VocabID,
+				SenseID:            e.SenseID,
+				ContextSentence:    e.Sentence,
+				ContextTranslation: e.Translation,
+				SourceArticleID:    articleID,
+				Position:           i, // 按在文章中首次出现的顺序排列
+			})
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&newBook).Error; err != nil {
+				return err
+			}
+			return tx.CreateInBatches(&relations, 100).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建失败: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "词书创建成功",
+			"id":           vocabBookID,
+			"valid_import": len(entries),
+			"filtered":     filtered,
+		})
+	}
+}
+
+// loadAnalysisSource 取出请求指定的分析结果及来源文章 ID；失败时返回对应的 HTTP 状态码
+func loadAnalysisSource(db *gorm.DB, req CreateBookFromAnalysisReq) (AnalyzeResp, string, int, error) {
+	if req.Analysis != nil {
+		return *req.Analysis, "", http.StatusOK, nil
+	}
+
+	var snapshot model.ArticleAnalysis
+	var resp AnalyzeResp
+	if req.AnalysisID != "" {
+		if err := db.First(&snapshot, "id = ?", req.AnalysisID).Error; err != nil {
+			return resp, "", http.StatusNotFound, errAnalysisNotFound
+		}
+		if err := json.Unmarshal(snapshot.Result, &resp); err != nil {
+			return resp, "", http.StatusInternalServerError, err
+		}
+	} else {
+		latest, r, err := latestSnapshot(db, req.ArticleID)
+		if err != nil {
+			return resp, "", http.StatusInternalServerError, err
+		}
+		if latest == nil {
+			return resp, "", http.StatusNotFound, errAnalysisNotFound
+		}
+		snapshot, resp = *latest, r
+	}
+
+	var overrides []model.ArticleOverride
+	if err := db.Where("article_id = ?", snapshot.ArticleID).Find(&overrides).Error; err != nil {
+		return resp, "", http.StatusInternalServerError, err
+	}
+	applyOverrides(&resp, overrides)
+	return resp, snapshot.ArticleID, http.StatusOK, nil
+}
+
+// bookEntriesFromAnalysis 按出现顺序收集符合条件的单词 (同一单词只取第一次符合条件的出现)
+// 返回被条件排除的单词数 (每次出现都不符合条件的单词)
+func bookEntriesFromAnalysis(resp AnalyzeResp, f BookWordFilter) ([]bookEntry, int) {
+	var entries []bookEntry
+	seen := make(map[string]bool)
+	rejected := make(map[string]bool)
+
+	for _, t := range resp.Tokens {
+		if !t.IsWord || t.Detail == nil || t.Detail.VocabID == "" || seen[t.Detail.VocabID] {
+			continue
+		}
+		// 同一单词在别处可能选中了不同的释义 (词性、等级不同)，不符合时继续看后面的出现
+		if !f.match(*t.Detail) {
+			rejected[t.Detail.VocabID] = true
+			continue
+		}
+		seen[t.Detail.VocabID] = true
+		delete(rejected, t.Detail.VocabID)
+
+		e := bookEntry{VocabID: t.Detail.VocabID, SenseID: t.Detail.SenseID}
+		if t.Sentence >= 0 && t.Sentence < len(resp.Sentences) {
+			s := resp.Sentences[t.Sentence]
+			e.Sentence = strings.TrimSpace(s.Text)
+			e.Translation = s.Translation
+		}
+		entries = append(entries, e)
+	}
+	return entries, len(rejected)
+}
+
+func (f BookWordFilter) match(d WordDetail) bool {
+	if f.ExcludeParticles && (strings.Contains(d.Pos, "助词") || strings.Contains(d.Pos, "助詞")) {
+		return false
+	}
+	if len(f.Levels) > 0 && !containsFold(f.Levels, d.Level) {
+		return false
+	}
+	if len(f.Pos) > 0 {
+		for _, p := range f.Pos {
+			if p != "" && strings.Contains(d.Pos, p) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	s = strings.TrimSpace(s)
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// existingBookEntries 去掉词库中已不存在的单词；释义已被删除时保留单词并清空 SenseID
+// (快照可能早于单词的修改，客户端提交的分析结果也不可信)
+func existingBookEntries(db *gorm.DB, entries []bookEntry) ([]bookEntry, error) {
+	if len(entries) == 0 {
+		return entries, nil
+	}
+
+	vocabIDs := make([]string, 0, len(entries))
+	senseIDs := make([]string, 0, len(entries))
+	for _, e := range entries {
+		vocabIDs = append(vocabIDs, e.VocabID)
+		if e.SenseID != "" {
+			senseIDs = append(senseIDs, e.SenseID)
+		}
+	}
+
+	var vocabs []model.Vocab
+	if err := db.Select("id").Where("id IN ?", vocabIDs).Find(&vocabs).Error; err != nil {
+		return nil, err
+	}
+	var senses []model.VocabSense
+	if len(senseIDs) > 0 {
+		if err := db.Select("id, vocab_id").Where("id IN ?", senseIDs).Find(&senses).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	validVocab := make(map[string]bool, len(vocabs))
+	for _, v := range vocabs {
+		validVocab[v.ID] = true
+	}
+	senseOwner := make(map[string]string, len(senses))
+	for _, s := range senses {
+		senseOwner[s.ID] = s.VocabID
+	}
+
+	out := entries[:0]
+	for _, e := range entries {
+		if !validVocab[e.VocabID] {
+			continue
+		}
+		if senseOwner[e.SenseID] != e.VocabID {
+			e.SenseID = ""
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
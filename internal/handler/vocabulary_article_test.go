@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBookEntriesFromAnalysis(t *testing.T) {
+	resp := AnalyzeResp{
+		Sentences: []Sentence{{Text: "猫が好き。", Translation: "喜欢猫。"}, {Text: "猫を見た。"}},
+		Tokens: []Token{
+			{IsWord: true, Sentence: 0, Detail: &WordDetail{VocabID: "v_neko", SenseID: "s_neko", Level: "N5", Pos: "名词"}},
+			{IsWord: true, Sentence: 0, Detail: &WordDetail{VocabID: "v_ga", SenseID: "s_ga", Level: "N5", Pos: "格助词"}},
+			{IsWord: true, Sentence: 0, Detail: &WordDetail{VocabID: "v_suki", SenseID: "s_suki", Level: "N4", Pos: "形容动词"}},
+			{Text: "。", Sentence: 0},
+			// 重复出现只取第一次
+			{IsWord: true, Sentence: 1, Detail: &WordDetail{VocabID: "v_neko", SenseID: "s_neko_2", Level: "N5", Pos: "名词"}},
+			{IsWord: true, Sentence: 1, Detail: &WordDetail{VocabID: "v_miru", SenseID: "s_miru", Level: "n5", Pos: "动词"}},
+		},
+	}
+
+	entries, filtered := bookEntriesFromAnalysis(resp, BookWordFilter{Levels: []string{"N5"}, ExcludeParticles: true})
+	if filtered != 2 || len(entries) != 2 {
+		t.Fatalf("entries = %+v, filtered = %d", entries, filtered)
+	}
+	if e := entries[0]; e.VocabID != "v_neko" || e.SenseID != "s_neko" || e.Sentence != "猫が好き。" || e.Translation != "喜欢猫。" {
+		t.Errorf("entries[0] = %+v", e)
+	}
+	if entries[1].VocabID != "v_miru" || entries[1].Sentence != "猫を見た。" {
+		t.Errorf("entries[1] = %+v", entries[1])
+	}
+
+	entries, _ = bookEntriesFromAnalysis(resp, BookWordFilter{Pos: []string{"动词"}})
+	if len(entries) != 2 || entries[0].VocabID != "v_suki" || entries[1].VocabID != "v_miru" {
+		t.Errorf("pos filter entries = %+v", entries)
+	}
+
+	// 第一次出现不符合条件，后面选中了其他释义的出现符合条件时仍然收录
+	resp.Tokens = append(resp.Tokens,
+		Token{IsWord: true, Sentence: 1, Detail: &WordDetail{VocabID: "v_suki", SenseID: "s_suki_2", Level: "N5", Pos: "名词"}})
+	entries, filtered = bookEntriesFromAnalysis(resp, BookWordFilter{Levels: []string{"N5"}, ExcludeParticles: true})
+	if filtered != 1 || len(entries) != 3 {
+		t.Fatalf("entries = %+v, filtered = %d", entries, filtered)
+	}
+	if e := entries[2]; e.VocabID != "v_suki" || e.SenseID != "s_suki_2" || e.Sentence != "猫を見た。" {
+		t.Errorf("entries[2] = %+v", e)
+	}
+}
+
+func TestCreateVocabularyFromAnalysis(t *testing.T) {
+	resp := analyzedResp(t)
+	db := setupAnalyze(t)
+
+	r := gin.New()
+	r.POST("/vocab-book/from-analysis", CreateVocabularyFromAnalysis(db))
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/vocab-book/from-analysis", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	body, _ := json.Marshal(map[string]any{"name": "第一课", "analysis": resp, "levels": []string{"N5"}})
+	w := post(string(body))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var out struct {
+		ValidImport int `json:"valid_import"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ValidImport != 2 {
+		t.Errorf("valid_import = %d", out.ValidImport)
+	}
+
+	// 来源必须且只能有一个
+	if w := post(`{"name": "x", "article_id": "a1", "analysis_id": "s1"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("two sources: status = %d", w.Code)
+	}
+}
@@ -0,0 +1,424 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"dongwai_backend/internal/dto"
+	"dongwai_backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 词书详情的排序方式
+const (
+	BookSortDefault = "default" // 多义词优先，其次后加入的靠前
+	BookSortAdded   = "added"   // 加入时间 (默认新的在前)
+	BookSortKana    = "kana"    // 读音的五十音顺序
+	BookSortLevel   = "level"   // 等级 (默认 N5 → N1)
+	// 按分组与分组内的顺序 (未分组的单词排在最后)，词书有分组时为默认排序
+	BookSortPosition = "position"
+)
+
+const (
+	defaultBookPageSize = 50
+	maxBookPageSize     = 200
+)
+
+// bookSenseScope 单词在词书中 "有效" 的释义：已选中时只看选中的释义，否则看全部释义
+const bookSenseScope = "s.vocab_id = vocabulary_words.vocab_id AND (COALESCE(vocabulary_words.sense_id, '') = '' OR s.id = vocabulary_words.sense_id)"
+
+// bookUnselectedCond 尚未选定释义：只有多义词需要选择，单义词不算在内 (统计数与 unselected 筛选共用)
+const bookUnselectedCond = "vocabs.is_multi AND COALESCE(vocabulary_words.sense_id, '') = ''"
+
+// 排序键：读音取有效释义中最小的读音，等级取有效释义中最简单的等级 (字符串上 N5 最大)
+const (
+	bookKanaKey  = "COALESCE((SELECT MIN(s.reading) FROM vocab_senses s WHERE " + bookSenseScope + "), '')"
+	bookLevelKey = "COALESCE((SELECT MAX(s.level) FROM vocab_senses s WHERE " + bookSenseScope + "), '')"
+	// 所属分组的顺序，未分组 (或分组已不存在) 时排在最后
+	bookSectionKey = "COALESCE((SELECT vs.position FROM vocab_sections vs WHERE vs.id = vocabulary_words.section_id), 2147483647)"
+)
+
+var errBadCursor = errors.New("cursor 无效或与当前排序方式、筛选条件不匹配")
+
+// BookDetailQuery 词书详情的查询参数
+type BookDetailQuery struct {
+	Cursor     string   // 上一页返回的 next_cursor
+	Limit      int      // 每页条数 (默认 50，最多 200)
+	Sort       string   // default / added / kana / level / position
+	Desc       bool     // order=asc|desc，未指定时 added 为倒序，kana / level / position 为正序
+	Levels     []string // level=N5,N4：有效释义属于其一
+	Pos        []string // pos=名詞,動詞：有效释义的词性包含其一
+	IsMulti    *bool    // is_multi=true|false
+	Unselected bool     // unselected=1：只看尚未选定释义的多义词
+	Section    *string  // section=<分组 ID>，section= (空) 表示只看未分组的单词
+}
+
+// bookCursor 游标：上一页最后一行的排序键 (base64 编码的 JSON，客户端只需原样传回)
+// 同时记录排序方式与筛选条件的摘要，换了条件后旧游标不再有效
+type bookCursor struct {
+	Sort    string    `json:"s"`
+	Desc    bool      `json:"d,omitempty"`
+	Filter  string    `json:"f,omitempty"`
+	IsMulti bool      `json:"m,omitempty"`
+	AddedAt time.Time `json:"t"`
+	Key     string    `json:"k,omitempty"`
+	Section int       `json:"sp,omitempty"`
+	Pos     int       `json:"p,omitempty"`
+	VocabID string    `json:"id"`
+}
+
+// bookPageRow 分页查询只取排序所需的列，单词内容随后按 vocab_id 批量加载
+type bookPageRow struct {
+	VocabID   string
+	CreatedAt time.Time
+	IsMulti   bool
+	SortKey   string
+	SortSect  int
+	Position  int
+}
+
+// GetVocabBookDetail 获取词书详情 (游标分页)
+// 查询参数: cursor, limit, sort (default/added/kana/level/position), order (asc/desc),
+// level, pos (逗号分隔), is_multi, unselected, section
+// 返回 total (词书总词数)、unselected (尚未选定释义的多义词数)、matched (符合筛选条件的词数) 与分组列表
+func GetVocabBookDetail(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bookID := c.Param("id")
+
+		var book model.Vocabulary
+		if err := db.Select("id").First(&book, "id = ?", bookID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": errBookNotFound.Error()})
+			return
+		}
+		sections, err := bookSectionDTOs(db, bookID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询详情失败"})
+			return
+		}
+
+		// 有分组的词书默认按教师编排的顺序显示
+		defaultSort := BookSortDefault
+		if len(sections) > 0 {
+			defaultSort = BookSortPosition
+		}
+		q, err := parseBookDetailQuery(c.Request.URL.Query(), defaultSort)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var cursor *bookCursor
+		if q.Cursor != "" {
+			if cursor, err = q.decodeCursor(); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		var total, unselected, matched int64
+		if err := db.Model(&model.VocabularyWord{}).Where("vocabulary_id = ?", bookID).Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询详情失败"})
+			return
+		}
+		err = bookWordsQuery(db, bookID).Where(bookUnselectedCond).Count(&unselected).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询详情失败"})
+			return
+		}
+		if err := q.filter(bookWordsQuery(db, bookID)).Count(&matched).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询详情失败"})
+			return
+		}
+
+		// 多取一行判断是否还有下一页
+		page := q.filter(bookWordsQuery(db, bookID)).
+			Select("vocabulary_words.vocab_id, vocabulary_words.created_at, vocabs.is_multi, vocabulary_words.position, " +
+				q.sortKey() + " AS sort_key, " + bookSectionKey + " AS sort_sect")
+		if cursor != nil {
+			cond, args := q.after(*cursor)
+			page = page.Where(cond, args...)
+		}
+		var rows []bookPageRow
+		if err := page.Order(q.orderBy()).Limit(q.Limit + 1).Scan(&rows).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询详情失败"})
+			return
+		}
+
+		nextCursor := ""
+		if len(rows) > q.Limit {
+			rows = rows[:q.Limit]
+			nextCursor = q.encodeCursor(rows[len(rows)-1])
+		}
+
+		words, err := loadBookWords(db, bookID, rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询详情失败"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"id":          bookID,
+			"words":       words,
+			"total":       total,
+			"unselected":  unselected,
+			"matched":     matched,
+			"sections":    sections,
+			"next_cursor": nextCursor, // 为空表示没有下一页
+		})
+	}
+}
+
+// bookWordsQuery 词书中的单词 (关联 vocabs 以便按 is_multi 筛选、排序)
+func bookWordsQuery(db *gorm.DB, bookID string) *gorm.DB {
+	return db.Table("vocabulary_words").
+		Joins("JOIN vocabs ON vocabs.id = vocabulary_words.vocab_id").
+		Where("vocabulary_words.vocabulary_id = ?", bookID)
+}
+
+// loadBookWords 按分页结果的顺序加载单词、释义与例句
+func loadBookWords(db *gorm.DB, bookID string, rows []bookPageRow) ([]dto.VocabBookWordDTO, error) {
+	words := make([]dto.VocabBookWordDTO, 0, len(rows))
+	if len(rows) == 0 {
+		return words, nil
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.VocabID)
+	}
+
+	var relations []model.VocabularyWord
+	err := db.
+		Where("vocabulary_id = ? AND vocab_id IN ?", bookID, ids).
+		Preload("Vocab").
+		Preload("Vocab.Senses").
+		Preload("Vocab.Senses.Examples", func(db *gorm.DB) *gorm.DB {
+			return db.Limit(2) // ✅ 每个 sense 最多 2 个例句
+		}).
+		Find(&relations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]model.VocabularyWord, len(relations))
+	for _, rel := range relations {
+		byID[rel.VocabID] = rel
+	}
+	for _, id := range ids {
+		if rel, ok := byID[id]; ok {
+			words = append(words, dto.ToVocabBookWordDTO(rel))
+		}
+	}
+	return words, nil
+}
+
+// parseBookDetailQuery 解析并校验查询参数，未指定 sort 时使用 defaultSort
+func parseBookDetailQuery(v url.Values, defaultSort string) (BookDetailQuery, error) {
+	q := BookDetailQuery{
+		Cursor:     v.Get("cursor"),
+		Sort:       v.Get("sort"),
+		Levels:     splitList(v.Get("level")),
+		Pos:        splitList(v.Get("pos")),
+		Unselected: v.Get("unselected") == "1" || v.Get("unselected") == "true",
+	}
+
+	q.Limit = defaultBookPageSize
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return q, errors.New("limit 必须是正整数")
+		}
+		q.Limit = min(n, maxBookPageSize)
+	}
+
+	switch q.Sort {
+	case "":
+		q.Sort = defaultSort
+	case BookSortDefault, BookSortAdded, BookSortKana, BookSortLevel, BookSortPosition:
+	default:
+		return q, errors.New("sort 只能是 default、added、kana、level 或 position")
+	}
+
+	switch v.Get("order") {
+	case "":
+		q.Desc = q.Sort == BookSortDefault || q.Sort == BookSortAdded
+	case "asc":
+		q.Desc = false
+	case "desc":
+		q.Desc = true
+	default:
+		return q, errors.New("order 只能是 asc 或 desc")
+	}
+	if q.Sort == BookSortDefault {
+		q.Desc = true // 默认排序固定为多义词优先、新加入的靠前
+	}
+
+	if s := v.Get("is_multi"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return q, errors.New("is_multi 必须是 true 或 false")
+		}
+		q.IsMulti = &b
+	}
+	if sections, ok := v["section"]; ok {
+		section := strings.TrimSpace(sections[0])
+		q.Section = &section
+	}
+	return q, nil
+}
+
+// splitList 拆分逗号分隔的参数，去掉空项
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// filter 应用筛选条件
+func (q BookDetailQuery) filter(db *gorm.DB) *gorm.DB {
+	if len(q.Levels) > 0 {
+		db = db.Where("EXISTS (SELECT 1 FROM vocab_senses s WHERE "+bookSenseScope+" AND s.level IN ?)", q.Levels)
+	}
+	if len(q.Pos) > 0 {
+		conds := make([]string, 0, len(q.Pos))
+		args := make([]any, 0, len(q.Pos))
+		for _, p := range q.Pos {
+			conds = append(conds, "s.pos LIKE ?")
+			args = append(args, "%"+p+"%")
+		}
+		db = db.Where("EXISTS (SELECT 1 FROM vocab_senses s WHERE "+bookSenseScope+" AND ("+strings.Join(conds, " OR ")+"))", args...)
+	}
+	if q.IsMulti != nil {
+		db = db.Where("vocabs.is_multi = ?", *q.IsMulti)
+	}
+	if q.Unselected {
+		db = db.Where(bookUnselectedCond)
+	}
+	if q.Section != nil {
+		db = db.Where("COALESCE(vocabulary_words.section_id, '') = ?", *q.Section)
+	}
+	return db
+}
+
+// sortColumns 排序列 (最后一列总是 vocab_id，保证顺序唯一)
+func (q BookDetailQuery) sortColumns() []string {
+	switch q.Sort {
+	case BookSortAdded:
+		return []string{"vocabulary_words.created_at", "vocabulary_words.vocab_id"}
+	case BookSortKana:
+		return []string{bookKanaKey, "vocabulary_words.vocab_id"}
+	case BookSortLevel:
+		return []string{bookLevelKey, "vocabulary_words.vocab_id"}
+	case BookSortPosition:
+		return []string{bookSectionKey, "vocabulary_words.position", "vocabulary_words.vocab_id"}
+	default:
+		return []string{"vocabs.is_multi", "vocabulary_words.created_at", "vocabulary_words.vocab_id"}
+	}
+}
+
+// sortKey 写入游标的排序键 (kana / level 为计算列，其余排序直接取行中的字段，分组顺序见 bookSectionKey)
+func (q BookDetailQuery) sortKey() string {
+	switch q.Sort {
+	case BookSortKana:
+		return bookKanaKey
+	case BookSortLevel:
+		return bookLevelKey
+	default:
+		return "''"
+	}
+}
+
+// descending 实际的排序方向：等级的字符串顺序与难度相反 (N5 > N1)，正序 (N5 → N1) 需要倒排
+func (q BookDetailQuery) descending() bool {
+	if q.Sort == BookSortLevel {
+		return !q.Desc
+	}
+	return q.Desc
+}
+
+func (q BookDetailQuery) orderBy() string {
+	dir := " ASC"
+	if q.descending() {
+		dir = " DESC"
+	}
+	cols := q.sortColumns()
+	for i := range cols {
+		cols[i] += dir
+	}
+	return strings.Join(cols, ", ")
+}
+
+// after 游标之后的行 (各排序列方向一致，可以直接用行比较)
+func (q BookDetailQuery) after(cur bookCursor) (string, []any) {
+	op := " > "
+	if q.descending() {
+		op = " < "
+	}
+	var args []any
+	switch q.Sort {
+	case BookSortAdded:
+		args = []any{cur.AddedAt, cur.VocabID}
+	case BookSortKana, BookSortLevel:
+		args = []any{cur.Key, cur.VocabID}
+	case BookSortPosition:
+		args = []any{cur.Section, cur.Pos, cur.VocabID}
+	default:
+		args = []any{cur.IsMulti, cur.AddedAt, cur.VocabID}
+	}
+	holders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+	return "(" + strings.Join(q.sortColumns(), ", ") + ")" + op + "(" + holders + ")", args
+}
+
+// filterHash 筛选条件的摘要 (没有筛选时为空)，写入游标以识别翻页途中改变的条件
+func (q BookDetailQuery) filterHash() string {
+	if len(q.Levels) == 0 && len(q.Pos) == 0 && q.IsMulti == nil && !q.Unselected && q.Section == nil {
+		return ""
+	}
+	data, _ := json.Marshal([]any{q.Levels, q.Pos, q.IsMulti, q.Unselected, q.Section})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+func (q BookDetailQuery) encodeCursor(last bookPageRow) string {
+	data, _ := json.Marshal(bookCursor{
+		Sort:    q.Sort,
+		Desc:    q.Desc,
+		Filter:  q.filterHash(),
+		IsMulti: last.IsMulti,
+		AddedAt: last.CreatedAt,
+		Key:     last.SortKey,
+		Section: last.SortSect,
+		Pos:     last.Position,
+		VocabID: last.VocabID,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor 解析游标；排序方式或筛选条件改变后旧游标不再有效
+func (q BookDetailQuery) decodeCursor() (*bookCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, errBadCursor
+	}
+	var cur bookCursor
+	if err := json.Unmarshal(data, &cur); err != nil || cur.VocabID == "" {
+		return nil, errBadCursor
+	}
+	if cur.Sort != q.Sort || cur.Desc != q.Desc || cur.Filter != q.filterHash() {
+		return nil, errBadCursor
+	}
+	return &cur, nil
+}
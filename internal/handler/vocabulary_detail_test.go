@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseBookDetailQuery(t *testing.T) {
+	q, err := parseBookDetailQuery(url.Values{
+		"level":      {"N5, N4,"},
+		"pos":        {"名詞"},
+		"is_multi":   {"true"},
+		"unselected": {"1"},
+		"limit":      {"1000"},
+		"sort":       {"kana"},
+	}, BookSortDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q.Levels, []string{"N5", "N4"}) || !reflect.DeepEqual(q.Pos, []string{"名詞"}) {
+		t.Errorf("levels = %v, pos = %v", q.Levels, q.Pos)
+	}
+	if q.IsMulti == nil || !*q.IsMulti || !q.Unselected {
+		t.Errorf("is_multi = %v, unselected = %v", q.IsMulti, q.Unselected)
+	}
+	if q.Limit != maxBookPageSize || q.Desc {
+		t.Errorf("limit = %d, desc = %v", q.Limit, q.Desc)
+	}
+
+	if q, _ := parseBookDetailQuery(url.Values{}, BookSortDefault); q.Sort != BookSortDefault || !q.Desc || q.Limit != defaultBookPageSize {
+		t.Errorf("defaults = %+v", q)
+	}
+
+	for _, bad := range []url.Values{
+		{"sort": {"random"}},
+		{"order": {"up"}},
+		{"limit": {"0"}},
+		{"is_multi": {"maybe"}},
+	} {
+		if _, err := parseBookDetailQuery(bad, BookSortDefault); err == nil {
+			t.Errorf("%v: expected error", bad)
+		}
+	}
+}
+
+func TestBookDetailOrder(t *testing.T) {
+	cases := []struct {
+		query     url.Values
+		wantOrder string
+		wantOp    string
+	}{
+		{url.Values{}, "vocabs.is_multi DESC, vocabulary_words.created_at DESC, vocabulary_words.vocab_id DESC", " < "},
+		{url.Values{"sort": {"added"}, "order": {"asc"}}, "vocabulary_words.created_at ASC, vocabulary_words.vocab_id ASC", " > "},
+		// N5 → N1 在字符串上是倒序
+		{url.Values{"sort": {"level"}}, bookLevelKey + " DESC, vocabulary_words.vocab_id DESC", " < "},
+		{url.Values{"sort": {"level"}, "order": {"desc"}}, bookLevelKey + " ASC, vocabulary_words.vocab_id ASC", " > "},
+		{url.Values{"sort": {"position"}}, bookSectionKey + " ASC, vocabulary_words.position ASC, vocabulary_words.vocab_id ASC", " > "},
+	}
+	for _, tc := range cases {
+		q, err := parseBookDetailQuery(tc.query, BookSortDefault)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := q.orderBy(); got != tc.wantOrder {
+			t.Errorf("%v: order = %s", tc.query, got)
+		}
+		cond, args := q.after(bookCursor{VocabID: "v_1"})
+		cols := q.sortColumns()
+		want := "(" + strings.Join(cols, ", ") + ")" + tc.wantOp + "(" + strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ") + ")"
+		if cond != want || len(args) != len(cols) {
+			t.Errorf("%v: after = %s %v", tc.query, cond, args)
+		}
+	}
+}
+
+func TestBookCursorRoundTrip(t *testing.T) {
+	q, _ := parseBookDetailQuery(url.Values{"sort": {"kana"}}, BookSortDefault)
+	added := time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC)
+	q.Cursor = q.encodeCursor(bookPageRow{VocabID: "v_benkyou", CreatedAt: added, SortKey: "べんきょう"})
+
+	cur, err := q.decodeCursor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cur.VocabID != "v_benkyou" || cur.Key != "べんきょう" || !cur.AddedAt.Equal(added) {
+		t.Errorf("cursor = %+v", cur)
+	}
+
+	// 换了排序方式或筛选条件，旧游标失效
+	for _, v := range []url.Values{
+		{"sort": {"kana"}, "order": {"desc"}},
+		{"sort": {"kana"}, "unselected": {"1"}},
+		{"sort": {"kana"}, "section": {""}},
+	} {
+		other, _ := parseBookDetailQuery(v, BookSortDefault)
+		other.Cursor = q.Cursor
+		if _, err := other.decodeCursor(); err != errBadCursor {
+			t.Errorf("%v: err = %v, want errBadCursor", v, err)
+		}
+	}
+
+	// 条件相同 (顺序不同的参数解析结果一致) 时游标仍然有效
+	filtered, _ := parseBookDetailQuery(url.Values{"sort": {"kana"}, "level": {"N5,N4"}, "is_multi": {"true"}}, BookSortDefault)
+	filtered.Cursor = filtered.encodeCursor(bookPageRow{VocabID: "v_benkyou"})
+	same, _ := parseBookDetailQuery(url.Values{"is_multi": {"1"}, "level": {"N5, N4"}, "sort": {"kana"}}, BookSortDefault)
+	same.Cursor = filtered.Cursor
+	if _, err := same.decodeCursor(); err != nil {
+		t.Errorf("same filters: err = %v", err)
+	}
+	q.Cursor = "not-a-cursor"
+	if _, err := q.decodeCursor(); err != errBadCursor {
+		t.Errorf("err = %v, want errBadCursor", err)
+	}
+}
+
+func TestBookDetailUnselectedFilter(t *testing.T) {
+	db, fake := newFakeDB(t, map[string]fakeTable{
+		"vocabularies": {columns: []string{"id"}, rows: [][]driver.Value{{"vb1"}}},
+	})
+	fake.onQuery("SELECT count(*)", fakeTable{columns: []string{"count"}, rows: [][]driver.Value{{int64(3)}}})
+
+	r := gin.New()
+	r.GET("/vocab-book/:id", GetVocabBookDetail(db))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/vocab-book/vb1?unselected=1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	// unselected 统计数与 unselected=1 筛选 (matched 与分页) 使用同一条件，单义词不计入
+	var withCond int
+	for _, st := range fake.stmts(`FROM "vocabulary_words"`) {
+		if strings.Contains(st.query, bookUnselectedCond) {
+			withCond++
+		}
+	}
+	if withCond != 3 {
+		t.Errorf("%d statements use the unselected condition, want 3 (unselected, matched, page)", withCond)
+	}
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"dongwai_backend/internal/dto"
+	"dongwai_backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// unsectionedName 导出时未分组单词所在分组的名称
+const unsectionedName = "未分组"
+
+// bookGroup 导出时的一个分组及其中的单词 (按顺序)
+type bookGroup struct {
+	Section model.VocabSection
+	Words   []model.VocabularyWord
+}
+
+// ExportVocabBook 按分组与顺序导出词书
+// format=json (默认) 返回分组后的单词；format=csv 返回表格 (分组、序号、单词、读音、等级、词性、释义、语境例句、备注)
+// 两种格式都使用合并了词书自定义内容后的释义
+func ExportVocabBook(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bookID := c.Param("id")
+		format := c.DefaultQuery("format", "json")
+		if format != "json" && format != "csv" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format 只能是 json 或 csv"})
+			return
+		}
+
+		var book model.Vocabulary
+		if err := db.First(&book, "id = ?", bookID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": errBookNotFound.Error()})
+			return
+		}
+		sections, err := loadSections(db, bookID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "导出失败"})
+			return
+		}
+		var relations []model.VocabularyWord
+		err = db.Where("vocabulary_id = ?", bookID).
+			Preload("Vocab").
+			Preload("Vocab.Senses").
+			Preload("Vocab.Senses.Examples").
+			Order("position, created_at, vocab_id").
+			Find(&relations).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "导出失败"})
+			return
+		}
+
+		groups := groupBookWords(sections, relations)
+		if format == "csv" {
+			writeBookCSV(c, book, groups)
+			return
+		}
+
+		out := make([]gin.H, 0, len(groups))
+		for _, g := range groups {
+			words := make([]dto.VocabBookWordDTO, 0, len(g.Words))
+			for _, rel := range g.Words {
+				words = append(words, dto.ToVocabBookWordDTO(rel))
+			}
+			out = append(out, gin.H{"id": g.Section.ID, "name": g.Section.Name, "words": words})
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"id":       book.ID,
+			"name":     book.Name,
+			"descript": book.Descript,
+			"count":    len(relations),
+			"sections": out,
+		})
+	}
+}
+
+// groupBookWords 按分组顺序归类单词 (words 已按分组内顺序排列)
+// 空分组也会保留；未分组 (或所属分组已不存在) 的单词放在最后一组
+func groupBookWords(sections []model.VocabSection, words []model.VocabularyWord) []bookGroup {
+	groups := make([]bookGroup, 0, len(sections)+1)
+	index := make(map[string]int, len(sections))
+	for _, s := range sections {
+		index[s.ID] = len(groups)
+		groups = append(groups, bookGroup{Section: s})
+	}
+
+	var rest []model.VocabularyWord
+	for _, w := range words {
+		if i, ok := index[w.SectionID]; ok {
+			groups[i].Words = append(groups[i].Words, w)
+		} else {
+			rest = append(rest, w)
+		}
+	}
+	if len(rest) > 0 {
+		groups = append(groups, bookGroup{Section: model.VocabSection{Name: unsectionedName}, Words: rest})
+	}
+	return groups
+}
+
+// writeBookCSV 输出 CSV (带 UTF-8 BOM，便于 Excel 直接打开)
+func writeBookCSV(c *gin.Context, book model.Vocabulary, groups []bookGroup) {
+	filename := url.PathEscape(book.Name + ".csv")
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+filename)
+	c.Status(http.StatusOK)
+
+	c.Writer.WriteString("\uFEFF")
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"分组", "序号", "单词", "读音", "等级", "词性", "释义", "语境例句", "例句译文", "备注"})
+	for _, g := range groups {
+		for i, rel := range g.Words {
+			w.Write(append([]string{g.Section.Name, strconv.Itoa(i + 1), rel.Vocab.Kanji}, exportSenseFields(rel)...))
+		}
+	}
+	w.Flush()
+}
+
+// exportSenseFields 读音、等级、词性、释义、语境例句与备注
+// 已选定释义时只导出该释义，否则导出全部释义 (读音等取第一个释义)
+func exportSenseFields(rel model.VocabularyWord) []string {
+	word := dto.ToVocabBookWordDTO(rel)
+	senses := word.Word.Senses
+	for _, s := range senses {
+		if word.SelectedSenseID != "" && s.ID == word.SelectedSenseID {
+			senses = []dto.SenseDTO{s}
+			break
+		}
+	}
+
+	var reading, level, pos string
+	defs := make([]string, 0, len(senses))
+	for i, s := range senses {
+		if i == 0 {
+			reading, level, pos = s.Reading, s.Level, s.Pos
+		}
+		if s.Def != "" {
+			defs = append(defs, s.Def)
+		}
+	}
+	return []string{reading, level, pos, strings.Join(defs, "；"), rel.ContextSentence, rel.ContextTranslation, word.Note}
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+
+	"dongwai_backend/internal/model"
+)
+
+func TestGroupBookWords(t *testing.T) {
+	sections := []model.VocabSection{{ID: "vs_1", Name: "第一课"}, {ID: "vs_2", Name: "第二课"}}
+	words := []model.VocabularyWord{
+		{VocabID: "v_a"},
+		{VocabID: "v_b", SectionID: "vs_1"},
+		{VocabID: "v_c", SectionID: "vs_gone"},
+		{VocabID: "v_d", SectionID: "vs_1"},
+	}
+
+	groups := groupBookWords(sections, words)
+	var got [][]string
+	for _, g := range groups {
+		ids := []string{g.Section.Name}
+		for _, w := range g.Words {
+			ids = append(ids, w.VocabID)
+		}
+		got = append(got, ids)
+	}
+	want := [][]string{{"第一课", "v_b", "v_d"}, {"第二课"}, {unsectionedName, "v_a", "v_c"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groups = %v, want %v", got, want)
+	}
+}
+
+func TestExportSenseFields(t *testing.T) {
+	rel := model.VocabularyWord{
+		VocabID: "v_benkyou",
+		Vocab: model.Vocab{Kanji: "勉強", Senses: []model.VocabSense{
+			{ID: "s_benkyou_1", Reading: "べんきょう", Level: "N5", Pos: "名詞", Def: "学习"},
+			{ID: "s_benkyou_2", Reading: "べんきょう", Level: "N5", Pos: "名詞", Def: "便宜，让价"},
+		}},
+		ContextSentence: "日本語を勉強する。",
+	}
+
+	got := exportSenseFields(rel)
+	if want := []string{"べんきょう", "N5", "名詞", "学习；便宜，让价", "日本語を勉強する。", "", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unselected = %v", got)
+	}
+
+	rel.SenseID = "s_benkyou_2"
+	if got := exportSenseFields(rel); got[3] != "便宜，让价" {
+		t.Errorf("selected def = %q", got[3])
+	}
+
+	// 自定义释义覆盖选中的释义
+	rel.CustomDef, rel.Note, rel.CustomSenseID = "砍价", "商人用语", "s_benkyou_2"
+	if got := exportSenseFields(rel); got[3] != "砍价" || got[6] != "商人用语" {
+		t.Errorf("custom = %v", got)
+	}
+
+	// 自定义释义是为其他释义写的，导出词库释义
+	rel.CustomSenseID = "s_benkyou_1"
+	if got := exportSenseFields(rel); got[3] != "便宜，让价" {
+		t.Errorf("detached custom def = %q", got[3])
+	}
+}
@@ -0,0 +1,490 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"dongwai_backend/internal/dto"
+	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// --- DTO ---
+
+// CreateSectionReq 新建分组，未指定 position 时追加到末尾
+type CreateSectionReq struct {
+	Name     string `json:"name" binding:"required"`
+	Position *int   `json:"position"` // 插入位置 (从 0 开始)
+}
+
+// UpdateSectionReq 修改分组名称
+type UpdateSectionReq struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// ReorderSectionsReq 调整分组顺序，须列出词书的全部分组
+type ReorderSectionsReq struct {
+	SectionIDs []string `json:"section_ids" binding:"required"`
+}
+
+// ReorderWordsReq 调整分组内单词的顺序，须列出该分组的全部单词
+type ReorderWordsReq struct {
+	SectionID string   `json:"section_id"` // 为空表示未分组的单词
+	VocabIDs  []string `json:"vocab_ids" binding:"required"`
+}
+
+// MoveWordsReq 把单词移动到某个分组 (保持请求中的顺序)
+type MoveWordsReq struct {
+	VocabIDs  []string `json:"vocab_ids" binding:"required"`
+	SectionID string   `json:"section_id"` // 目标分组，为空表示移出分组
+	Before    string   `json:"before"`     // 插到目标分组中该单词之前，为空表示追加到末尾
+}
+
+var (
+	errSectionNotFound = errors.New("分组不存在")
+	errOrderMismatch   = errors.New("顺序列表必须包含且只包含全部条目，且不能重复")
+	errWordNotInBook   = errors.New("部分单词不在当前词书中")
+	errBadBefore       = errors.New("before 必须是目标分组中未被移动的单词")
+)
+
+// --- Handler ---
+
+// CreateSection 新建分组
+func CreateSection(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bookID := c.Param("id")
+		var req CreateSectionReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "分组名称不能为空"})
+			return
+		}
+
+		section := model.VocabSection{
+			ID:           utils.GenerateID("vs_", bookID, name, uuid.New().String()),
+			VocabularyID: bookID,
+			Name:         name,
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := lockBook(tx, bookID); err != nil {
+				return err
+			}
+			sections, err := loadSections(tx, bookID)
+			if err != nil {
+				return err
+			}
+
+			ids := make([]string, 0, len(sections)+1)
+			for _, s := range sections {
+				ids = append(ids, s.ID)
+			}
+			section.Position = len(ids)
+			if req.Position != nil && *req.Position >= 0 && *req.Position < len(ids) {
+				section.Position = *req.Position
+			}
+			ids = append(ids[:section.Position], append([]string{section.ID}, ids[section.Position:]...)...)
+
+			if err := tx.Create(&section).Error; err != nil {
+				return err
+			}
+			if err := setSectionPositions(tx, bookID, ids); err != nil {
+				return err
+			}
+			return touchBook(tx, bookID)
+		})
+		if err != nil {
+			respondSectionError(c, err, "创建分组失败")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "分组已创建", "section": dto.ToVocabSectionDTO(section, 0)})
+	}
+}
+
+// UpdateSection 修改分组名称
+func UpdateSection(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req UpdateSectionReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "分组名称不能为空"})
+			return
+		}
+
+		result := db.Model(&model.VocabSection{}).
+			Where("id = ? AND vocabulary_id = ?", c.Param("sid"), c.Param("id")).
+			Update("name", name)
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败"})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": errSectionNotFound.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "更新成功"})
+	}
+}
+
+// DeleteSection 删除分组，其中的单词按原顺序移到未分组部分的末尾
+func DeleteSection(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bookID, sectionID := c.Param("id"), c.Param("sid")
+
+		moved := 0
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := lockBook(tx, bookID); err != nil {
+				return err
+			}
+			if err := checkSection(tx, bookID, sectionID); err != nil {
+				return err
+			}
+
+			words, err := sectionWordIDs(tx, bookID, sectionID)
+			if err != nil {
+				return err
+			}
+			next, err := nextWordPosition(tx, bookID, "")
+			if err != nil {
+				return err
+			}
+			if err := setWordPositions(tx, bookID, "", words, next); err != nil {
+				return err
+			}
+			moved = len(words)
+
+			if err := tx.Delete(&model.VocabSection{}, "id = ?", sectionID).Error; err != nil {
+				return err
+			}
+			sections, err := loadSections(tx, bookID)
+			if err != nil {
+				return err
+			}
+			ids := make([]string, 0, len(sections))
+			for _, s := range sections {
+				ids = append(ids, s.ID)
+			}
+			if err := setSectionPositions(tx, bookID, ids); err != nil {
+				return err
+			}
+			return touchBook(tx, bookID)
+		})
+		if err != nil {
+			respondSectionError(c, err, "删除分组失败")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "分组已删除", "moved": moved})
+	}
+}
+
+// ReorderSections 调整分组顺序
+func ReorderSections(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bookID := c.Param("id")
+		var req ReorderSectionsReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := lockBook(tx, bookID); err != nil {
+				return err
+			}
+			sections, err := loadSections(tx, bookID)
+			if err != nil {
+				return err
+			}
+			current := make([]string, 0, len(sections))
+			for _, s := range sections {
+				current = append(current, s.ID)
+			}
+			if !sameSet(current, req.SectionIDs) {
+				return errOrderMismatch
+			}
+			if err := setSectionPositions(tx, bookID, req.SectionIDs); err != nil {
+				return err
+			}
+			return touchBook(tx, bookID)
+		})
+		if err != nil {
+			respondSectionError(c, err, "调整顺序失败")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "顺序已更新"})
+	}
+}
+
+// ReorderWords 调整分组内单词的顺序
+func ReorderWords(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bookID := c.Param("id")
+		var req ReorderWordsReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := lockBook(tx, bookID); err != nil {
+				return err
+			}
+			if err := checkSection(tx, bookID, req.SectionID); err != nil {
+				return err
+			}
+			current, err := sectionWordIDs(tx, bookID, req.SectionID)
+			if err != nil {
+				return err
+			}
+			if !sameSet(current, req.VocabIDs) {
+				return errOrderMismatch
+			}
+			if err := setWordPositions(tx, bookID, req.SectionID, req.VocabIDs, 0); err != nil {
+				return err
+			}
+			return touchBook(tx, bookID)
+		})
+		if err != nil {
+			respondSectionError(c, err, "调整顺序失败")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "顺序已更新"})
+	}
+}
+
+// MoveWords 把单词移动到指定分组的指定位置 (也可用于同一分组内的移动)
+func MoveWords(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bookID := c.Param("id")
+		var req MoveWordsReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		moved := uniqueWords(req.VocabIDs)
+		if len(moved) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请提供要移动的单词"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := lockBook(tx, bookID); err != nil {
+				return err
+			}
+			if err := checkSection(tx, bookID, req.SectionID); err != nil {
+				return err
+			}
+			var inBook int64
+			if err := tx.Model(&model.VocabularyWord{}).
+				Where("vocabulary_id = ? AND vocab_id IN ?", bookID, moved).
+				Count(&inBook).Error; err != nil {
+				return err
+			}
+			if int(inBook) != len(moved) {
+				return errWordNotInBook
+			}
+
+			target, err := sectionWordIDs(tx, bookID, req.SectionID)
+			if err != nil {
+				return err
+			}
+			order, err := moveBefore(target, moved, req.Before)
+			if err != nil {
+				return err
+			}
+			if err := setWordPositions(tx, bookID, req.SectionID, order, 0); err != nil {
+				return err
+			}
+			return touchBook(tx, bookID)
+		})
+		if err != nil {
+			respondSectionError(c, err, "移动失败")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "移动成功", "moved": len(moved)})
+	}
+}
+
+// respondSectionError 把分组操作的错误映射为 HTTP 响应
+func respondSectionError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, errBookNotFound), errors.Is(err, errSectionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, errOrderMismatch), errors.Is(err, errWordNotInBook), errors.Is(err, errBadBefore):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
+
+// --- 内部逻辑 ---
+
+// loadSections 词书的全部分组 (按顺序)
+func loadSections(db *gorm.DB, bookID string) ([]model.VocabSection, error) {
+	var sections []model.VocabSection
+	err := db.Where("vocabulary_id = ?", bookID).Order("position, created_at").Find(&sections).Error
+	return sections, err
+}
+
+// bookSectionDTOs 词书的分组及各分组的单词数
+func bookSectionDTOs(db *gorm.DB, bookID string) ([]dto.VocabSectionDTO, error) {
+	sections, err := loadSections(db, bookID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]dto.VocabSectionDTO, 0, len(sections))
+	if len(sections) == 0 {
+		return out, nil
+	}
+
+	var counts []struct {
+		SectionID string
+		Count     int
+	}
+	err = db.Model(&model.VocabularyWord{}).
+		Select("section_id, COUNT(*) AS count").
+		Where("vocabulary_id = ?", bookID).
+		Group("section_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	bySection := make(map[string]int, len(counts))
+	for _, c := range counts {
+		bySection[c.SectionID] = c.Count
+	}
+	for _, s := range sections {
+		out = append(out, dto.ToVocabSectionDTO(s, bySection[s.ID]))
+	}
+	return out, nil
+}
+
+// checkSection 确认分组属于该词书 (空字符串表示未分组，总是有效)
+func checkSection(tx *gorm.DB, bookID, sectionID string) error {
+	if sectionID == "" {
+		return nil
+	}
+	var n int64
+	if err := tx.Model(&model.VocabSection{}).Where("id = ? AND vocabulary_id = ?", sectionID, bookID).Count(&n).Error; err != nil {
+		return err
+	}
+	if n == 0 {
+		return errSectionNotFound
+	}
+	return nil
+}
+
+// sectionWordIDs 分组内的单词 (按当前顺序)
+func sectionWordIDs(tx *gorm.DB, bookID, sectionID string) ([]string, error) {
+	var ids []string
+	err := tx.Model(&model.VocabularyWord{}).
+		Where("vocabulary_id = ? AND COALESCE(section_id, '') = ?", bookID, sectionID).
+		Order("position, created_at, vocab_id").
+		Pluck("vocab_id", &ids).Error
+	return ids, err
+}
+
+// nextWordPosition 分组末尾的下一个位置
+func nextWordPosition(tx *gorm.DB, bookID, sectionID string) (int, error) {
+	var next int
+	err := tx.Model(&model.VocabularyWord{}).
+		Select("COALESCE(MAX(position), -1) + 1").
+		Where("vocabulary_id = ? AND COALESCE(section_id, '') = ?", bookID, sectionID).
+		Scan(&next).Error
+	return next, err
+}
+
+// setWordPositions 把单词依次放入分组，位置从 start 开始连续编号
+func setWordPositions(tx *gorm.DB, bookID, sectionID string, vocabIDs []string, start int) error {
+	for i, id := range vocabIDs {
+		err := tx.Model(&model.VocabularyWord{}).
+			Where("vocabulary_id = ? AND vocab_id = ?", bookID, id).
+			Updates(map[string]interface{}{"section_id": sectionID, "position": start + i}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setSectionPositions 按给定顺序为分组连续编号
+func setSectionPositions(tx *gorm.DB, bookID string, sectionIDs []string) error {
+	for i, id := range sectionIDs {
+		err := tx.Model(&model.VocabSection{}).
+			Where("id = ? AND vocabulary_id = ?", id, bookID).
+			Update("position", i).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func touchBook(tx *gorm.DB, bookID string) error {
+	return tx.Model(&model.Vocabulary{}).Where("id = ?", bookID).Update("updata_at", time.Now()).Error
+}
+
+// sameSet 两个列表是否由相同且不重复的元素组成
+func sameSet(current, proposed []string) bool {
+	if len(current) != len(proposed) {
+		return false
+	}
+	want := make(map[string]bool, len(current))
+	for _, id := range current {
+		want[id] = true
+	}
+	for _, id := range proposed {
+		if !want[id] {
+			return false
+		}
+		delete(want, id)
+	}
+	return true
+}
+
+// moveBefore 从 order 中取出 moved 中的条目，再按 moved 的顺序插到 before 之前 (before 为空时追加到末尾)
+// moved 中不在 order 里的条目 (来自其他分组) 同样插入
+func moveBefore(order, moved []string, before string) ([]string, error) {
+	isMoved := make(map[string]bool, len(moved))
+	for _, id := range moved {
+		isMoved[id] = true
+	}
+	if before != "" && isMoved[before] {
+		return nil, errBadBefore
+	}
+
+	rest := make([]string, 0, len(order))
+	at := -1
+	for _, id := range order {
+		if isMoved[id] {
+			continue
+		}
+		if id == before {
+			at = len(rest)
+		}
+		rest = append(rest, id)
+	}
+	if before == "" {
+		at = len(rest)
+	} else if at < 0 {
+		return nil, errBadBefore
+	}
+
+	out := make([]string, 0, len(rest)+len(moved))
+	out = append(out, rest[:at]...)
+	out = append(out, moved...)
+	return append(out, rest[at:]...), nil
+}
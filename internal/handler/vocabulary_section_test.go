@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMoveBefore(t *testing.T) {
+	order := []string{"a", "b", "c", "d"}
+	cases := []struct {
+		name   string
+		moved  []string
+		before string
+		want   []string
+	}{
+		{"append", []string{"b"}, "", []string{"a", "c", "d", "b"}},
+		{"within section", []string{"d", "b"}, "a", []string{"d", "b", "a", "c"}},
+		{"from other section", []string{"x"}, "c", []string{"a", "b", "x", "c", "d"}},
+	}
+	for _, tc := range cases {
+		got, err := moveBefore(order, tc.moved, tc.before)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	for _, before := range []string{"b", "missing"} {
+		if _, err := moveBefore(order, []string{"b"}, before); err != errBadBefore {
+			t.Errorf("before %q: err = %v, want errBadBefore", before, err)
+		}
+	}
+}
+
+func TestSameSet(t *testing.T) {
+	if !sameSet([]string{"a", "b"}, []string{"b", "a"}) {
+		t.Error("permutation rejected")
+	}
+	for _, proposed := range [][]string{{"a"}, {"a", "a"}, {"a", "c"}, {"a", "b", "c"}} {
+		if sameSet([]string{"a", "b"}, proposed) {
+			t.Errorf("%v accepted", proposed)
+		}
+	}
+}
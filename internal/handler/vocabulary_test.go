@@ -0,0 +1,382 @@
+package handler
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"dongwai_backend/internal/dto"
+	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseWordList(t *testing.T) {
+	got := parseWordList("勉強，日本語, 勉強\n 猫")
+	if want := []string{"勉強", "日本語", "猫"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseWordList = %v, want %v", got, want)
+	}
+}
+
+func TestResolveBookWordsReq(t *testing.T) {
+	db := setupAnalyze(t)
+	vocabs, missing, err := resolveBookWordsReq(db, BookWordsReq{
+		Words:       []string{"勉強"},
+		WordListStr: "勉強,存在しない",
+		VocabIDs:    []string{"v_nihongo"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, v := range vocabs {
+		ids = append(ids, v.ID)
+	}
+	// 假数据库忽略 WHERE，按 ID 查询会返回全部单词；结果仍按 ID 去重
+	if len(ids) != 2 || ids[0] != "v_benkyou" {
+		t.Errorf("vocabs = %v", ids)
+	}
+	if !reflect.DeepEqual(missing, []string{"存在しない"}) {
+		t.Errorf("missing = %v", missing)
+	}
+}
+
+func TestAddBookWordsUnknownBook(t *testing.T) {
+	db := setupAnalyze(t)
+	r := gin.New()
+	r.POST("/vocab-book/:id/words", AddBookWords(db))
+
+	req := httptest.NewRequest(http.MethodPost, "/vocab-book/vb_x/words", strings.NewReader(`{"words": ["勉強"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestImportReport(t *testing.T) {
+	matches := []wordMatch{
+		{Input: "勉強", Vocabs: []model.Vocab{{ID: "v1", Kanji: "勉強"}}},
+		{Input: "ﾃｽﾄ", Vocabs: []model.Vocab{{ID: "v2", Kanji: "テスト"}}, Normalized: true},
+		{Input: "生", Vocabs: []model.Vocab{{ID: "v3", Kanji: "生"}, {ID: "v4", Kanji: "生"}}},
+		{Input: "存在しない"},
+	}
+	report, summary := importReport(matches)
+
+	var statuses []string
+	for _, r := range report {
+		statuses = append(statuses, r.Status)
+	}
+	want := []string{ImportMatched, ImportNormalized, ImportAmbiguous, ImportMissing}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+	if !reflect.DeepEqual(report[1].Kanji, []string{"テスト"}) || !reflect.DeepEqual(report[2].VocabIDs, []string{"v3", "v4"}) {
+		t.Errorf("report = %+v", report)
+	}
+	if summary != (ImportSummary{Matched: 1, Normalized: 1, Ambiguous: 1, Missing: 1}) {
+		t.Errorf("summary = %+v", summary)
+	}
+}
+
+func TestCreateCustomVocabularyAllMissing(t *testing.T) {
+	db := setupAnalyze(t)
+	r := gin.New()
+	r.POST("/vocab-book", CreateCustomVocabulary(db, nil))
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/vocab-book", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 假数据库忽略 WHERE 会返回全部单词，但结果按原文分组，不在词库中的输入仍视为缺失
+	w := post(`{"name": "x", "word_list_str": "存在しない,ない"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var out struct {
+		Summary ImportSummary `json:"summary"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Summary.Missing != 2 {
+		t.Errorf("summary = %+v", out.Summary)
+	}
+
+	// 未配置 AI 时不能生成缺失的单词
+	if w := post(`{"name": "x", "word_list_str": "存在しない", "generate_missing": true}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("generate without AI: status = %d", w.Code)
+	}
+}
+
+func TestUpdateBookWordCustom(t *testing.T) {
+	put := func(senseID, body string) *httptest.ResponseRecorder {
+		db := newTestDB(t, map[string]fakeTable{
+			"vocabulary_words": {
+				columns: []string{"vocabulary_id", "vocab_id", "sense_id"},
+				rows:    [][]driver.Value{{"vb_1", "v_benkyou", senseID}},
+			},
+			"vocabs": {
+				columns: []string{"id", "kanji", "is_multi"},
+				rows:    [][]driver.Value{{"v_benkyou", "勉強", true}},
+			},
+			"vocab_senses": {
+				columns: []string{"id", "vocab_id", "level", "reading", "def"},
+				rows: [][]driver.Value{
+					{"s_benkyou_1", "v_benkyou", "N5", "べんきょう", "学习"},
+					{"s_benkyou_2", "v_benkyou", "N5", "べんきょう", "便宜，让价"},
+				},
+			},
+		})
+		r := gin.New()
+		r.PUT("/vocab-book/:id/words/:vocab_id/custom", UpdateBookWordCustom(db))
+		req := httptest.NewRequest(http.MethodPut, "/vocab-book/vb_1/words/v_benkyou/custom", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	body := `{"custom_def": "砍价", "note": "商人用语", "custom_examples": [{"kanji": "もう少し勉強してください。", "def": "请再便宜一点。"}]}`
+
+	// 多义词尚未选定释义，自定义内容没有可覆盖的释义
+	if w := put("", body); w.Code != http.StatusBadRequest {
+		t.Errorf("unselected: status = %d: %s", w.Code, w.Body.String())
+	}
+	// 备注不依赖释义
+	if w := put("", `{"note": "商人用语"}`); w.Code != http.StatusOK {
+		t.Errorf("note only: status = %d: %s", w.Code, w.Body.String())
+	}
+
+	w := put("s_benkyou_2", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var out struct {
+		Word dto.VocabBookWordDTO `json:"word"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Word.Note != "商人用语" || len(out.Word.CustomExamples) != 1 {
+		t.Errorf("word = %+v", out.Word)
+	}
+	first, second := out.Word.Word.Senses[0], out.Word.Word.Senses[1]
+	if first.Def != "学习" || first.OriginalDef != "" {
+		t.Errorf("unselected sense changed: %+v", first)
+	}
+	if second.Def != "砍价" || second.OriginalDef != "便宜，让价" {
+		t.Errorf("selected sense = %+v", second)
+	}
+	if len(second.Examples) == 0 || !second.Examples[0].Custom || second.Examples[0].Kanji != "もう少し勉強してください。" {
+		t.Errorf("examples = %+v", second.Examples)
+	}
+}
+
+func TestUpdateBookWordSenseWithCustom(t *testing.T) {
+	put := func(custom []driver.Value, body string) (*httptest.ResponseRecorder, *fakeDB) {
+		db, fake := newFakeDB(t, map[string]fakeTable{
+			"vocabulary_words": {
+				columns: []string{"vocabulary_id", "vocab_id", "sense_id", "custom_def", "custom_examples", "custom_sense_id"},
+				rows:    [][]driver.Value{append([]driver.Value{"vb_1", "v_benkyou", "s_benkyou_2"}, custom...)},
+			},
+			"vocabs": {columns: []string{"id", "kanji", "is_multi"}, rows: [][]driver.Value{{"v_benkyou", "勉強", true}}},
+			"vocab_senses": {
+				columns: []string{"id", "vocab_id", "def"},
+				rows:    [][]driver.Value{{"s_benkyou_1", "v_benkyou", "学习"}, {"s_benkyou_2", "v_benkyou", "便宜，让价"}},
+			},
+		})
+		fake.onExec(`UPDATE "vocabulary_words"`, 1)
+		r := gin.New()
+		r.PUT("/vocab-book/:id/sense", UpdateBookWordSense(db))
+		r.PUT("/vocab-book/:id/words/:vocab_id/custom", UpdateBookWordCustom(db))
+		path := "/vocab-book/vb_1/sense"
+		if !strings.Contains(body, "vocab_id") {
+			path = "/vocab-book/vb_1/words/v_benkyou/custom"
+		}
+		req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w, fake
+	}
+	customDef := []driver.Value{"砍价", nil, "s_benkyou_2"}
+
+	// 自定义释义是为 s_benkyou_2 写的，不能挂到别的释义上
+	w, fake := put(customDef, `{"vocab_id": "v_benkyou", "sense_id": "s_benkyou_1"}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("switch: status = %d: %s", w.Code, w.Body.String())
+	}
+	if n := len(fake.stmts(`UPDATE "vocabulary_words"`)); n != 0 {
+		t.Errorf("switch rejected but %d updates ran", n)
+	}
+	// 检查在锁住该行之后进行
+	if locked := fake.stmts("FOR UPDATE"); len(locked) != 1 || !strings.Contains(locked[0].query, `FROM "vocabulary_words"`) {
+		t.Errorf("locked = %+v", locked)
+	}
+	// 其他单词的释义不能选
+	w, fake = put([]driver.Value{"", nil, ""}, `{"vocab_id": "v_benkyou", "sense_id": "s_nihongo_1"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("foreign sense: status = %d: %s", w.Code, w.Body.String())
+	}
+	if n := len(fake.stmts(`UPDATE "vocabulary_words"`)); n != 0 {
+		t.Errorf("foreign sense stored")
+	}
+	// 清除自定义内容后可以自由切换；切回自定义内容所针对的释义也可以
+	if w, _ := put([]driver.Value{"", nil, ""}, `{"vocab_id": "v_benkyou", "sense_id": "s_benkyou_1"}`); w.Code != http.StatusOK {
+		t.Errorf("no custom: status = %d: %s", w.Code, w.Body.String())
+	}
+	if w, _ := put(customDef, `{"vocab_id": "v_benkyou", "sense_id": "s_benkyou_2"}`); w.Code != http.StatusOK {
+		t.Errorf("same sense: status = %d: %s", w.Code, w.Body.String())
+	}
+
+	// 已有的例句针对的是别的释义，只改释义会把旧例句挂到新释义上
+	staleExamples := []driver.Value{"", `[{"kanji": "毎日勉強する。"}]`, "s_benkyou_1"}
+	if w, _ := put(staleExamples, `{"custom_def": "砍价"}`); w.Code != http.StatusConflict {
+		t.Errorf("stale examples: status = %d: %s", w.Code, w.Body.String())
+	}
+	w, fake = put(staleExamples, `{"custom_def": "砍价", "custom_examples": []}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("replace both: status = %d: %s", w.Code, w.Body.String())
+	}
+	if got := fake.stmts(`"custom_sense_id"=`); len(got) != 1 || !containsArg(got[0].args, "s_benkyou_2") {
+		t.Errorf("custom_sense_id update = %+v", got)
+	}
+}
+
+// containsArg 语句参数中是否有 v
+func containsArg(args []driver.Value, v driver.Value) bool {
+	for _, a := range args {
+		if a == v {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBookWordCustomDetached(t *testing.T) {
+	sense := func(id, def string) model.VocabSense { return model.VocabSense{ID: id, VocabID: "v_benkyou", Def: def} }
+	rel := model.VocabularyWord{
+		VocabID:       "v_benkyou",
+		CustomDef:     "砍价",
+		CustomSenseID: "s_benkyou_2",
+		Vocab:         model.Vocab{ID: "v_benkyou", Senses: []model.VocabSense{sense("s_benkyou_2", "便宜，让价")}},
+	}
+
+	// 单义词未选择释义时覆盖唯一的释义
+	if w := dto.ToVocabBookWordDTO(rel); w.CustomDetached || w.Word.Senses[0].Def != "砍价" {
+		t.Errorf("single sense: %+v", w)
+	}
+
+	cases := map[string]func(r *model.VocabularyWord){
+		// 审核草稿后单词多了一个释义，未选择时没有覆盖目标
+		"gained sense": func(r *model.VocabularyWord) {
+			r.Vocab.Senses = append(r.Vocab.Senses, sense("s_benkyou_1", "学习"))
+		},
+		// 选中的释义在编辑单词时被删掉了
+		"sense deleted": func(r *model.VocabularyWord) {
+			r.SenseID = "s_benkyou_2"
+			r.Vocab.Senses = []model.VocabSense{sense("s_benkyou_3", "用功")}
+		},
+		// 目标释义不是自定义内容所针对的释义
+		"other sense": func(r *model.VocabularyWord) {
+			r.SenseID = "s_benkyou_1"
+			r.Vocab.Senses = append(r.Vocab.Senses, sense("s_benkyou_1", "学习"))
+		},
+	}
+	for name, change := range cases {
+		r := rel
+		r.Vocab.Senses = append([]model.VocabSense(nil), rel.Vocab.Senses...)
+		change(&r)
+		w := dto.ToVocabBookWordDTO(r)
+		if !w.CustomDetached || w.CustomDef != "砍价" {
+			t.Errorf("%s: detached = %v, custom_def = %q", name, w.CustomDetached, w.CustomDef)
+		}
+		for _, s := range w.Word.Senses {
+			if s.Def == "砍价" || s.OriginalDef != "" {
+				t.Errorf("%s: custom def merged into %+v", name, s)
+			}
+		}
+	}
+}
+
+func TestBookWordCount(t *testing.T) {
+	cache.GlobalDict = cache.NewDictCache()
+	db, fake := newFakeDB(t, map[string]fakeTable{
+		"vocabularies": {columns: []string{"id"}, rows: [][]driver.Value{{"b1"}, {"b2"}}},
+		"vocabs":       {columns: []string{"id", "kanji"}, rows: [][]driver.Value{{"v_benkyou", "勉強"}}},
+		// 勉強 在两本词书中
+		"vocabulary_words": {columns: []string{"vocabulary_id"}, rows: [][]driver.Value{{"b1"}, {"b2"}}},
+	})
+	fake.onQuery("MAX(position)", fakeTable{columns: []string{"next"}, rows: [][]driver.Value{{int64(0)}}})
+	fake.onQuery(`SELECT "vocab_id" FROM "vocabulary_words"`, fakeTable{columns: []string{"vocab_id"}})
+	fake.onExec(`DELETE FROM "vocabulary_words"`, 1)
+	bookCount := func(n int64) {
+		fake.onQuery("SELECT count(*)", fakeTable{columns: []string{"count"}, rows: [][]driver.Value{{n}}})
+	}
+	// 写回词书的单词数，按词书 ID
+	writtenCounts := func() map[string]any {
+		out := map[string]any{}
+		for _, s := range fake.stmts(`UPDATE "vocabularies" SET "count"`) {
+			out[s.args[len(s.args)-1].(string)] = s.args[0]
+		}
+		return out
+	}
+
+	r := gin.New()
+	r.POST("/vocab-book/:id/words", AddBookWords(db))
+	r.DELETE("/vocab-book/:id/words", RemoveBookWords(db))
+	r.DELETE("/word/:id", DeleteWord(db))
+	send := func(method, path, body string) map[string]any {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s: status = %d: %s", method, path, w.Code, w.Body)
+		}
+		var out map[string]any
+		json.Unmarshal(w.Body.Bytes(), &out)
+		return out
+	}
+
+	bookCount(1)
+	if out := send(http.MethodPost, "/vocab-book/b1/words", `{"vocab_ids": ["v_benkyou"]}`); out["added"] != 1.0 || out["count"] != 1.0 {
+		t.Errorf("add = %v", out)
+	}
+	if got := writtenCounts(); got["b1"] != int64(1) {
+		t.Errorf("count after add = %v", got)
+	}
+
+	// 已从词库删除的单词也能按 vocab_id 移除
+	bookCount(0)
+	if out := send(http.MethodDelete, "/vocab-book/b1/words", `{"vocab_ids": ["v_deleted"]}`); out["removed"] != 1.0 || out["count"] != 0.0 {
+		t.Errorf("remove = %v", out)
+	}
+	del := fake.stmts(`DELETE FROM "vocabulary_words"`)
+	if len(del) != 1 || del[0].args[1] != "v_deleted" {
+		t.Errorf("remove = %+v", del)
+	}
+	if got := writtenCounts(); got["b1"] != int64(0) {
+		t.Errorf("count after remove = %v", got)
+	}
+
+	// 删除单词时从所有词书中移除并重新统计
+	bookCount(4)
+	send(http.MethodDelete, "/word/v_benkyou", "")
+	del = fake.stmts(`DELETE FROM "vocabulary_words"`)
+	if len(del) != 2 || !strings.Contains(del[1].query, "vocab_id = $1") || del[1].args[0] != "v_benkyou" {
+		t.Errorf("delete word relations = %+v", del)
+	}
+	if got := writtenCounts(); got["b1"] != int64(4) || got["b2"] != int64(4) {
+		t.Errorf("count after delete word = %v", got)
+	}
+}
@@ -1,13 +1,19 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"dongwai_backend/internal/dto"
 	"dongwai_backend/internal/model"
 	"dongwai_backend/internal/pkg/ai"
 	"dongwai_backend/internal/pkg/cache"
+	"dongwai_backend/internal/pkg/disambig"
+	"dongwai_backend/internal/pkg/usage"
 	"dongwai_backend/internal/pkg/utils"
 
 	"github.com/gin-gonic/gin"
@@ -82,67 +88,15 @@ func CreateWord(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// AI 智能补全
-
 		// 🔥 自动检测：如果义项数量大于1，则标记为多义词
 		// 这覆盖了前端传来的值，也覆盖了 AI 的判断，确保数据库真实性
 		req.IsMulti = len(req.Senses) > 1
 
-		vocabID := utils.GenerateID("w_", req.Kanji, uuid.New().String())
-
-		newVocab := model.Vocab{
-			ID:       vocabID,
-			Kanji:    req.Kanji,
-			IsMulti:  req.IsMulti,
-			CreatAt:  time.Now(),
-			UpdataAt: time.Now(),
-		}
-
-		var senses []model.VocabSense
-		var examples []model.SenseExample
-
-		for _, s := range req.Senses {
-			senseID := utils.GenerateID("s_", vocabID, uuid.New().String())
-			newSense := model.VocabSense{
-				ID:       senseID,
-				VocabID:  vocabID,
-				Level:    s.Level,
-				Reading:  s.Reading,
-				Def:      s.Def,
-				Pos:      s.Pos,
-				Pitch:    s.Pitch,
-				Furigana: datatypes.JSON(utils.ToJSON(s.Furigana)),
-			}
-			senses = append(senses, newSense)
-			for _, ex := range s.Examples {
-				exID := utils.GenerateID("e_", senseID, uuid.New().String())
-				newEx := model.SenseExample{
-					ID:       exID,
-					SenseID:  senseID,
-					Kanji:    ex.Kanji,
-					Def:      ex.Def,
-					Audio:    ex.Audio,
-					Furigana: datatypes.JSON(utils.ToJSON(ex.Furigana)),
-				}
-				examples = append(examples, newEx)
-			}
-		}
-
+		var newVocab model.Vocab
 		err := db.Transaction(func(tx *gorm.DB) error {
-			if err := tx.Create(&newVocab).Error; err != nil {
-				return err
-			}
-			if len(senses) > 0 {
-				if err := tx.Create(&senses).Error; err != nil {
-					return err
-				}
-			}
-			if len(examples) > 0 {
-				if err := tx.Create(&examples).Error; err != nil {
-					return err
-				}
-			}
-			return nil
+			var err error
+			newVocab, err = createWord(tx, req)
+			return err
 		})
 
 		if err != nil {
@@ -150,10 +104,71 @@ func CreateWord(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		cache.GlobalDict.AddOrUpdate(req.Kanji, vocabID)
+		cache.GlobalDict.AddOrUpdate(newVocab)
+
+		c.JSON(http.StatusOK, gin.H{"id": newVocab.ID, "message": "创建成功", "data": req})
+	}
+}
+
+// createWord 在事务中写入新单词及其释义、例句，返回带 Senses 的单词 (供刷新词典缓存)
+func createWord(tx *gorm.DB, req CreateWordReq) (model.Vocab, error) {
+	vocabID := utils.GenerateID("w_", req.Kanji, uuid.New().String())
+
+	newVocab := model.Vocab{
+		ID:        vocabID,
+		Kanji:     req.Kanji,
+		KanjiNorm: cache.NormalizeKey(req.Kanji),
+		IsMulti:   req.IsMulti,
+		CreatAt:   time.Now(),
+		UpdataAt:  time.Now(),
+	}
+
+	var senses []model.VocabSense
+	var examples []model.SenseExample
+
+	for _, s := range req.Senses {
+		senseID := utils.GenerateID("s_", vocabID, uuid.New().String())
+		newSense := model.VocabSense{
+			ID:       senseID,
+			VocabID:  vocabID,
+			Level:    s.Level,
+			Reading:  s.Reading,
+			Def:      s.Def,
+			Pos:      s.Pos,
+			Pitch:    s.Pitch,
+			Furigana: datatypes.JSON(utils.ToJSON(s.Furigana)),
+		}
+		senses = append(senses, newSense)
+		for _, ex := range s.Examples {
+			exID := utils.GenerateID("e_", senseID, uuid.New().String())
+			newEx := model.SenseExample{
+				ID:       exID,
+				SenseID:  senseID,
+				Kanji:    ex.Kanji,
+				Def:      ex.Def,
+				Audio:    ex.Audio,
+				Furigana: datatypes.JSON(utils.ToJSON(ex.Furigana)),
+			}
+			examples = append(examples, newEx)
+		}
+	}
 
-		c.JSON(http.StatusOK, gin.H{"id": vocabID, "message": "创建成功", "data": req})
+	if err := tx.Create(&newVocab).Error; err != nil {
+		return newVocab, err
+	}
+	if len(senses) > 0 {
+		if err := tx.Create(&senses).Error; err != nil {
+			return newVocab, err
+		}
+	}
+	if len(examples) > 0 {
+		if err := tx.Create(&examples).Error; err != nil {
+			return newVocab, err
+		}
 	}
+
+	newVocab.Senses = senses
+	return newVocab, nil
 }
 
 // UpdateWord 修改单词
@@ -175,104 +190,143 @@ func UpdateWord(db *gorm.DB) gin.HandlerFunc {
 		}
 		oldKanji := oldVocab.Kanji
 
-		err := db.Transaction(func(tx *gorm.DB) error {
-			// 更新 Vocab 表 (包含自动计算的 IsMulti)
-			if err := tx.Model(&model.Vocab{}).Where("id = ?", req.ID).Updates(map[string]interface{}{
-				"kanji":     req.Kanji,
-				"is_multi":  req.IsMulti,
-				"updata_at": time.Now(),
-			}).Error; err != nil {
-				return err
-			}
+		// 更新前已有的释义，成功后据此清理消歧缓存
+		existingSenseIDs, err := saveWordUpdate(db, req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败: " + err.Error()})
+			return
+		}
 
-			// --- Sense 处理逻辑 ---
-			var existingSenseIDs []string
-			tx.Model(&model.VocabSense{}).Where("vocab_id = ?", req.ID).Pluck("id", &existingSenseIDs)
-			existingMap := make(map[string]bool)
-			for _, id := range existingSenseIDs {
-				existingMap[id] = true
-			}
+		refreshWordCache(c.Request.Context(), oldKanji, req, existingSenseIDs)
 
-			processedIDs := make(map[string]bool)
-
-			for _, s := range req.Senses {
-				var senseID string
-				if s.ID != "" && existingMap[s.ID] {
-					// 更新现有
-					senseID = s.ID
-					tx.Model(&model.VocabSense{}).Where("id = ?", senseID).Updates(model.VocabSense{
-						Level:    s.Level,
-						Reading:  s.Reading,
-						Def:      s.Def,
-						Pos:      s.Pos,
-						Pitch:    s.Pitch,
-						Furigana: datatypes.JSON(utils.ToJSON(s.Furigana)),
-					})
-					tx.Where("sense_id = ?", senseID).Delete(&model.SenseExample{})
-				} else {
-					// 新增
-					senseID = utils.GenerateID("s_", req.ID, uuid.New().String())
-					newSense := model.VocabSense{
-						ID:       senseID,
-						VocabID:  req.ID,
-						Level:    s.Level,
-						Reading:  s.Reading,
-						Def:      s.Def,
-						Pos:      s.Pos,
-						Pitch:    s.Pitch,
-						Furigana: datatypes.JSON(utils.ToJSON(s.Furigana)),
-					}
-					if err := tx.Create(&newSense).Error; err != nil {
-						return err
-					}
-				}
-				processedIDs[senseID] = true
-
-				var newExamples []model.SenseExample
-				for _, ex := range s.Examples {
-					exID := utils.GenerateID("e_", senseID, uuid.New().String())
-					newExamples = append(newExamples, model.SenseExample{
-						ID:       exID,
-						SenseID:  senseID,
-						Kanji:    ex.Kanji,
-						Def:      ex.Def,
-						Audio:    ex.Audio,
-						Furigana: datatypes.JSON(utils.ToJSON(ex.Furigana)),
-					})
+		c.JSON(http.StatusOK, gin.H{"message": "更新成功"})
+	}
+}
+
+// saveWordUpdate 在事务中写入单词的完整内容：带 ID 的释义原地更新，其余新增，未保留的释义删除
+// 返回更新前已有的释义 ID
+func saveWordUpdate(db *gorm.DB, req UpdateWordReq) ([]string, error) {
+	var existingSenseIDs []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 更新 Vocab 表 (包含自动计算的 IsMulti)
+		if err := tx.Model(&model.Vocab{}).Where("id = ?", req.ID).Updates(map[string]interface{}{
+			"kanji":      req.Kanji,
+			"kanji_norm": cache.NormalizeKey(req.Kanji),
+			"is_multi":   req.IsMulti,
+			"updata_at":  time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		// --- Sense 处理逻辑 ---
+		tx.Model(&model.VocabSense{}).Where("vocab_id = ?", req.ID).Pluck("id", &existingSenseIDs)
+		existingMap := make(map[string]bool)
+		for _, id := range existingSenseIDs {
+			existingMap[id] = true
+		}
+
+		processedIDs := make(map[string]bool)
+
+		for _, s := range req.Senses {
+			var senseID string
+			if s.ID != "" && existingMap[s.ID] {
+				// 更新现有
+				senseID = s.ID
+				tx.Model(&model.VocabSense{}).Where("id = ?", senseID).Updates(model.VocabSense{
+					Level:    s.Level,
+					Reading:  s.Reading,
+					Def:      s.Def,
+					Pos:      s.Pos,
+					Pitch:    s.Pitch,
+					Furigana: datatypes.JSON(utils.ToJSON(s.Furigana)),
+				})
+				tx.Where("sense_id = ?", senseID).Delete(&model.SenseExample{})
+			} else {
+				// 新增
+				senseID = utils.GenerateID("s_", req.ID, uuid.New().String())
+				newSense := model.VocabSense{
+					ID:       senseID,
+					VocabID:  req.ID,
+					Level:    s.Level,
+					Reading:  s.Reading,
+					Def:      s.Def,
+					Pos:      s.Pos,
+					Pitch:    s.Pitch,
+					Furigana: datatypes.JSON(utils.ToJSON(s.Furigana)),
 				}
-				if len(newExamples) > 0 {
-					if err := tx.Create(&newExamples).Error; err != nil {
-						return err
-					}
+				if err := tx.Create(&newSense).Error; err != nil {
+					return err
 				}
 			}
+			processedIDs[senseID] = true
 
-			// 删除未保留的 Sense
-			for _, oldID := range existingSenseIDs {
-				if !processedIDs[oldID] {
-					tx.Where("sense_id = ?", oldID).Delete(&model.SenseExample{})
-					tx.Where("id = ?", oldID).Delete(&model.VocabSense{})
+			var newExamples []model.SenseExample
+			for _, ex := range s.Examples {
+				exID := utils.GenerateID("e_", senseID, uuid.New().String())
+				newExamples = append(newExamples, model.SenseExample{
+					ID:       exID,
+					SenseID:  senseID,
+					Kanji:    ex.Kanji,
+					Def:      ex.Def,
+					Audio:    ex.Audio,
+					Furigana: datatypes.JSON(utils.ToJSON(ex.Furigana)),
+				})
+			}
+			if len(newExamples) > 0 {
+				if err := tx.Create(&newExamples).Error; err != nil {
+					return err
 				}
 			}
+		}
 
-			return nil
-		})
-
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败: " + err.Error()})
-			return
+		// 删除未保留的 Sense
+		for _, oldID := range existingSenseIDs {
+			if !processedIDs[oldID] {
+				tx.Where("sense_id = ?", oldID).Delete(&model.SenseExample{})
+				tx.Where("id = ?", oldID).Delete(&model.VocabSense{})
+			}
 		}
 
+		return nil
+	})
+	return existingSenseIDs, err
+}
+
+// refreshWordCache 单词更新后刷新词典缓存并清理相关的消歧缓存
+func refreshWordCache(ctx context.Context, oldKanji string, req UpdateWordReq, senseIDs []string) {
+	cache.GlobalDict.Batch(func(b *cache.DictBatch) {
 		if oldKanji != req.Kanji {
-			cache.GlobalDict.Remove(oldKanji, req.ID)
+			b.Remove(oldKanji, req.ID)
 		}
-		cache.GlobalDict.AddOrUpdate(req.Kanji, req.ID)
+		b.AddOrUpdate(toCacheVocab(req))
+	})
+	invalidateDisambig(ctx, senseIDs)
+}
 
-		c.JSON(http.StatusOK, gin.H{"message": "更新成功"})
+// invalidateDisambig 释义被修改或删除后，清理以其为候选的消歧缓存
+func invalidateDisambig(ctx context.Context, senseIDs []string) {
+	if disambig.Default == nil || len(senseIDs) == 0 {
+		return
+	}
+	if err := disambig.Default.InvalidateSenses(ctx, senseIDs); err != nil {
+		log.Printf("清理消歧缓存失败: %v", err)
+	}
+}
+
+// toCacheVocab 将更新请求转换为刷新缓存所需的 Vocab (只包含缓存关心的字段)
+func toCacheVocab(req UpdateWordReq) model.Vocab {
+	vocab := model.Vocab{ID: req.ID, Kanji: req.Kanji}
+	for _, s := range req.Senses {
+		vocab.Senses = append(vocab.Senses, model.VocabSense{
+			VocabID: req.ID,
+			Level:   s.Level,
+			Reading: s.Reading,
+		})
 	}
+	return vocab
 }
 
-// DeleteWord 删除单词
+// DeleteWord 删除单词 (同时从所有词书中移除并更新这些词书的单词数)
 func DeleteWord(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
@@ -283,8 +337,11 @@ func DeleteWord(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		var senseIDs []string
 		err := db.Transaction(func(tx *gorm.DB) error {
-			var senseIDs []string
+			if err := removeWordFromBooks(tx, id); err != nil {
+				return err
+			}
 			tx.Model(&model.VocabSense{}).Where("vocab_id = ?", id).Pluck("id", &senseIDs)
 			if len(senseIDs) > 0 {
 				tx.Where("sense_id IN ?", senseIDs).Delete(&model.SenseExample{})
@@ -298,11 +355,46 @@ func DeleteWord(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		cache.GlobalDict.Remove(vocab.Kanji, id)
+		invalidateDisambig(c.Request.Context(), senseIDs)
 
 		c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
 	}
 }
 
+// removeWordFromBooks 在事务中把单词从所有词书中移除，并重新统计这些词书的单词数
+// 按 ID 顺序锁定词书，与其他词书增删操作的加锁顺序一致
+func removeWordFromBooks(tx *gorm.DB, vocabID string) error {
+	var bookIDs []string
+	if err := tx.Model(&model.VocabularyWord{}).Where("vocab_id = ?", vocabID).
+		Distinct().Order("vocabulary_id").Pluck("vocabulary_id", &bookIDs).Error; err != nil {
+		return err
+	}
+	if len(bookIDs) == 0 {
+		return nil
+	}
+
+	var locked []string
+	for _, bookID := range bookIDs {
+		err := lockBook(tx, bookID)
+		if errors.Is(err, errBookNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		locked = append(locked, bookID)
+	}
+	if err := tx.Where("vocab_id = ?", vocabID).Delete(&model.VocabularyWord{}).Error; err != nil {
+		return err
+	}
+	for _, bookID := range locked {
+		if _, err := refreshBookCount(tx, bookID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ListWords 分页获取列表
 func ListWords(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -323,7 +415,12 @@ func ListWords(db *gorm.DB) gin.HandlerFunc {
 
 		query := db.Model(&model.Vocab{})
 		if req.Keyword != "" {
-			query = query.Where("kanji LIKE ? OR id = ?", "%"+req.Keyword+"%", req.Keyword)
+			// 原文按原文匹配；规范化后的关键词匹配规范化后的词面 (全角/半角、旧字体、~ 标记等两边一致)
+			normalized := cache.NormalizeKey(req.Keyword)
+			if normalized == "" {
+				normalized = req.Keyword
+			}
+			query = query.Where("kanji LIKE ? OR kanji_norm LIKE ? OR id = ?", "%"+req.Keyword+"%", "%"+normalized+"%", req.Keyword)
 		}
 
 		if err := query.Count(&total).Error; err != nil {
@@ -390,8 +487,15 @@ func GetWordDetail(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
+// GenerateWordResp AI 生成结果：可直接填入创建表单的数据 + 校验未通过的字段 (需人工确认)
+type GenerateWordResp struct {
+	CreateWordReq
+	Warnings      []ai.ValidationWarning `json:"warnings"`
+	PromptVersion string                 `json:"prompt_version"` // 生成所用的提示词版本
+}
+
 // GenerateWordInfoHandler AI 自动生成单词信息 (不保存，仅返回给前端填充表单)
-func GenerateWordInfoHandler() gin.HandlerFunc {
+func GenerateWordInfoHandler(provider ai.Provider) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
 			Kanji string `json:"kanji" binding:"required"`
@@ -402,9 +506,10 @@ func GenerateWordInfoHandler() gin.HandlerFunc {
 		}
 
 		// ✅ 传递上下文，支持取消
-		aiData, err := ai.GenerateWordInfo(c.Request.Context(), req.Kanji)
+		ctx := usage.WithUser(c.Request.Context(), c.GetString("userID"))
+		aiData, warnings, err := ai.GenerateWordInfo(ctx, provider, req.Kanji)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "AI 生成失败: " + err.Error()})
+			c.JSON(aiErrorStatus(err), gin.H{"error": "AI 生成失败: " + err.Error()})
 			return
 		}
 
@@ -430,12 +535,53 @@ func GenerateWordInfoHandler() gin.HandlerFunc {
 			})
 		}
 
-		resp := CreateWordReq{
-			Kanji:   req.Kanji,
-			IsMulti: len(senses) > 1,
-			Senses:  senses,
+		resp := GenerateWordResp{
+			CreateWordReq: CreateWordReq{
+				Kanji:   req.Kanji,
+				IsMulti: len(senses) > 1,
+				Senses:  senses,
+			},
+			Warnings:      warnings,
+			PromptVersion: aiData.PromptVersion,
+		}
+		if resp.Warnings == nil {
+			resp.Warnings = []ai.ValidationWarning{}
 		}
 
 		c.JSON(http.StatusOK, resp)
 	}
 }
+
+// syncKanjiNormBatch SyncKanjiNorm 每条 UPDATE 更新的行数
+const syncKanjiNormBatch = 500
+
+// SyncKanjiNorm 重新计算规范化词面 (启动时调用：补齐旧数据，TEXT_NORMALIZE 变更后也需重算)
+// 规范化方式与词典缓存的键相同 (cache.NormalizeKey)；只更新与当前结果不一致的行，
+// 每批一条 UPDATE，不修改 updata_at (草稿审核靠它判断单词是否被改过)
+// 返回更新的行数
+func SyncKanjiNorm(db *gorm.DB) (int, error) {
+	var rows []struct {
+		ID        string
+		Kanji     string
+		KanjiNorm string
+	}
+	if err := db.Model(&model.Vocab{}).Select("id, kanji, kanji_norm").Find(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	var stale []any
+	for _, r := range rows {
+		if norm := cache.NormalizeKey(r.Kanji); norm != r.KanjiNorm {
+			stale = append(stale, r.ID, norm)
+		}
+	}
+	for start := 0; start < len(stale); start += 2 * syncKanjiNormBatch {
+		batch := stale[start:min(start+2*syncKanjiNormBatch, len(stale))]
+		values := strings.TrimSuffix(strings.Repeat("(?, ?), ", len(batch)/2), ", ")
+		err := db.Exec("UPDATE vocabs SET kanji_norm = v.norm FROM (VALUES "+values+") AS v(id, norm) WHERE vocabs.id = v.id", batch...).Error
+		if err != nil {
+			return start / 2, err
+		}
+	}
+	return len(stale) / 2, nil
+}
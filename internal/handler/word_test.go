@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"dongwai_backend/internal/pkg/ai"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGenerateWordInfoHandler(t *testing.T) {
+	const reply = `{"kanji":"猫","is_multi":false,"senses":[{"level":"N5","reading":"ねこ","furigana":[["猫","ねこ"]],"pitch":"①","pos":"名词","def":"猫","examples":[{"kanji":"猫が好きです","furigana":[["猫","ねこ"],["が",""],["好き","すき"],["です",""]],"def":"我喜欢猫。"}]}]}`
+
+	cases := []struct {
+		name     string
+		provider ai.Provider
+		want     int
+	}{
+		{"ok", ai.NewFake(ai.FakeReply{Content: "```json\n" + reply + "\n```"}), http.StatusOK},
+		{"provider error", ai.NewFake(ai.FakeReply{Err: errors.New("boom")}), http.StatusBadGateway},
+		{"circuit open", ai.NewFake(ai.FakeReply{Err: ai.ErrCircuitOpen}), http.StatusServiceUnavailable},
+		{"not configured", nil, http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			r.POST("/word/generate", GenerateWordInfoHandler(tc.provider))
+
+			req := httptest.NewRequest(http.MethodPost, "/word/generate", strings.NewReader(`{"kanji":"猫"}`))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.want {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tc.want, w.Body.String())
+			}
+			if tc.want != http.StatusOK {
+				return
+			}
+
+			var resp GenerateWordResp
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Kanji != "猫" || len(resp.Senses) != 1 {
+				t.Fatalf("resp = %+v", resp)
+			}
+			s := resp.Senses[0]
+			if s.Reading != "ねこ" || s.Level != "N5" || len(s.Examples) != 1 {
+				t.Errorf("sense = %+v", s)
+			}
+			if len(resp.Warnings) != 0 {
+				t.Errorf("warnings = %+v", resp.Warnings)
+			}
+		})
+	}
+}
+
+func TestSyncKanjiNorm(t *testing.T) {
+	db, fake := newFakeDB(t, map[string]fakeTable{
+		"vocabs": {
+			columns: []string{"id", "kanji", "kanji_norm"},
+			rows: [][]driver.Value{
+				{"v_kokugo", "國語", ""},       // 旧数据，尚未规范化
+				{"v_nihongo", "日本語", "日本語"},  // 已是最新
+				{"v_bakari", "~ばかり", "~ばかり"}, // 与词典缓存的键一样去掉 ~ 标记
+			},
+		},
+	})
+
+	n, err := SyncKanjiNorm(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 需要更新的行合并成一条 UPDATE
+	updates := fake.stmts("UPDATE vocabs SET kanji_norm")
+	if n != 2 || len(updates) != 1 {
+		t.Fatalf("updated = %d, stmts = %v", n, updates)
+	}
+	if args, want := updates[0].args, []driver.Value{"v_kokugo", "国語", "v_bakari", "ばかり"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+}
+
+func TestListWordsSearchesNormalizedKanji(t *testing.T) {
+	db, fake := newFakeDB(t, nil)
+	r := gin.New()
+	r.POST("/word/list", ListWords(db))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/word/list", strings.NewReader(`{"keyword":"國語"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	// 关键词与词面都经过规范化：搜 國語 / 国語 都能找到存为 國語 的单词
+	counts := fake.stmts("kanji_norm LIKE")
+	if len(counts) == 0 {
+		t.Fatal("search does not use kanji_norm")
+	}
+	if args := counts[0].args; args[0] != "%國語%" || args[1] != "%国語%" {
+		t.Errorf("args = %v", args)
+	}
+
+	// 接头/接尾辞按去掉 ~ 后的词面查找
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/word/list", strings.NewReader(`{"keyword":"～ばかり"}`)))
+	counts = fake.stmts("kanji_norm LIKE")
+	if args := counts[len(counts)-1].args; args[1] != "%ばかり%" {
+		t.Errorf("affix args = %v", args)
+	}
+}
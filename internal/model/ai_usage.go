@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// AIUsage 一次 AI 接口调用的用量记录
+type AIUsage struct {
+	ID               string    `gorm:"primaryKey;type:varchar(36)"`
+	Feature          string    `gorm:"index;type:varchar(32)"` // 功能: disambiguate / generate_word ...
+	UserID           string    `gorm:"index;type:varchar(64)"` // JWT 中的用户 ID，匿名访问为 "ip:<客户端 IP>"
+	Model            string    `gorm:"type:varchar(64)"`
+	PromptVersion    string    `gorm:"type:varchar(16)"` // 所用提示词版本
+	PromptTokens     int       `gorm:"default:0"`
+	CompletionTokens int       `gorm:"default:0"`
+	TotalTokens      int       `gorm:"default:0"`
+	LatencyMs        int64     `gorm:"default:0"`
+	Outcome          string    `gorm:"index;type:varchar(16)"` // ok / error / rejected
+	Error            string    `gorm:"type:text"`
+	CreatedAt        time.Time `gorm:"index"`
+}
+
+// AIQuota 用户的每日 token 配额 (覆盖全局默认值，0 表示不限)
+type AIQuota struct {
+	UserID      string `gorm:"primaryKey;type:varchar(64)"`
+	DailyTokens int64  `gorm:"not null"`
+	UpdatedAt   time.Time
+}
+
+// AIDailyUsage 用户每日已用 (含预留中) 的 token 数，配额检查在这一行上原子地预留
+type AIDailyUsage struct {
+	UserID string `gorm:"primaryKey;type:varchar(64)"`
+	Day    string `gorm:"primaryKey;type:varchar(10)"` // YYYY-MM-DD (服务器时区)
+	Tokens int64  `gorm:"not null;default:0"`
+}
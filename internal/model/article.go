@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Article 文章库中的文章
+type Article struct {
+	ID        string         `gorm:"primaryKey;type:varchar(36)"`
+	Title     string         `gorm:"not null"`
+	Source    string         // 出处 (网址、教材名等)
+	Content   string         `gorm:"type:text"`
+	OwnerID   string         `gorm:"index;type:varchar(36)"`
+	Tags      datatypes.JSON `gorm:"type:jsonb"` // []string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ArticleAnalysis 文章的一次分析结果快照 (不含教师修正，修正在读取时应用)
+type ArticleAnalysis struct {
+	ID        string `gorm:"primaryKey;type:varchar(36)"`
+	ArticleID string `gorm:"index;type:varchar(36)"`
+	Strategy  string `gorm:"type:varchar(16)"`
+	// 分析时正文的哈希，与文章当前正文不一致说明快照已过期
+	ContentHash string         `gorm:"type:varchar(64)"`
+	Result      datatypes.JSON `gorm:"type:jsonb"` // 完整的分析结果 (handler.AnalyzeResp)
+	CreatedBy   string         `gorm:"type:varchar(36)"`
+	CreatedAt   time.Time
+}
+
+// ArticleOverride 教师对某个 Token 选中释义的修正
+// 按原文位置记录；重新分析后该位置的 Token 起止与原文都未变时继续生效，
+// 修改正文时改动之后的修正随之平移，改动处的修正是否生效同样按原文判断
+type ArticleOverride struct {
+	ID        string `gorm:"primaryKey;type:varchar(36)"`
+	ArticleID string `gorm:"uniqueIndex:idx_article_override_pos;type:varchar(36)"`
+	Start     int    `gorm:"uniqueIndex:idx_article_override_pos"` // Token 的 rune 起始下标
+	End       int    // Token 的 rune 结束下标 (不含)
+	Text      string `gorm:"type:varchar(64)"` // 修正时该位置的原文
+	VocabID   string `gorm:"type:varchar(32)"`
+	SenseID   string `gorm:"type:varchar(32)"`
+	CreatedBy string `gorm:"type:varchar(36)"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
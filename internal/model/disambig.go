@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// DisambigResult AI 消歧结果缓存
+// Key 为 (单词, 上下文, 候选释义 ID 列表) 的哈希，Choice 为候选释义的下标
+type DisambigResult struct {
+	Key    string `gorm:"primaryKey;type:varchar(64)"`
+	Choice int    `gorm:"not null"`
+	// 产生该结果的提示词版本
+	PromptVersion string    `gorm:"type:varchar(16)"`
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+}
+
+// DisambigResultSense 缓存条目与候选释义的关联 (释义被修改时据此失效)
+type DisambigResultSense struct {
+	Key     string `gorm:"primaryKey;type:varchar(64)"`
+	SenseID string `gorm:"primaryKey;type:varchar(32);index"`
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// EnrichJob 批量 AI 补全任务
+type EnrichJob struct {
+	ID         string         `gorm:"primaryKey;type:varchar(36)"`
+	Status     string         `gorm:"index;type:varchar(16)"` // pending / running / done / cancelled
+	Filter     datatypes.JSON `gorm:"type:jsonb"`             // 创建任务时的筛选条件
+	Total      int            `gorm:"default:0"`
+	Processed  int            `gorm:"default:0"` // 已结束的条目 (成功 + 失败 + 跳过)
+	Succeeded  int            `gorm:"default:0"` // 生成了草稿的条目
+	Skipped    int            `gorm:"default:0"` // 生成结果没有可补全内容的条目
+	Failed     int            `gorm:"default:0"`
+	CreatedBy  string         `gorm:"type:varchar(36)"` // 发起人 (AI 用量记在其名下)
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+}
+
+// EnrichItem 任务中的一个单词 (队列中的一项)
+type EnrichItem struct {
+	ID      string `gorm:"primaryKey;type:varchar(36)"`
+	JobID   string `gorm:"index;type:varchar(36)"`
+	VocabID string `gorm:"index;type:varchar(32)"` // 为空表示词库中还没有该单词，按 Kanji 生成新词
+	Kanji   string `gorm:"type:varchar(64)"`
+	Status  string `gorm:"index;type:varchar(16)"` // pending / running / done / skipped / failed / cancelled
+	// 生成的草稿审核通过后加入的词书 (导入词书时找不到的单词)
+	VocabularyID string    `gorm:"type:varchar(32)"`
+	Attempts     int       `gorm:"default:0"`
+	Error        string    `gorm:"type:text"` // 最近一次失败的原因
+	DraftID      string    `gorm:"type:varchar(36)"`
+	NextRunAt    time.Time `gorm:"index"` // 重试前的等待截止时间
+	LockedAt     *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// WordDraft 待审核的单词草稿 (AI 生成，审核通过后才写入词库)
+type WordDraft struct {
+	ID      string `gorm:"primaryKey;type:varchar(36)"`
+	VocabID string `gorm:"index;type:varchar(32)"` // 补全的目标单词，为空表示审核通过后新建单词
+	Kanji   string `gorm:"type:varchar(64)"`
+	// 审核通过后把单词加入该词书
+	VocabularyID string `gorm:"type:varchar(32)"`
+	JobID        string `gorm:"index;type:varchar(36)"`
+	Status       string `gorm:"index;type:varchar(16)"` // pending / approved / rejected
+
+	Data     datatypes.JSON `gorm:"type:jsonb"` // 审核通过后的完整单词 (dto.WordDTO，已有释义保留 ID)
+	Changes  datatypes.JSON `gorm:"type:jsonb"` // 补全了哪些字段，如 ["senses[0].pitch"]
+	Warnings datatypes.JSON `gorm:"type:jsonb"` // AI 结果未通过的校验项
+
+	PromptVersion string `gorm:"type:varchar(16)"`
+	// 生成草稿时单词的修改时间，审核时据此判断单词是否已被他人修改
+	BaseUpdatedAt time.Time
+
+	CreatedBy  string `gorm:"type:varchar(36)"`
+	ReviewedBy string `gorm:"type:varchar(36)"`
+	CreatedAt  time.Time
+	ReviewedAt *time.Time
+}
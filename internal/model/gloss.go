@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// SentenceGloss 逐句翻译缓存，Hash 为句子原文的哈希
+type SentenceGloss struct {
+	Hash          string    `gorm:"primaryKey;type:varchar(64)"`
+	Text          string    `gorm:"type:text"`
+	Translation   string    `gorm:"type:text"`
+	Grammar       string    `gorm:"type:text"` // 语法说明
+	PromptVersion string    `gorm:"type:varchar(16)"`
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+}
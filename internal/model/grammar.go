@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// GrammarPattern 语法点 (如 〜ばかり、〜わけではない)
+// Pattern 为匹配规则，语法见 internal/pkg/grammar
+type GrammarPattern struct {
+	ID          string           `gorm:"primaryKey;type:varchar(32)"`
+	Name        string           `gorm:"index;not null"`        // 展示用名称，如 〜わけではない
+	Pattern     string           `gorm:"not null"`              // 匹配规则，如 〜わけ では|じゃ ない
+	Level       string           `gorm:"index;type:varchar(5)"` // JLPT 等级 N1-N5
+	Meaning     string           `gorm:"type:text"`             // 简短释义
+	Explanation string           `gorm:"type:text"`             // 用法说明
+	CreatedAt   time.Time        `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time        `gorm:"autoUpdateTime"`
+	Examples    []GrammarExample `gorm:"foreignKey:PatternID"`
+}
+
+// GrammarExample 语法点的例句
+type GrammarExample struct {
+	ID        string         `gorm:"primaryKey;type:varchar(32)"`
+	PatternID string         `gorm:"index"`
+	Kanji     string         `gorm:"type:text"`
+	Furigana  datatypes.JSON `gorm:"type:jsonb"`
+	Def       string         `gorm:"type:text"`
+}
@@ -2,6 +2,8 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/datatypes"
 )
 
 // Vocabulary 词书表
@@ -24,8 +26,34 @@ type VocabularyWord struct {
 	// 如果为空字符串，表示用户尚未指定具体释义
 	SenseID string `gorm:"type:varchar(32);index"`
 
+	// 从文章生成词书时，记录单词所在的原句 (及译文) 作为语境例句
+	ContextSentence    string `gorm:"type:text"`
+	ContextTranslation string `gorm:"type:text"`
+	SourceArticleID    string `gorm:"type:varchar(36)"` // 来源文章，未保存的分析为空
+
+	// 所属分组与在分组内的顺序；SectionID 为空表示未分组 (排在所有分组之后)
+	SectionID string `gorm:"type:varchar(32);index"`
+	Position  int    `gorm:"default:0"`
+
+	// 仅在本词书中生效的自定义内容 (不修改词库)，渲染时覆盖在选中的释义上
+	CustomDef      string         `gorm:"type:text"`  // 自定义释义，为空表示沿用词库释义
+	Note           string         `gorm:"type:text"`  // 记忆提示、备注
+	CustomExamples datatypes.JSON `gorm:"type:jsonb"` // 自定义例句 (与 dto.ExampleDTO 结构相同的数组)
+	// 自定义释义与例句是为哪个释义写的；与当前覆盖的释义不一致时 (释义被删除、单词新增了释义) 不再合并
+	CustomSenseID string `gorm:"type:varchar(32)"`
+
 	CreatedAt time.Time `gorm:"autoCreateTime"`
 
 	// 关联 Vocab，方便 Preload 查询
 	Vocab Vocab `gorm:"foreignKey:VocabID"`
 }
+
+// VocabSection 词书中的分组 (章节 / 课)，按 Position 排列
+type VocabSection struct {
+	ID           string `gorm:"primaryKey;type:varchar(32)"`
+	VocabularyID string `gorm:"type:varchar(32);index"`
+	Name         string `gorm:"not null"`
+	Position     int    `gorm:"default:0"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
@@ -7,12 +7,13 @@ import (
 )
 
 type Vocab struct {
-	ID       string `gorm:"primary;type:varchar(32)"`
-	Kanji    string `gorm:"index;not null"`
-	IsMulti  bool   `gorm:"type:bool"`
-	CreatAt  time.Time
-	UpdataAt time.Time
-	Senses   []VocabSense `gorm:"foreignKey:VocabID"`
+	ID        string `gorm:"primary;type:varchar(32)"`
+	Kanji     string `gorm:"index;not null"`
+	KanjiNorm string `gorm:"index"` // 规范化后的词面 (与词典缓存的键相同，见 cache.NormalizeKey)，供搜索与查重使用，与搜索关键词走同一套规范化
+	IsMulti   bool   `gorm:"type:bool"`
+	CreatAt   time.Time
+	UpdataAt  time.Time
+	Senses    []VocabSense `gorm:"foreignKey:VocabID"`
 }
 
 type VocabSense struct {
@@ -0,0 +1,127 @@
+package ai
+
+import (
+	"context"
+	"sync"
+)
+
+// 分块消歧的默认参数
+const (
+	DefaultChunkSize   = 20 // 每块最多的候选词数
+	DefaultConcurrency = 4  // 同时进行的请求数
+)
+
+// ChunkOptions 分块消歧的参数，非正数时使用默认值
+type ChunkOptions struct {
+	ChunkSize   int
+	Concurrency int
+}
+
+func (o ChunkOptions) concurrency() int {
+	if o.Concurrency <= 0 {
+		return DefaultConcurrency
+	}
+	return o.Concurrency
+}
+
+// ChunkResult 单个分块的消歧结果
+type ChunkResult struct {
+	Index  int            // 分块下标 (从 0 开始)
+	Total  int            // 分块总数
+	Words  []string       // 本块包含的 WordID
+	Result map[string]int // 成功时的 WordID -> 释义下标
+	Err    error          // 失败原因，非空时 Result 为 nil
+
+	PromptVersion string // 所用提示词版本
+}
+
+// ChunkCandidates 按句子边界把候选集切成不超过 size 个候选的分块
+// 同一句子的候选尽量放在同一块 (保证上下文一致)，只有单句超过 size 时才会被拆开
+func ChunkCandidates(candidates []Candidate, size int) [][]Candidate {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+
+	var chunks [][]Candidate
+	var cur []Candidate
+	for i := 0; i < len(candidates); {
+		// 取出同一句子的连续候选
+		j := i + 1
+		for j < len(candidates) && candidates[j].Sentence == candidates[i].Sentence {
+			j++
+		}
+		group := candidates[i:j]
+		i = j
+
+		if len(cur)+len(group) > size && len(cur) > 0 {
+			chunks = append(chunks, cur)
+			cur = nil
+		}
+		for len(group) > size {
+			chunks = append(chunks, group[:size])
+			group = group[size:]
+		}
+		cur = append(cur, group...)
+	}
+	if len(cur) > 0 {
+		chunks = append(chunks, cur)
+	}
+	return chunks
+}
+
+// DisambiguateChunks 分块并发消歧
+// 每个分块完成 (成功或失败) 后回调一次 onChunk，回调在调用方的 goroutine 之外串行执行；
+// 某块失败不影响其他块。返回时所有回调均已执行完毕。
+func DisambiguateChunks(ctx context.Context, p Provider, candidates []Candidate, opts ChunkOptions, onChunk func(ChunkResult)) {
+	chunks := ChunkCandidates(candidates, opts.ChunkSize)
+	runChunks(ctx, len(chunks), opts.concurrency(), func(i int) ChunkResult {
+		res := ChunkResult{Index: i, Total: len(chunks), Words: wordIDs(chunks[i])}
+		res.Result, res.PromptVersion, res.Err = BatchDisambiguate(ctx, p, chunks[i])
+		return res
+	}, func(i int, err error) ChunkResult {
+		return ChunkResult{Index: i, Total: len(chunks), Words: wordIDs(chunks[i]), Err: err}
+	}, onChunk)
+}
+
+func wordIDs(chunk []Candidate) []string {
+	words := make([]string, len(chunk))
+	for k, c := range chunk {
+		words[k] = c.WordID
+	}
+	return words
+}
+
+// runChunks 以 concurrency 的并发度执行 n 个分块
+// ctx 取消后尚未开始的分块不再执行，改用 cancelled 生成失败结果；
+// 结果按完成顺序串行交给 onChunk，返回时所有回调均已执行完毕。
+func runChunks[T any](ctx context.Context, n, concurrency int, run func(i int) T, cancelled func(i int, err error) T, onChunk func(T)) {
+	results := make(chan T)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			var res T
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+				res = run(i)
+			case <-ctx.Done():
+				res = cancelled(i, ctx.Err())
+			}
+			results <- res
+		}(i)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for res := range results {
+		onChunk(res)
+	}
+}
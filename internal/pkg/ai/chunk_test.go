@@ -0,0 +1,56 @@
+package ai
+
+import (
+	"context"
+	"testing"
+)
+
+func TestChunkCandidates(t *testing.T) {
+	// 句子 0: 2 个候选，句子 1: 3 个，句子 2: 5 个
+	var cs []Candidate
+	for s, n := range []int{2, 3, 5} {
+		for k := 0; k < n; k++ {
+			cs = append(cs, Candidate{WordID: string(rune('a' + len(cs))), Sentence: s})
+		}
+	}
+
+	chunks := ChunkCandidates(cs, 4)
+	var sizes []int
+	for _, c := range chunks {
+		sizes = append(sizes, len(c))
+	}
+	// 句子 0 与句子 1 合起来超过 4 → 分开；句子 2 超过 4 → 拆成 4 + 1
+	want := []int{2, 3, 4, 1}
+	if len(sizes) != len(want) {
+		t.Fatalf("sizes = %v, want %v", sizes, want)
+	}
+	for i := range want {
+		if sizes[i] != want[i] {
+			t.Fatalf("sizes = %v, want %v", sizes, want)
+		}
+	}
+
+	total := 0
+	for _, c := range chunks {
+		total += len(c)
+	}
+	if total != len(cs) {
+		t.Fatalf("lost candidates: %d of %d", total, len(cs))
+	}
+}
+
+func TestBatchDisambiguateFiltersChoices(t *testing.T) {
+	cs := []Candidate{
+		{WordID: "token_0", WordText: "勉強", Options: []string{"学习", "便宜，让价"}},
+		{WordID: "token_2", WordText: "生", Options: []string{"生的", "生命", "学生"}},
+	}
+	// 越界的下标与不在请求中的 WordID 都要丢弃
+	fake := NewFake(FakeReply{Content: `{"token_0": 2, "token_2": 1, "token_9": 0, "token_x": -1}`})
+	got, _, err := BatchDisambiguate(context.Background(), fake, cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got["token_2"] != 1 {
+		t.Errorf("choices = %v, want {token_2: 1}", got)
+	}
+}
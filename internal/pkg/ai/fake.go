@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// FakeReply 脚本中的一步：返回 Content，或者返回 Err
+type FakeReply struct {
+	Content string
+	Usage   Usage
+	Err     error
+}
+
+// FakeProvider 按脚本应答的本地 Provider，用于测试与离线开发
+// 优先使用 Handler (适合并发请求、需要根据提示词作答的场景)，否则按顺序消费 Replies。
+type FakeProvider struct {
+	Handler func(req ChatRequest) FakeReply
+
+	mu       sync.Mutex
+	replies  []FakeReply
+	requests []ChatRequest
+}
+
+// ErrFakeExhausted 脚本中的应答已用完
+var ErrFakeExhausted = errors.New("fake provider: no scripted reply left")
+
+// NewFake 创建按顺序应答的 FakeProvider
+func NewFake(replies ...FakeReply) *FakeProvider {
+	return &FakeProvider{replies: replies}
+}
+
+func (f *FakeProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	var reply FakeReply
+	switch {
+	case f.Handler != nil:
+		f.mu.Unlock()
+		reply = f.Handler(req)
+	case len(f.replies) > 0:
+		reply = f.replies[0]
+		f.replies = f.replies[1:]
+		f.mu.Unlock()
+	default:
+		f.mu.Unlock()
+		return nil, ErrFakeExhausted
+	}
+
+	if reply.Err != nil {
+		return nil, reply.Err
+	}
+	return &ChatResponse{Content: reply.Content, Model: "fake", Usage: reply.Usage}, nil
+}
+
+// Requests 返回已收到的请求 (按到达顺序)
+func (f *FakeProvider) Requests() []ChatRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ChatRequest(nil), f.requests...)
+}
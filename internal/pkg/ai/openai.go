@@ -0,0 +1,134 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAIConfig OpenAI 兼容接口 (DeepSeek 等) 的配置
+type OpenAIConfig struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+	Timeout time.Duration // 单次请求超时，默认 120 秒
+}
+
+// OpenAIProvider 调用 OpenAI 兼容的 /chat/completions 接口
+type OpenAIProvider struct {
+	cfg    OpenAIConfig
+	client *http.Client
+}
+
+// NewOpenAI 创建 OpenAI 兼容的 Provider (复用同一个 http.Client)
+func NewOpenAI(cfg OpenAIConfig) *OpenAIProvider {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 120 * time.Second
+	}
+	return &OpenAIProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+type chatCompletionReq struct {
+	Model          string          `json:"model"`
+	Messages       []Message       `json:"messages"`
+	Stream         bool            `json:"stream"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type responseFormat struct {
+	Type string `json:"type"`
+}
+
+type chatCompletionResp struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message Message `json:"message"`
+	} `json:"choices"`
+	Usage Usage `json:"usage"`
+}
+
+// StatusError 接口返回非 2xx 状态码
+type StatusError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // 服务端要求的等待时间 (Retry-After 头)，0 表示未提供
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("AI 接口返回 %d: %s", e.StatusCode, e.Body)
+}
+
+func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	body := chatCompletionReq{
+		Model:    p.cfg.Model,
+		Messages: req.Messages,
+		Stream:   false,
+	}
+	if req.JSONMode {
+		body.ResponseFormat = &responseFormat{Type: "json_object"}
+	}
+
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	// ✅ 使用 NewRequestWithContext 支持取消
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(p.cfg.BaseURL, "/")+"/chat/completions", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(raw)),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	var apiResp chatCompletionResp
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, &MalformedError{Err: err}
+	}
+	if len(apiResp.Choices) == 0 {
+		return nil, &MalformedError{Err: fmt.Errorf("empty choices")}
+	}
+
+	return &ChatResponse{
+		Content: apiResp.Choices[0].Message.Content,
+		Model:   apiResp.Model,
+		Usage:   apiResp.Usage,
+	}, nil
+}
+
+// parseRetryAfter 解析 Retry-After 头 (秒数或 HTTP 日期)
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
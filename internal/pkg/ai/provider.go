@@ -0,0 +1,53 @@
+package ai
+
+import (
+	"context"
+	"errors"
+)
+
+// Message 对话消息
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRequest 一次对话补全请求
+type ChatRequest struct {
+	Feature       string // 发起调用的功能 (用于用量统计)，见 Feature* 常量
+	PromptVersion string // 所用提示词模板的版本
+	Messages      []Message
+	JSONMode      bool // 要求模型只输出 JSON 对象
+}
+
+// 调用 AI 的功能
+const (
+	FeatureDisambiguate = "disambiguate"
+	FeatureGenerateWord = "generate_word"
+	FeatureTranslate    = "translate"
+)
+
+// Usage token 用量统计
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChatResponse 对话补全结果
+type ChatResponse struct {
+	Content string
+	Model   string
+	Usage   Usage
+}
+
+// Provider 大模型服务
+// 实现需支持通过 ctx 取消，并可被多个 goroutine 并发调用
+type Provider interface {
+	Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error)
+}
+
+// ErrNotConfigured 未配置 AI 服务
+var ErrNotConfigured = errors.New("AI 服务未配置 (DEEPSEEK_API_KEY 为空)")
+
+// ErrQuotaExceeded 发起调用的用户当日 token 用量已达配额 (由计量层返回，请求没有发出，不代表 AI 服务异常)
+var ErrQuotaExceeded = errors.New("今日 AI 用量已达上限")
@@ -0,0 +1,296 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrorClass 错误分类
+type ErrorClass int
+
+const (
+	ErrorFatal     ErrorClass = iota // 重试也不会成功 (参数错误、鉴权失败、调用方取消)
+	ErrorRetryable                   // 限流、服务端错误、网络抖动、返回内容损坏
+)
+
+// ErrCircuitOpen 熔断器打开，请求被直接拒绝
+var ErrCircuitOpen = errors.New("AI 服务暂时不可用 (熔断中)")
+
+// MalformedError 返回内容无法解析
+type MalformedError struct {
+	Err error
+}
+
+func (e *MalformedError) Error() string { return "AI 返回内容格式错误: " + e.Err.Error() }
+func (e *MalformedError) Unwrap() error { return e.Err }
+
+// Classify 判断错误是否值得重试
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return ErrorFatal
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrNotConfigured) || errors.Is(err, ErrQuotaExceeded) {
+		return ErrorFatal
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusTooManyRequests,
+			statusErr.StatusCode == http.StatusRequestTimeout,
+			statusErr.StatusCode >= 500:
+			return ErrorRetryable
+		default:
+			return ErrorFatal
+		}
+	}
+
+	var malformed *MalformedError
+	if errors.As(err, &malformed) {
+		return ErrorRetryable
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorRetryable
+	}
+	return ErrorFatal
+}
+
+// ResilienceConfig 重试与熔断参数，零值字段使用默认值
+type ResilienceConfig struct {
+	MaxAttempts      int           // 单次调用最多尝试次数 (默认 3)
+	BaseDelay        time.Duration // 首次退避上限 (默认 500ms)，之后按 2 倍增长并做全抖动
+	MaxDelay         time.Duration // 单次退避上限 (默认 10s)
+	MaxRetryAfter    time.Duration // 服务端 Retry-After 的上限 (默认 30s)
+	FailureThreshold int           // 连续失败多少次后熔断 (默认 5)
+	OpenDuration     time.Duration // 熔断持续时间，之后放行一个探测请求 (默认 30s)
+}
+
+func (c ResilienceConfig) withDefaults() ResilienceConfig {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 3
+	}
+	if c.BaseDelay <= 0 {
+		c.BaseDelay = 500 * time.Millisecond
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = 10 * time.Second
+	}
+	if c.MaxRetryAfter <= 0 {
+		c.MaxRetryAfter = 30 * time.Second
+	}
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = 5
+	}
+	if c.OpenDuration <= 0 {
+		c.OpenDuration = 30 * time.Second
+	}
+	return c
+}
+
+// 熔断器状态
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// Health 客户端层的运行状态 (供健康检查接口展示)
+type Health struct {
+	Circuit             string     `json:"circuit"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	Requests            int64      `json:"requests"`
+	Failures            int64      `json:"failures"`
+	Retries             int64      `json:"retries"`
+	Rejected            int64      `json:"rejected"`
+}
+
+// ResilientProvider 为任意 Provider 增加重试、退避与熔断
+type ResilientProvider struct {
+	next Provider
+	cfg  ResilienceConfig
+
+	// 可在测试中替换
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu       sync.Mutex
+	circuit  string
+	failures int
+	openedAt time.Time
+	probing  bool
+	stats    Health
+}
+
+// NewResilient 包装一个 Provider
+func NewResilient(next Provider, cfg ResilienceConfig) *ResilientProvider {
+	return &ResilientProvider{
+		next:    next,
+		cfg:     cfg.withDefaults(),
+		now:     time.Now,
+		sleep:   sleepCtx,
+		circuit: CircuitClosed,
+	}
+}
+
+func (p *ResilientProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	var lastErr error
+	for attempt := 0; attempt < p.cfg.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := p.sleep(ctx, p.backoff(attempt, lastErr)); err != nil {
+				return nil, err
+			}
+			p.count(func(h *Health) { h.Retries++ })
+		}
+
+		if !p.allow() {
+			p.count(func(h *Health) { h.Rejected++ })
+			return nil, ErrCircuitOpen
+		}
+
+		resp, err := p.next.Chat(ctx, req)
+		if err == nil && req.JSONMode && !json.Valid([]byte(cleanJSON(resp.Content))) {
+			err = &MalformedError{Err: fmt.Errorf("not a JSON object")}
+		}
+		if err == nil {
+			p.record(nil)
+			return resp, nil
+		}
+
+		// 调用方主动取消、用户配额用完都与服务是否可用无关，不计入失败次数与熔断
+		if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrQuotaExceeded) {
+			p.release()
+			return nil, err
+		}
+
+		lastErr = err
+		p.record(err)
+		if Classify(err) == ErrorFatal {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("重试 %d 次后仍失败: %w", p.cfg.MaxAttempts, lastErr)
+}
+
+// Health 返回当前状态快照
+func (p *ResilientProvider) Health() Health {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h := p.stats
+	h.Circuit = p.state()
+	h.ConsecutiveFailures = p.failures
+	if p.circuit != CircuitClosed {
+		openedAt := p.openedAt
+		h.OpenedAt = &openedAt
+	}
+	return h
+}
+
+// backoff 第 attempt 次重试前的等待时间：指数增长 + 全抖动，服务端给出 Retry-After 时以其为准
+func (p *ResilientProvider) backoff(attempt int, lastErr error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(lastErr, &statusErr) && statusErr.RetryAfter > 0 {
+		return min(statusErr.RetryAfter, p.cfg.MaxRetryAfter)
+	}
+
+	ceiling := p.cfg.BaseDelay << (attempt - 1)
+	if ceiling > p.cfg.MaxDelay || ceiling <= 0 {
+		ceiling = p.cfg.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// state 计算当前状态 (调用方需持有 mu)：熔断时间到期后进入半开
+func (p *ResilientProvider) state() string {
+	if p.circuit == CircuitOpen && p.now().Sub(p.openedAt) >= p.cfg.OpenDuration {
+		return CircuitHalfOpen
+	}
+	return p.circuit
+}
+
+// allow 是否放行请求；半开状态只放行一个探测请求
+func (p *ResilientProvider) allow() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stats.Requests++
+	switch p.state() {
+	case CircuitClosed:
+		return true
+	case CircuitHalfOpen:
+		if p.probing {
+			return false
+		}
+		p.circuit = CircuitHalfOpen
+		p.probing = true
+		return true
+	default:
+		return false
+	}
+}
+
+// release 放弃本次请求 (不影响熔断判断)
+func (p *ResilientProvider) release() {
+	p.mu.Lock()
+	p.probing = false
+	p.mu.Unlock()
+}
+
+// record 记录一次请求结果并更新熔断器
+func (p *ResilientProvider) record(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.probing = false
+	if err == nil {
+		p.failures = 0
+		p.circuit = CircuitClosed
+		return
+	}
+
+	now := p.now()
+	p.stats.Failures++
+	p.stats.LastError = err.Error()
+	p.stats.LastErrorAt = &now
+
+	// 参数类错误说明请求本身有问题，不代表服务不可用
+	if Classify(err) == ErrorFatal {
+		return
+	}
+
+	p.failures++
+	if p.circuit == CircuitHalfOpen || p.failures >= p.cfg.FailureThreshold {
+		p.circuit = CircuitOpen
+		p.openedAt = now
+	}
+}
+
+func (p *ResilientProvider) count(fn func(h *Health)) {
+	p.mu.Lock()
+	fn(&p.stats)
+	p.mu.Unlock()
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
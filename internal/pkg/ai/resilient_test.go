@@ -0,0 +1,122 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// newTestResilient 使用假时钟，退避时只推进时钟不真正等待
+func newTestResilient(next Provider, cfg ResilienceConfig) (*ResilientProvider, *time.Time, *[]time.Duration) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var waits []time.Duration
+	p := NewResilient(next, cfg)
+	p.now = func() time.Time { return now }
+	p.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		now = now.Add(d)
+		return nil
+	}
+	return p, &now, &waits
+}
+
+func TestResilientRetry(t *testing.T) {
+	fake := NewFake(
+		FakeReply{Err: &StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 2 * time.Second}},
+		FakeReply{Content: "not json"},
+		FakeReply{Content: `{"ok": 1}`},
+	)
+	p, _, waits := newTestResilient(fake, ResilienceConfig{MaxAttempts: 3})
+
+	resp, err := p.Chat(context.Background(), ChatRequest{JSONMode: true})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Content != `{"ok": 1}` {
+		t.Errorf("content = %q", resp.Content)
+	}
+	if len(*waits) != 2 || (*waits)[0] != 2*time.Second {
+		t.Errorf("waits = %v, want Retry-After honored first", *waits)
+	}
+	if h := p.Health(); h.Retries != 2 || h.Circuit != CircuitClosed || h.ConsecutiveFailures != 0 {
+		t.Errorf("health = %+v", h)
+	}
+}
+
+func TestResilientFatalNotRetried(t *testing.T) {
+	fake := NewFake(FakeReply{Err: &StatusError{StatusCode: http.StatusBadRequest}})
+	p, _, _ := newTestResilient(fake, ResilienceConfig{})
+
+	_, err := p.Chat(context.Background(), ChatRequest{})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || len(fake.Requests()) != 1 {
+		t.Fatalf("err = %v, requests = %d", err, len(fake.Requests()))
+	}
+}
+
+func TestResilientIgnoresCallerErrors(t *testing.T) {
+	// 某个用户的配额用完、调用方取消，都不应让 /health 显示服务故障或触发熔断
+	quota := fmt.Errorf("用户 u1: %w", ErrQuotaExceeded)
+	var replies []FakeReply
+	for i := 0; i < 10; i++ {
+		replies = append(replies, FakeReply{Err: quota}, FakeReply{Err: context.Canceled})
+	}
+	fake := NewFake(replies...)
+	p, _, _ := newTestResilient(fake, ResilienceConfig{FailureThreshold: 2})
+
+	for i := 0; i < len(replies); i++ {
+		if _, err := p.Chat(context.Background(), ChatRequest{}); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if len(fake.Requests()) != len(replies) {
+		t.Errorf("requests = %d, want %d (no retries)", len(fake.Requests()), len(replies))
+	}
+	if h := p.Health(); h.Failures != 0 || h.LastError != "" || h.Circuit != CircuitClosed || h.ConsecutiveFailures != 0 {
+		t.Errorf("health = %+v", h)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	serverErr := FakeReply{Err: &StatusError{StatusCode: http.StatusBadGateway}}
+	fake := NewFake(serverErr, serverErr, FakeReply{Content: "ok"})
+	p, now, _ := newTestResilient(fake, ResilienceConfig{MaxAttempts: 1, FailureThreshold: 2, OpenDuration: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		p.Chat(context.Background(), ChatRequest{})
+	}
+	if h := p.Health(); h.Circuit != CircuitOpen {
+		t.Fatalf("circuit = %s, want open", h.Circuit)
+	}
+	if _, err := p.Chat(context.Background(), ChatRequest{}); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+
+	// 冷却期结束后放行探测请求，成功则恢复
+	*now = now.Add(time.Minute)
+	if h := p.Health(); h.Circuit != CircuitHalfOpen {
+		t.Fatalf("circuit = %s, want half_open", h.Circuit)
+	}
+	if _, err := p.Chat(context.Background(), ChatRequest{}); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if h := p.Health(); h.Circuit != CircuitClosed || h.Rejected != 1 {
+		t.Errorf("health = %+v", h)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if d := parseRetryAfter("3", now); d != 3*time.Second {
+		t.Errorf("seconds: %v", d)
+	}
+	if d := parseRetryAfter(now.Add(5*time.Second).Format(http.TimeFormat), now); d != 5*time.Second {
+		t.Errorf("date: %v", d)
+	}
+	if d := parseRetryAfter("soon", now); d != 0 {
+		t.Errorf("invalid: %v", d)
+	}
+}
@@ -0,0 +1,181 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+
+	"dongwai_backend/internal/pkg/prompt"
+)
+
+// 提示词模板名称 (见 internal/pkg/prompt/templates)
+const (
+	PromptDisambiguate = "disambiguate"
+	PromptGenerateWord = "generate_word"
+	PromptTranslate    = "translate"
+)
+
+// --- 功能一：文章单词消歧 ---
+
+// Candidate 表示一个待消歧的单词及其选项
+type Candidate struct {
+	WordID   string
+	WordText string
+	Context  string   // 单词所在的句子
+	Options  []string // 候选释义列表
+	Sentence int      // 所在句子的下标 (分块时按句子边界切分)
+}
+
+// BatchDisambiguate 批量消歧 (单次请求，长文章请使用 DisambiguateChunks)
+// 返回 WordID -> 释义下标，以及所用的提示词版本；只保留请求中的 WordID 与候选范围内的下标
+func BatchDisambiguate(ctx context.Context, p Provider, candidates []Candidate) (map[string]int, string, error) {
+	if p == nil {
+		return nil, "", ErrNotConfigured
+	}
+	if len(candidates) == 0 {
+		return map[string]int{}, "", nil
+	}
+
+	rendered, err := prompt.Default.Render(PromptDisambiguate, struct{ Candidates []Candidate }{candidates})
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := p.Chat(ctx, ChatRequest{
+		Feature:       FeatureDisambiguate,
+		PromptVersion: rendered.Version,
+		Messages: []Message{
+			{Role: "system", Content: rendered.System},
+			{Role: "user", Content: rendered.User},
+		},
+		JSONMode: true,
+	})
+	if err != nil {
+		log.Printf("AI disambiguate error: %v", err)
+		return nil, rendered.Version, err
+	}
+
+	var raw map[string]int
+	content := cleanJSON(resp.Content)
+	if err := json.Unmarshal([]byte(content), &raw); err != nil {
+		log.Printf("AI JSON parse error: %v | Content: %s", err, content)
+		return nil, rendered.Version, &MalformedError{Err: err}
+	}
+
+	options := make(map[string]int, len(candidates))
+	for _, c := range candidates {
+		options[c.WordID] = len(c.Options)
+	}
+	result := make(map[string]int, len(raw))
+	for wordID, idx := range raw {
+		if n, ok := options[wordID]; ok && idx >= 0 && idx < n {
+			result[wordID] = idx
+		}
+	}
+	return result, rendered.Version, nil
+}
+
+// --- 功能二：单词智能补全 (含 Furigana) ---
+
+// GeneratedWordData AI 生成的单词结构
+type GeneratedWordData struct {
+	Kanji   string           `json:"kanji"`
+	IsMulti bool             `json:"is_multi"`
+	Senses  []GeneratedSense `json:"senses"`
+
+	PromptVersion string `json:"-"` // 生成该结果的提示词版本
+}
+
+// GeneratedSense AI 生成的释义
+type GeneratedSense struct {
+	Level    string             `json:"level"`    // N1-N5
+	Reading  string             `json:"reading"`  // 平假名 (片假名外来语为片假名)
+	Furigana [][]string         `json:"furigana"` // ✅ 单词本身的振假名拆解
+	Pitch    string             `json:"pitch"`    // 音调，如 ⓪ ①
+	Pos      string             `json:"pos"`      // 词性
+	Def      string             `json:"def"`      // 释义
+	Examples []GeneratedExample `json:"examples"`
+}
+
+// GeneratedExample AI 生成的例句
+type GeneratedExample struct {
+	Kanji    string     `json:"kanji"`    // 例句原文
+	Furigana [][]string `json:"furigana"` // ✅ 例句的振假名拆解
+	Def      string     `json:"def"`      // 例句翻译
+}
+
+// GenerateWordInfo 调用 AI 自动补全单词信息
+// 结果会先做格式修复与校验；有问题时带着具体的校验错误重新请求一次，仍有问题则连同警告一起返回
+func GenerateWordInfo(ctx context.Context, p Provider, word string) (*GeneratedWordData, []ValidationWarning, error) {
+	if p == nil {
+		return nil, nil, ErrNotConfigured
+	}
+
+	rendered, err := prompt.Default.Render(PromptGenerateWord, struct{ Word string }{word})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	messages := []Message{
+		{Role: "system", Content: rendered.System},
+		{Role: "user", Content: rendered.User},
+	}
+	result, content, err := generateOnce(ctx, p, rendered.Version, messages)
+	if err != nil {
+		return nil, nil, err
+	}
+	warnings := ValidateWordData(word, result)
+	if len(warnings) == 0 {
+		return result, nil, nil
+	}
+
+	// 带着校验错误重新请求一次
+	repair, err := prompt.Default.RenderBlock(PromptGenerateWord, rendered.Version, "repair", struct {
+		Word     string
+		Warnings []ValidationWarning
+	}{word, warnings})
+	if err != nil {
+		return result, warnings, nil
+	}
+	messages = append(messages,
+		Message{Role: "assistant", Content: content},
+		Message{Role: "user", Content: repair},
+	)
+	retried, _, err := generateOnce(ctx, p, rendered.Version, messages)
+	if err != nil {
+		log.Printf("AI 修正请求失败，返回首次结果: %v", err)
+		return result, warnings, nil
+	}
+	if retryWarnings := ValidateWordData(word, retried); len(retryWarnings) <= len(warnings) {
+		return retried, retryWarnings, nil
+	}
+	return result, warnings, nil
+}
+
+// generateOnce 请求一次并解析、修复结果，同时返回原始内容 (供重新请求时作为上下文)
+func generateOnce(ctx context.Context, p Provider, version string, messages []Message) (*GeneratedWordData, string, error) {
+	resp, err := p.Chat(ctx, ChatRequest{Feature: FeatureGenerateWord, PromptVersion: version, Messages: messages, JSONMode: true})
+	if err != nil {
+		return nil, "", err
+	}
+
+	content := cleanJSON(resp.Content)
+
+	var result GeneratedWordData
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		log.Printf("AI JSON Parse Error: %v \nContent: %s", err, content)
+		return nil, "", &MalformedError{Err: err}
+	}
+
+	RepairWordData(&result)
+	result.PromptVersion = version
+	return &result, content, nil
+}
+
+func cleanJSON(content string) string {
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+	return strings.TrimSpace(content)
+}
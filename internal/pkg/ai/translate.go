@@ -0,0 +1,114 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+
+	"dongwai_backend/internal/pkg/prompt"
+)
+
+// --- 功能三：逐句翻译与语法说明 ---
+
+// DefaultTranslateBatch 每个翻译请求最多包含的句子数
+const DefaultTranslateBatch = 8
+
+// SentenceInput 一个待翻译的句子
+type SentenceInput struct {
+	Index int      // 句子下标 (对应分析结果中的 Sentence.Index)
+	Text  string   // 句子原文
+	Hints []string // 句中已确定的词义，如 "勉強（べんきょう）: 学习"
+}
+
+// SentenceGloss 一个句子的翻译与语法说明
+type SentenceGloss struct {
+	Translation string `json:"translation"`
+	Grammar     string `json:"grammar"`
+}
+
+// TranslateResult 单个批次的翻译结果
+type TranslateResult struct {
+	Index     int                   // 批次下标 (从 0 开始)
+	Total     int                   // 批次总数
+	Sentences []int                 // 本批包含的句子下标
+	Result    map[int]SentenceGloss // 成功时的 句子下标 -> 翻译
+	Err       error                 // 失败原因，非空时 Result 为 nil
+
+	PromptVersion string
+}
+
+// TranslateSentences 翻译一批句子 (单次请求)，返回 句子下标 -> 翻译，以及所用的提示词版本
+// 回复中不属于本批的下标会被忽略
+func TranslateSentences(ctx context.Context, p Provider, sentences []SentenceInput) (map[int]SentenceGloss, string, error) {
+	if p == nil {
+		return nil, "", ErrNotConfigured
+	}
+	if len(sentences) == 0 {
+		return map[int]SentenceGloss{}, "", nil
+	}
+
+	rendered, err := prompt.Default.Render(PromptTranslate, struct{ Sentences []SentenceInput }{sentences})
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := p.Chat(ctx, ChatRequest{
+		Feature:       FeatureTranslate,
+		PromptVersion: rendered.Version,
+		Messages: []Message{
+			{Role: "system", Content: rendered.System},
+			{Role: "user", Content: rendered.User},
+		},
+		JSONMode: true,
+	})
+	if err != nil {
+		log.Printf("AI translate error: %v", err)
+		return nil, rendered.Version, err
+	}
+
+	var raw map[string]SentenceGloss
+	content := cleanJSON(resp.Content)
+	if err := json.Unmarshal([]byte(content), &raw); err != nil {
+		log.Printf("AI JSON parse error: %v | Content: %s", err, content)
+		return nil, rendered.Version, &MalformedError{Err: err}
+	}
+
+	wanted := make(map[int]bool, len(sentences))
+	for _, s := range sentences {
+		wanted[s.Index] = true
+	}
+	result := make(map[int]SentenceGloss, len(raw))
+	for key, g := range raw {
+		idx, err := strconv.Atoi(key)
+		if err != nil || !wanted[idx] || g.Translation == "" {
+			continue
+		}
+		result[idx] = g
+	}
+	return result, rendered.Version, nil
+}
+
+// TranslateChunks 按 DefaultTranslateBatch 分批并发翻译，回调约定与 DisambiguateChunks 相同
+func TranslateChunks(ctx context.Context, p Provider, sentences []SentenceInput, opts ChunkOptions, onChunk func(TranslateResult)) {
+	var batches [][]SentenceInput
+	for start := 0; start < len(sentences); start += DefaultTranslateBatch {
+		end := min(start+DefaultTranslateBatch, len(sentences))
+		batches = append(batches, sentences[start:end])
+	}
+	indexes := func(i int) []int {
+		out := make([]int, len(batches[i]))
+		for k, s := range batches[i] {
+			out[k] = s.Index
+		}
+		return out
+	}
+
+	runChunks(ctx, len(batches), opts.concurrency(), func(i int) TranslateResult {
+		res := TranslateResult{Index: i, Total: len(batches), Sentences: indexes(i)}
+		res.Result, res.PromptVersion, res.Err = TranslateSentences(ctx, p, batches[i])
+		return res
+	}, func(i int, err error) TranslateResult {
+		return TranslateResult{Index: i, Total: len(batches), Sentences: indexes(i), Err: err}
+	}, onChunk)
+}
@@ -0,0 +1,44 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTranslateChunks(t *testing.T) {
+	var inputs []SentenceInput
+	for i := 0; i < DefaultTranslateBatch+2; i++ {
+		inputs = append(inputs, SentenceInput{Index: i, Text: "猫が好きです。", Hints: []string{"猫（ねこ）: 猫"}})
+	}
+
+	fake := &FakeProvider{Handler: func(req ChatRequest) FakeReply {
+		if !strings.Contains(req.Messages[1].Content, "猫（ねこ）: 猫") {
+			return FakeReply{Content: "{}"}
+		}
+		// 第二批只有两个句子 (8, 9)，额外的下标 0 必须被忽略
+		return FakeReply{Content: `{"0": {"translation": "我喜欢猫。", "grammar": ""}, "9": {"translation": "我喜欢猫。"}}`}
+	}}
+
+	got := map[int]bool{}
+	batches := 0
+	TranslateChunks(context.Background(), fake, inputs, ChunkOptions{Concurrency: 1}, func(res TranslateResult) {
+		batches++
+		if res.Err != nil {
+			t.Fatalf("batch %d: %v", res.Index, res.Err)
+		}
+		for idx := range res.Result {
+			if got[idx] {
+				t.Errorf("sentence %d translated twice", idx)
+			}
+			got[idx] = true
+		}
+	})
+
+	if batches != 2 {
+		t.Errorf("batches = %d, want 2", batches)
+	}
+	if len(got) != 2 || !got[0] || !got[9] {
+		t.Errorf("translated = %v, want {0, 9}", got)
+	}
+}
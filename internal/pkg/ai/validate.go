@@ -0,0 +1,250 @@
+package ai
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"dongwai_backend/internal/pkg/textnorm"
+)
+
+// ValidationWarning 一条字段级的校验警告
+type ValidationWarning struct {
+	Field   string `json:"field"`   // 字段路径，如 senses[0].examples[1].furigana
+	Code    string `json:"code"`    // 机器可读的错误码
+	Message string `json:"message"` // 给人 (和模型) 看的说明
+}
+
+// 校验错误码
+const (
+	WarnKanjiMismatch   = "kanji_mismatch"
+	WarnNoSenses        = "no_senses"
+	WarnInvalidLevel    = "invalid_level"
+	WarnInvalidPitch    = "invalid_pitch"
+	WarnInvalidReading  = "invalid_reading"
+	WarnFuriganaFormat  = "furigana_format"
+	WarnFuriganaText    = "furigana_text_mismatch"
+	WarnFuriganaReading = "furigana_reading_mismatch"
+	WarnExampleCount    = "example_count"
+	WarnEmptyField      = "empty"
+)
+
+// 每个释义的例句数量范围
+const (
+	MinExamples = 1
+	MaxExamples = 2
+)
+
+var validLevels = map[string]bool{"N1": true, "N2": true, "N3": true, "N4": true, "N5": true}
+
+// ValidateWordData 校验 AI 生成的单词条目，word 为请求生成的单词
+func ValidateWordData(word string, d *GeneratedWordData) []ValidationWarning {
+	var ws []ValidationWarning
+	add := func(field, code, format string, args ...any) {
+		ws = append(ws, ValidationWarning{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if textnorm.String(stripAffixMark(d.Kanji)) != textnorm.String(stripAffixMark(word)) {
+		add("kanji", WarnKanjiMismatch, "kanji 应为 %q，实际为 %q", word, d.Kanji)
+	}
+	if len(d.Senses) == 0 {
+		add("senses", WarnNoSenses, "至少需要一个释义")
+	}
+
+	for i, s := range d.Senses {
+		prefix := fmt.Sprintf("senses[%d]", i)
+
+		if !validLevels[s.Level] {
+			add(prefix+".level", WarnInvalidLevel, "level 必须是 N1-N5 之一，实际为 %q", s.Level)
+		}
+		if !isCircledPitch(s.Pitch) {
+			add(prefix+".pitch", WarnInvalidPitch, "pitch 必须是带圈数字 (如 ⓪ ①)，实际为 %q", s.Pitch)
+		}
+		if s.Reading == "" || !isKanaText(s.Reading) {
+			add(prefix+".reading", WarnInvalidReading, "reading 必须是假名，实际为 %q", s.Reading)
+		}
+		if strings.TrimSpace(s.Def) == "" {
+			add(prefix+".def", WarnEmptyField, "def 不能为空")
+		}
+		checkFurigana(prefix+".furigana", s.Furigana, d.Kanji, s.Reading, add)
+
+		if n := len(s.Examples); n < MinExamples || n > MaxExamples {
+			add(prefix+".examples", WarnExampleCount, "每个释义需要 %d-%d 个例句，实际为 %d 个", MinExamples, MaxExamples, n)
+		}
+		for j, ex := range s.Examples {
+			exPrefix := fmt.Sprintf("%s.examples[%d]", prefix, j)
+			if strings.TrimSpace(ex.Kanji) == "" {
+				add(exPrefix+".kanji", WarnEmptyField, "例句原文不能为空")
+			}
+			if strings.TrimSpace(ex.Def) == "" {
+				add(exPrefix+".def", WarnEmptyField, "例句翻译不能为空")
+			}
+			checkFurigana(exPrefix+".furigana", ex.Furigana, ex.Kanji, "", add)
+		}
+	}
+	return ws
+}
+
+// checkFurigana 校验振假名：每项为 [文本, 读音]，文本拼接后等于 text，
+// 含汉字的部分必须有读音；reading 非空时读音 (假名部分取文本本身) 拼接后需等于 reading
+func checkFurigana(field string, pairs [][]string, text, reading string, add func(field, code, format string, args ...any)) {
+	if len(pairs) == 0 {
+		add(field, WarnFuriganaFormat, "furigana 不能为空，格式为 [[文本, 读音], ...]")
+		return
+	}
+
+	var gotText, gotReading strings.Builder
+	for k, p := range pairs {
+		if len(p) != 2 {
+			add(fmt.Sprintf("%s[%d]", field, k), WarnFuriganaFormat, "每一项必须是 [文本, 读音] 两个元素")
+			return
+		}
+		gotText.WriteString(p[0])
+		switch {
+		case p[1] != "":
+			gotReading.WriteString(p[1])
+		case hasKanji(p[0]):
+			add(fmt.Sprintf("%s[%d]", field, k), WarnFuriganaFormat, "%q 含汉字，读音不能为空", p[0])
+		default:
+			gotReading.WriteString(p[0])
+		}
+	}
+
+	if textnorm.String(gotText.String()) != textnorm.String(stripAffixMark(text)) {
+		add(field, WarnFuriganaText, "furigana 文本拼接为 %q，与 %q 不一致", gotText.String(), text)
+	}
+	if reading != "" && toHiragana(gotReading.String()) != toHiragana(reading) {
+		add(field, WarnFuriganaReading, "furigana 读音拼接为 %q，与 reading %q 不一致", gotReading.String(), reading)
+	}
+}
+
+// RepairWordData 修复可以确定的格式问题 (等级大小写、阿拉伯数字音调、片假名读音)
+// 片假名外来语 (如 コーヒー) 的读音保持片假名，其余单词的片假名读音转为平假名
+func RepairWordData(d *GeneratedWordData) {
+	d.Kanji = strings.TrimSpace(d.Kanji)
+	d.IsMulti = len(d.Senses) > 1
+	loanword := isKatakanaWord(stripAffixMark(d.Kanji))
+	for i := range d.Senses {
+		s := &d.Senses[i]
+		s.Level = repairLevel(s.Level)
+		s.Pitch = repairPitch(s.Pitch)
+		s.Reading = strings.TrimSpace(s.Reading)
+		if !loanword {
+			s.Reading = toHiragana(s.Reading)
+		}
+	}
+}
+
+// repairLevel "n5" / "N 5" / "JLPT N5" -> "N5"
+func repairLevel(level string) string {
+	l := strings.ToUpper(strings.Join(strings.Fields(level), ""))
+	l = strings.TrimPrefix(l, "JLPT")
+	if len(l) == 1 && l[0] >= '1' && l[0] <= '5' {
+		l = "N" + l
+	}
+	return l
+}
+
+// repairPitch "0" / "[1]" / "0,2" -> "⓪" / "①" / "⓪/②"
+func repairPitch(pitch string) string {
+	p := strings.Trim(strings.TrimSpace(pitch), "[]()（）")
+	parts := strings.FieldsFunc(p, func(r rune) bool { return strings.ContainsRune("/,、・ ", r) })
+	if len(parts) == 0 {
+		return pitch
+	}
+
+	out := make([]string, 0, len(parts))
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			if isCircledPitch(part) {
+				out = append(out, part)
+				continue
+			}
+			return pitch
+		}
+		c, ok := circled(n)
+		if !ok {
+			return pitch
+		}
+		out = append(out, string(c))
+	}
+	return strings.Join(out, "/")
+}
+
+// circled 0-20 对应的带圈数字
+func circled(n int) (rune, bool) {
+	switch {
+	case n == 0:
+		return '⓪', true
+	case n >= 1 && n <= 20:
+		return rune('①' + n - 1), true
+	default:
+		return 0, false
+	}
+}
+
+func isCircledDigit(r rune) bool {
+	return r == '⓪' || (r >= '①' && r <= '⑳')
+}
+
+// isCircledPitch 一个或多个带圈数字，多个时以 / 或 ・ 分隔
+func isCircledPitch(p string) bool {
+	if p == "" {
+		return false
+	}
+	for _, part := range strings.FieldsFunc(p, func(r rune) bool { return r == '/' || r == '・' }) {
+		runes := []rune(part)
+		if len(runes) != 1 || !isCircledDigit(runes[0]) {
+			return false
+		}
+	}
+	return !strings.HasSuffix(p, "/") && !strings.HasPrefix(p, "/")
+}
+
+func isKanaText(s string) bool {
+	for _, r := range s {
+		if !(r >= 0x3041 && r <= 0x309F) && !(r >= 0x30A0 && r <= 0x30FF) {
+			return false
+		}
+	}
+	return true
+}
+
+// isKatakanaWord 词面以片假名书写 (不含汉字与平假名，可夹杂字母，如 Tシャツ)
+func isKatakanaWord(s string) bool {
+	katakana := false
+	for _, r := range s {
+		switch {
+		case r >= 0x30A1 && r <= 0x30FA:
+			katakana = true
+		case unicode.Is(unicode.Han, r) || r == '々' || (r >= 0x3041 && r <= 0x309F):
+			return false
+		}
+	}
+	return katakana
+}
+
+func hasKanji(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) || r == '々' {
+			return true
+		}
+	}
+	return false
+}
+
+// toHiragana 片假名转平假名 (长音符保留)
+func toHiragana(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 0x30A1 && r <= 0x30F6 {
+			return r - 0x60
+		}
+		return r
+	}, s)
+}
+
+func stripAffixMark(s string) string {
+	return strings.NewReplacer("~", "", "～", "").Replace(strings.TrimSpace(s))
+}
@@ -0,0 +1,129 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func validNeko() GeneratedWordData {
+	return GeneratedWordData{
+		Kanji: "猫",
+		Senses: []GeneratedSense{{
+			Level: "N5", Reading: "ねこ", Pitch: "①", Pos: "名词", Def: "猫",
+			Furigana: [][]string{{"猫", "ねこ"}},
+			Examples: []GeneratedExample{{
+				Kanji:    "猫が好きです",
+				Furigana: [][]string{{"猫", "ねこ"}, {"が", ""}, {"好", "す"}, {"きです", ""}},
+				Def:      "我喜欢猫。",
+			}},
+		}},
+	}
+}
+
+func codes(ws []ValidationWarning) map[string]string {
+	m := map[string]string{}
+	for _, w := range ws {
+		m[w.Field] = w.Code
+	}
+	return m
+}
+
+func TestValidateWordData(t *testing.T) {
+	d := validNeko()
+	if ws := ValidateWordData("猫", &d); len(ws) != 0 {
+		t.Fatalf("valid entry has warnings: %+v", ws)
+	}
+
+	cases := []struct {
+		name   string
+		mutate func(d *GeneratedWordData)
+		field  string
+		code   string
+	}{
+		{"level", func(d *GeneratedWordData) { d.Senses[0].Level = "N6" }, "senses[0].level", WarnInvalidLevel},
+		{"pitch", func(d *GeneratedWordData) { d.Senses[0].Pitch = "1" }, "senses[0].pitch", WarnInvalidPitch},
+		{"reading", func(d *GeneratedWordData) { d.Senses[0].Reading = "neko" }, "senses[0].reading", WarnInvalidReading},
+		{"furigana text", func(d *GeneratedWordData) { d.Senses[0].Furigana = [][]string{{"犬", "ねこ"}} }, "senses[0].furigana", WarnFuriganaText},
+		{"furigana reading", func(d *GeneratedWordData) { d.Senses[0].Furigana = [][]string{{"猫", "いぬ"}} }, "senses[0].furigana", WarnFuriganaReading},
+		{"missing kanji reading", func(d *GeneratedWordData) { d.Senses[0].Furigana = [][]string{{"猫", ""}} }, "senses[0].furigana[0]", WarnFuriganaFormat},
+		{"example text", func(d *GeneratedWordData) { d.Senses[0].Examples[0].Kanji = "犬が好きです" }, "senses[0].examples[0].furigana", WarnFuriganaText},
+		{"no examples", func(d *GeneratedWordData) { d.Senses[0].Examples = nil }, "senses[0].examples", WarnExampleCount},
+		{"kanji", func(d *GeneratedWordData) { d.Kanji = "犬" }, "kanji", WarnKanjiMismatch},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := validNeko()
+			tc.mutate(&d)
+			got := codes(ValidateWordData("猫", &d))
+			if got[tc.field] != tc.code {
+				t.Errorf("warnings = %v, want %s=%s", got, tc.field, tc.code)
+			}
+		})
+	}
+}
+
+func TestRepairWordData(t *testing.T) {
+	d := validNeko()
+	d.Senses[0].Level = "n5"
+	d.Senses[0].Pitch = "[1]"
+	d.Senses[0].Reading = "ネコ"
+	RepairWordData(&d)
+	if ws := ValidateWordData("猫", &d); len(ws) != 0 {
+		t.Fatalf("repaired entry has warnings: %+v", ws)
+	}
+
+	// 片假名外来语的读音保持片假名
+	coffee := GeneratedWordData{
+		Kanji:  "コーヒー",
+		Senses: []GeneratedSense{{Level: "N5", Reading: " コーヒー ", Pitch: "3", Pos: "名词", Def: "咖啡", Furigana: [][]string{{"コーヒー", ""}}}},
+	}
+	RepairWordData(&coffee)
+	if got := coffee.Senses[0].Reading; got != "コーヒー" {
+		t.Errorf("loanword reading = %q, want コーヒー", got)
+	}
+	if ws := codes(ValidateWordData("コーヒー", &coffee)); ws["senses[0].reading"] != "" || ws["senses[0].furigana"] != "" {
+		t.Errorf("loanword warnings = %v", ws)
+	}
+	for word, want := range map[string]bool{"コーヒー": true, "Tシャツ": true, "~センター": true, "ビール瓶": false, "ねこ": false, "猫": false, "ABC": false} {
+		if got := isKatakanaWord(stripAffixMark(word)); got != want {
+			t.Errorf("isKatakanaWord(%q) = %v", word, got)
+		}
+	}
+
+	if repairPitch("0,2") != "⓪/②" {
+		t.Errorf("repairPitch = %q", repairPitch("0,2"))
+	}
+}
+
+func TestGenerateWordInfoReprompt(t *testing.T) {
+	bad := validNeko()
+	bad.Senses[0].Level = "N6"
+	good := validNeko()
+	badJSON, _ := json.Marshal(bad)
+	goodJSON, _ := json.Marshal(good)
+
+	fake := NewFake(FakeReply{Content: string(badJSON)}, FakeReply{Content: string(goodJSON)})
+	data, warnings, err := GenerateWordInfo(context.Background(), fake, "猫")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 || data.Senses[0].Level != "N5" {
+		t.Fatalf("data = %+v, warnings = %+v", data, warnings)
+	}
+
+	reqs := fake.Requests()
+	if len(reqs) != 2 || len(reqs[1].Messages) != 4 {
+		t.Fatalf("expected one re-prompt with history, got %d requests", len(reqs))
+	}
+
+	// 第二次仍然不合格时返回警告，不再重试
+	fake = NewFake(FakeReply{Content: string(badJSON)}, FakeReply{Content: string(badJSON)})
+	_, warnings, err = GenerateWordInfo(context.Background(), fake, "猫")
+	if err != nil || len(warnings) != 1 || warnings[0].Code != WarnInvalidLevel {
+		t.Fatalf("warnings = %+v, err = %v", warnings, err)
+	}
+	if len(fake.Requests()) != 2 {
+		t.Fatalf("requests = %d, want 2", len(fake.Requests()))
+	}
+}
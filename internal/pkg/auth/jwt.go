@@ -16,9 +16,10 @@ func InitJWT(secret string) {
 type AuthRole string
 
 const (
-	Admin   AuthRole = "admin"
-	Teacher AuthRole = "teacher"
-	Student AuthRole = "student"
+	Admin      AuthRole = "admin"
+	SuperAdmin AuthRole = "super_admin"
+	Teacher    AuthRole = "teacher"
+	Student    AuthRole = "student"
 )
 
 type Claims struct {
@@ -2,126 +2,277 @@ package cache
 
 import (
 	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/textnorm"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"unicode/utf8"
 
 	"gorm.io/gorm"
 )
 
 // DictCache 线程安全的词典缓存
+// 写操作在互斥锁内修改 mapping，随后重建不可变的 dictSnapshot 并原子替换；
+// 读操作 (Get / PrefixSearch / Level / MaxLen) 只访问快照，完全无锁。
 type DictCache struct {
-	sync.RWMutex
+	mu sync.Mutex
 	// 映射: 清洗后的单词 -> [ID列表]
 	// 例如: "的" -> ["id_1", "id_2"]
 	mapping map[string][]string
+	// 第二键空间: 规范化后的读音 (VocabSense.Reading) -> [ID列表]
+	// 例如: "たべる" -> ["id_食べる"]；匹配优先级低于词面
+	readings map[string][]string
+	// 映射: Vocab ID -> 已登记的读音键 (用于更新/删除时清理)
+	idReadings map[string][]string
+	// 映射: Vocab ID -> 该词条最简单的 JLPT 等级 (供分词打分)
+	levels map[string]string
+
+	snap atomic.Pointer[dictSnapshot]
+}
+
+// dictSnapshot 某一时刻词典的只读视图
+type dictSnapshot struct {
+	index   *prefixIndex
+	reading *prefixIndex
+	levels  map[string]string
 	maxLen  int
 }
 
 var GlobalDict *DictCache
 
+// NewDictCache 创建空的词典缓存
+func NewDictCache() *DictCache {
+	c := &DictCache{
+		mapping:    make(map[string][]string),
+		readings:   make(map[string][]string),
+		idReadings: make(map[string][]string),
+		levels:     make(map[string]string),
+	}
+	c.publish()
+	return c
+}
+
 // InitDictCache 初始化并从数据库加载全量词典
 func InitDictCache(db *gorm.DB) error {
-	GlobalDict = &DictCache{
-		mapping: make(map[string][]string),
-		maxLen:  0,
-	}
+	GlobalDict = NewDictCache()
 	return GlobalDict.Reload(db)
 }
 
 // Reload 全量加载
 func (c *DictCache) Reload(db *gorm.DB) error {
-	c.Lock()
-	defer c.Unlock()
-
 	var vocabs []model.Vocab
 	// 只查询需要的字段
 	if err := db.Select("id, kanji").Find(&vocabs).Error; err != nil {
 		return err
 	}
 
+	var senses []model.VocabSense
+	if err := db.Select("vocab_id, level, reading").Find(&senses).Error; err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	// 重置 map
 	c.mapping = make(map[string][]string)
-	c.maxLen = 0
+	c.readings = make(map[string][]string)
+	c.idReadings = make(map[string][]string)
+	c.levels = make(map[string]string)
 
+	kanjiByID := make(map[string]string, len(vocabs))
 	for _, v := range vocabs {
 		c.addInternal(v.Kanji, v.ID)
+		kanjiByID[v.ID] = v.Kanji
 	}
+	for _, s := range senses {
+		c.addLevel(s.VocabID, s.Level)
+		if kanji, ok := kanjiByID[s.VocabID]; ok {
+			c.addReading(kanji, s.Reading, s.VocabID)
+		}
+	}
+	c.publish()
 	return nil
 }
 
 // AddOrUpdate 动态添加/更新单个词（无需查库）
-func (c *DictCache) AddOrUpdate(kanji, id string) {
-	c.Lock()
-	defer c.Unlock()
-	c.addInternal(kanji, id)
+// vocab 需携带 Senses，用于刷新等级信息
+// 每次调用都会重建快照，批量修改请使用 Batch
+func (c *DictCache) AddOrUpdate(vocab model.Vocab) {
+	c.Batch(func(b *DictBatch) { b.AddOrUpdate(vocab) })
 }
 
 // Remove 删除某个 ID 的引用
 func (c *DictCache) Remove(kanji, id string) {
-	c.Lock()
-	defer c.Unlock()
+	c.Batch(func(b *DictBatch) { b.Remove(kanji, id) })
+}
 
-	key := c.cleanKey(kanji)
-	ids, exists := c.mapping[key]
-	if !exists {
-		return
-	}
+// DictBatch 批量修改，只在 Batch 的回调中有效
+type DictBatch struct {
+	c *DictCache
+}
 
-	// 过滤掉该 ID
-	newIDs := make([]string, 0, len(ids))
-	for _, existingID := range ids {
-		if existingID != id {
-			newIDs = append(newIDs, existingID)
-		}
-	}
+// Batch 在一次加锁中应用多项修改，全部完成后只重建一次快照
+// 回调执行期间读操作仍看到修改前的快照
+func (c *DictCache) Batch(fn func(b *DictBatch)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fn(&DictBatch{c: c})
+	c.publish()
+}
 
-	if len(newIDs) == 0 {
-		delete(c.mapping, key)
-	} else {
-		c.mapping[key] = newIDs
+// AddOrUpdate 同 DictCache.AddOrUpdate，但不立即重建快照
+func (b *DictBatch) AddOrUpdate(vocab model.Vocab) {
+	c := b.c
+	c.addInternal(vocab.Kanji, vocab.ID)
+
+	delete(c.levels, vocab.ID)
+	c.removeReadings(vocab.ID)
+	for _, s := range vocab.Senses {
+		c.addLevel(vocab.ID, s.Level)
+		c.addReading(vocab.Kanji, s.Reading, vocab.ID)
 	}
 }
 
+// Remove 同 DictCache.Remove，但不立即重建快照
+func (b *DictBatch) Remove(kanji, id string) {
+	c := b.c
+	delete(c.levels, id)
+	c.removeReadings(id)
+	removeID(c.mapping, c.cleanKey(kanji), id)
+}
+
 // Get 查找词
 func (c *DictCache) Get(word string) ([]string, bool) {
-	c.RLock()
-	defer c.RUnlock()
-	ids, ok := c.mapping[word]
-	return ids, ok
+	return c.snap.Load().index.get(word)
+}
+
+// GetReading 按读音查找词
+func (c *DictCache) GetReading(reading string) ([]string, bool) {
+	return c.snap.Load().reading.get(reading)
+}
+
+// PrefixSearch 一次遍历返回所有从 runes[start] 开始的命中 (无锁)
+// fn 按结束位置升序回调；返回值为能在索引中走到的最远字符数
+func (c *DictCache) PrefixSearch(runes []rune, start int, fn func(end int, ids []string)) int {
+	return c.snap.Load().index.prefixSearch(runes, start, fn)
+}
+
+// ReadingPrefixSearch 与 PrefixSearch 相同，但在读音键空间中查找
+func (c *DictCache) ReadingPrefixSearch(runes []rune, start int, fn func(end int, ids []string)) int {
+	return c.snap.Load().reading.prefixSearch(runes, start, fn)
+}
+
+// Level 获取词条最简单的 JLPT 等级 (N5 最简单)，未知时返回空字符串
+func (c *DictCache) Level(id string) string {
+	return c.snap.Load().levels[id]
 }
 
 // MaxLen 获取最大词长
 func (c *DictCache) MaxLen() int {
-	c.RLock()
-	defer c.RUnlock()
-	return c.maxLen
+	return c.snap.Load().maxLen
+}
+
+// publish 根据当前 mapping 重建快照并原子替换（调用方需持有 mu）
+func (c *DictCache) publish() {
+	levels := make(map[string]string, len(c.levels))
+	for id, lv := range c.levels {
+		levels[id] = lv
+	}
+
+	maxLen := 0
+	for key := range c.mapping {
+		if rLen := utf8.RuneCountInString(key); rLen > maxLen {
+			maxLen = rLen
+		}
+	}
+
+	c.snap.Store(&dictSnapshot{
+		index:   buildPrefixIndex(c.mapping),
+		reading: buildPrefixIndex(c.readings),
+		levels:  levels,
+		maxLen:  maxLen,
+	})
 }
 
 // addInternal 内部添加逻辑（不带锁）
 func (c *DictCache) addInternal(kanji, id string) {
-	key := c.cleanKey(kanji)
+	addID(c.mapping, c.cleanKey(kanji), id)
+}
 
-	// 检查 ID 是否已存在，防止重复
-	exists := false
-	for _, oldID := range c.mapping[key] {
+// addReading 内部登记读音（不带锁）；与词面相同的读音 (纯假名词) 不重复登记
+func (c *DictCache) addReading(kanji, reading, id string) {
+	key := c.cleanKey(reading)
+	if key == "" || key == c.cleanKey(kanji) {
+		return
+	}
+	for _, k := range c.idReadings[id] {
+		if k == key {
+			return
+		}
+	}
+	addID(c.readings, key, id)
+	c.idReadings[id] = append(c.idReadings[id], key)
+}
+
+// removeReadings 内部清理某个 ID 的全部读音（不带锁）
+func (c *DictCache) removeReadings(id string) {
+	for _, key := range c.idReadings[id] {
+		removeID(c.readings, key, id)
+	}
+	delete(c.idReadings, id)
+}
+
+// addID 向键追加 ID，防止重复
+func addID(m map[string][]string, key, id string) {
+	for _, oldID := range m[key] {
 		if oldID == id {
-			exists = true
-			break
+			return
 		}
 	}
+	m[key] = append(m[key], id)
+}
+
+// removeID 从键中过滤掉 ID，键为空时删除
+func removeID(m map[string][]string, key, id string) {
+	ids, exists := m[key]
 	if !exists {
-		c.mapping[key] = append(c.mapping[key], id)
+		return
+	}
+
+	newIDs := make([]string, 0, len(ids))
+	for _, existingID := range ids {
+		if existingID != id {
+			newIDs = append(newIDs, existingID)
+		}
 	}
 
-	rLen := len([]rune(key))
-	if rLen > c.maxLen {
-		c.maxLen = rLen
+	if len(newIDs) == 0 {
+		delete(m, key)
+	} else {
+		m[key] = newIDs
+	}
+}
+
+// addLevel 内部记录等级（不带锁），同一词条保留最简单的等级
+func (c *DictCache) addLevel(id, level string) {
+	if level == "" {
+		return
+	}
+	if cur, ok := c.levels[id]; !ok || level > cur {
+		c.levels[id] = level
 	}
 }
 
 // cleanKey 统一的清洗逻辑
 func (c *DictCache) cleanKey(kanji string) string {
+	return NormalizeKey(kanji)
+}
+
+// NormalizeKey 词典键的清洗：去掉接头/接尾辞标记 ~ ～，再做文本规范化
+// 查询方 (文章分析、词书导入) 需对输入做同样的 textnorm 规范化才能命中
+func NormalizeKey(kanji string) string {
 	k := strings.ReplaceAll(kanji, "~", "")
 	k = strings.ReplaceAll(k, "～", "")
-	return k
+	return textnorm.String(k)
 }
@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+
+	"dongwai_backend/internal/model"
+)
+
+func TestDictCachePrefixSearch(t *testing.T) {
+	c := NewDictCache()
+	c.AddOrUpdate(model.Vocab{ID: "w1", Kanji: "日本"})
+	c.AddOrUpdate(model.Vocab{ID: "w2", Kanji: "日本語", Senses: []model.VocabSense{{Level: "N4"}, {Level: "N5"}}})
+	c.AddOrUpdate(model.Vocab{ID: "w3", Kanji: "～的"})
+
+	if ids, ok := c.Get("的"); !ok || !reflect.DeepEqual(ids, []string{"w3"}) {
+		t.Errorf("Get(的) = %v, %v", ids, ok)
+	}
+	if lv := c.Level("w2"); lv != "N5" {
+		t.Errorf("Level(w2) = %q, want N5", lv)
+	}
+	if c.MaxLen() != 3 {
+		t.Errorf("MaxLen() = %d, want 3", c.MaxLen())
+	}
+
+	var ends []int
+	depth := c.PrefixSearch([]rune("日本語学校"), 0, func(end int, ids []string) {
+		ends = append(ends, end)
+	})
+	if !reflect.DeepEqual(ends, []int{2, 3}) || depth != 3 {
+		t.Errorf("PrefixSearch ends = %v depth = %d", ends, depth)
+	}
+
+	c.AddOrUpdate(model.Vocab{ID: "w4", Kanji: "寿司", Senses: []model.VocabSense{{Reading: "すし"}}})
+	if ids, ok := c.GetReading("すし"); !ok || !reflect.DeepEqual(ids, []string{"w4"}) {
+		t.Errorf("GetReading(すし) = %v, %v", ids, ok)
+	}
+	c.AddOrUpdate(model.Vocab{ID: "w4", Kanji: "寿司", Senses: []model.VocabSense{{Reading: "スシ"}}})
+	if _, ok := c.GetReading("すし"); ok {
+		t.Error("更新读音后旧读音应被清理")
+	}
+
+	c.Remove("日本語", "w2")
+	if _, ok := c.Get("日本語"); ok {
+		t.Error("Remove 后仍能查到 日本語")
+	}
+	if c.MaxLen() != 2 {
+		t.Errorf("Remove 后 MaxLen() = %d, want 2", c.MaxLen())
+	}
+}
+
+func TestDictCacheConcurrentReadWrite(t *testing.T) {
+	c := NewDictCache()
+	runes := []rune("日本語学校")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				c.PrefixSearch(runes, 0, func(int, []string) {})
+				c.Get("日本")
+			}
+		}()
+	}
+	for j := 0; j < 50; j++ {
+		c.AddOrUpdate(model.Vocab{ID: fmt.Sprintf("w%d", j), Kanji: "日本"})
+	}
+	wg.Wait()
+
+	if ids, _ := c.Get("日本"); len(ids) != 50 {
+		t.Errorf("len(ids) = %d, want 50", len(ids))
+	}
+}
+
+// ========================================
+// 基准测试：10 万词条下，逐子串查 map 与前缀索引一次遍历的对比
+// ========================================
+
+const benchEntries = 100000
+
+var benchAlphabet = []rune("日本語学校先生大小中山川田人月火水木金土上下左右東西南北食飲見行来書読話聞言思会社電車駅道店")
+
+func benchData() (map[string][]string, []rune) {
+	rng := rand.New(rand.NewSource(1))
+	randWord := func(n int) string {
+		rs := make([]rune, n)
+		for i := range rs {
+			rs[i] = benchAlphabet[rng.Intn(len(benchAlphabet))]
+		}
+		return string(rs)
+	}
+
+	mapping := make(map[string][]string, benchEntries)
+	for len(mapping) < benchEntries-1 {
+		w := randWord(1 + rng.Intn(4))
+		mapping[w] = append(mapping[w], fmt.Sprintf("w%d", len(mapping)))
+	}
+	// 一个超长词条会把逐子串扫描的窗口拉大
+	mapping[randWord(24)] = []string{"long"}
+
+	return mapping, []rune(randWord(5000))
+}
+
+// legacyDict 旧实现：RWMutex + map，每个子串查一次
+type legacyDict struct {
+	sync.RWMutex
+	mapping map[string][]string
+	maxLen  int
+}
+
+func (d *legacyDict) Get(word string) ([]string, bool) {
+	d.RLock()
+	defer d.RUnlock()
+	ids, ok := d.mapping[word]
+	return ids, ok
+}
+
+func (d *legacyDict) MaxLen() int {
+	d.RLock()
+	defer d.RUnlock()
+	return d.maxLen
+}
+
+func BenchmarkMapScan(b *testing.B) {
+	mapping, article := benchData()
+	d := &legacyDict{mapping: mapping}
+	for k := range mapping {
+		if l := len([]rune(k)); l > d.maxLen {
+			d.maxLen = l
+		}
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		hits := 0
+		maxLen := d.MaxLen()
+		for i := range article {
+			limit := i + maxLen
+			if limit > len(article) {
+				limit = len(article)
+			}
+			for j := limit; j > i; j-- {
+				if _, ok := d.Get(string(article[i:j])); ok {
+					hits++
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkPrefixSearch(b *testing.B) {
+	mapping, article := benchData()
+	c := NewDictCache()
+	c.mapping = mapping
+	c.publish()
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		hits := 0
+		for i := range article {
+			c.PrefixSearch(article, i, func(int, []string) { hits++ })
+		}
+	}
+}
+
+func BenchmarkPublish(b *testing.B) {
+	mapping, _ := benchData()
+	c := NewDictCache()
+	c.mapping = mapping
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		c.publish()
+	}
+}
+
+func TestDictCacheBatch(t *testing.T) {
+	c := NewDictCache()
+	c.AddOrUpdate(model.Vocab{ID: "w1", Kanji: "日本"})
+	before := c.snap.Load()
+
+	c.Batch(func(b *DictBatch) {
+		b.AddOrUpdate(model.Vocab{ID: "w2", Kanji: "日本語", Senses: []model.VocabSense{{Level: "N5", Reading: "にほんご"}}})
+		b.AddOrUpdate(model.Vocab{ID: "w3", Kanji: "学校"})
+		b.Remove("日本", "w1")
+
+		// 回调结束前读者仍看到旧快照
+		if c.snap.Load() != before {
+			t.Error("snapshot published before batch finished")
+		}
+		if _, ok := c.Get("日本語"); ok {
+			t.Error("日本語 visible before batch finished")
+		}
+	})
+
+	if _, ok := c.Get("日本"); ok {
+		t.Error("日本 should be removed")
+	}
+	for _, w := range []string{"日本語", "学校"} {
+		if _, ok := c.Get(w); !ok {
+			t.Errorf("%s not found after batch", w)
+		}
+	}
+	if ids, ok := c.GetReading("にほんご"); !ok || !reflect.DeepEqual(ids, []string{"w2"}) {
+		t.Errorf("GetReading(にほんご) = %v, %v", ids, ok)
+	}
+	if c.Level("w2") != "N5" || c.MaxLen() != 3 {
+		t.Errorf("level = %q, maxLen = %d", c.Level("w2"), c.MaxLen())
+	}
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"sort"
+)
+
+// prefixIndex 不可变的前缀索引 (压缩存储的 rune trie)
+// 节点 n 的子边位于 labels/child 的 [first[n], first[n+1]) 区间内，按 rune 升序排列，
+// 查找子节点时做二分。构建完成后只读，可被多个 goroutine 无锁并发访问。
+type prefixIndex struct {
+	first  []int32    // 节点 -> 第一条子边下标 (长度为节点数 + 1)
+	labels []rune     // 边上的字符
+	child  []int32    // 边指向的节点
+	value  []int32    // 节点 -> entries 下标，-1 表示不是词尾
+	entry  [][]string // 词尾节点对应的 ID 列表
+}
+
+// buildPrefixIndex 从 清洗后的词 -> ID 列表 构建索引
+// 先按 rune 序排序，再逐层按公共前缀分组展开，避免构建期间为每个节点分配 map
+func buildPrefixIndex(mapping map[string][]string) *prefixIndex {
+	type item struct {
+		key []rune
+		ids []string
+	}
+	items := make([]item, 0, len(mapping))
+	for key, ids := range mapping {
+		if key == "" || len(ids) == 0 {
+			continue
+		}
+		items = append(items, item{key: []rune(key), ids: ids})
+	}
+	sort.Slice(items, func(a, b int) bool { return lessRunes(items[a].key, items[b].key) })
+
+	// span 表示一个节点：共享长度为 depth 的前缀的 items[lo:hi]
+	type span struct{ lo, hi, depth int }
+
+	idx := &prefixIndex{}
+	queue := []span{{0, len(items), 0}}
+	for q := 0; q < len(queue); q++ {
+		n := queue[q]
+		idx.first = append(idx.first, int32(len(idx.labels)))
+
+		lo := n.lo
+		// 排序后恰好等于前缀的词位于区间开头
+		if lo < n.hi && len(items[lo].key) == n.depth {
+			idx.value = append(idx.value, int32(len(idx.entry)))
+			idx.entry = append(idx.entry, append([]string(nil), items[lo].ids...))
+			lo++
+		} else {
+			idx.value = append(idx.value, -1)
+		}
+
+		for lo < n.hi {
+			r := items[lo].key[n.depth]
+			hi := lo + 1
+			for hi < n.hi && items[hi].key[n.depth] == r {
+				hi++
+			}
+			idx.labels = append(idx.labels, r)
+			idx.child = append(idx.child, int32(len(queue)))
+			queue = append(queue, span{lo, hi, n.depth + 1})
+			lo = hi
+		}
+	}
+	idx.first = append(idx.first, int32(len(idx.labels)))
+
+	return idx
+}
+
+func lessRunes(a, b []rune) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// next 沿字符 r 前进一步，不存在时返回 -1
+func (p *prefixIndex) next(node int32, r rune) int32 {
+	lo, hi := int(p.first[node]), int(p.first[node+1])
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if p.labels[mid] < r {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	if lo < int(p.first[node+1]) && p.labels[lo] == r {
+		return p.child[lo]
+	}
+	return -1
+}
+
+// get 精确查找
+func (p *prefixIndex) get(word string) ([]string, bool) {
+	node := int32(0)
+	for _, r := range word {
+		if node = p.next(node, r); node < 0 {
+			return nil, false
+		}
+	}
+	if v := p.value[node]; v >= 0 {
+		return p.entry[v], true
+	}
+	return nil, false
+}
+
+// prefixSearch 一次遍历返回所有从 runes[start] 开始的命中
+// 返回值 depth 为能在索引中走到的最远字符数 (即便该处不是词尾)
+func (p *prefixIndex) prefixSearch(runes []rune, start int, fn func(end int, ids []string)) int {
+	node := int32(0)
+	depth := 0
+	for i := start; i < len(runes); i++ {
+		if node = p.next(node, runes[i]); node < 0 {
+			break
+		}
+		depth++
+		if v := p.value[node]; v >= 0 {
+			fn(i+1, p.entry[v])
+		}
+	}
+	return depth
+}
@@ -0,0 +1,124 @@
+package deinflect
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Type 词形类别（位掩码），用于约束规则之间的衔接
+type Type uint16
+
+const (
+	TypeV1   Type = 1 << iota // 一段动词
+	TypeV5                    // 五段动词
+	TypeVS                    // サ变动词
+	TypeVK                    // カ变动词
+	TypeAdjI                  // い形容词
+	TypeTe                    // て形（中间态，只能继续还原）
+)
+
+// DictionaryForms 可以作为词典原形出现的类别
+const DictionaryForms = TypeV1 | TypeV5 | TypeVS | TypeVK | TypeAdjI
+
+// 变形原因 (由内向外记录在 Result.Reasons 中)
+const (
+	ReasonPolite            = "polite"
+	ReasonNegative          = "negative"
+	ReasonPast              = "past"
+	ReasonTe                = "te"
+	ReasonTara              = "tara"
+	ReasonTari              = "tari"
+	ReasonConditional       = "conditional"
+	ReasonPotential         = "potential"
+	ReasonPassive           = "passive"
+	ReasonCausative         = "causative"
+	ReasonCausativePassive  = "causative-passive"
+	ReasonVolitional        = "volitional"
+	ReasonImperative        = "imperative"
+	ReasonTai               = "tai"
+	ReasonMasuStem          = "masu-stem"
+	ReasonProgressive       = "progressive"
+	ReasonShimau            = "shimau"
+	ReasonAdverbial         = "adverbial"
+	ReasonNoun              = "noun"
+	ReasonSou               = "sou"
+	ReasonSugiru            = "sugiru"
+	ReasonBecome            = "become"
+	ReasonClassicalNegative = "zu"
+)
+
+// maxResults 单次还原的结果上限，防止规则组合爆炸
+const maxResults = 128
+
+// MaxSuffixLen 活用词尾相对原形最多多出的字符数，分词时据此放宽窗口
+const MaxSuffixLen = 10
+
+// Result 一个还原候选
+type Result struct {
+	Base    string   // 还原后的词典原形
+	Type    Type     // 原形的类别
+	Reasons []string // 识别出的活用链，按从原形向外的顺序排列
+}
+
+// Deinflect 将活用形还原为可能的词典原形
+// 只返回经过至少一次还原、且类别为词典原形的结果；原词本身不包含在内
+func Deinflect(word string) []Result {
+	if word == "" {
+		return nil
+	}
+
+	type state struct {
+		term    string
+		typ     Type
+		reasons []string
+	}
+
+	queue := []state{{term: word}}
+	seen := map[string]bool{word + "|0": true}
+	var results []Result
+
+	for i := 0; i < len(queue) && len(queue) < maxResults; i++ {
+		cur := queue[i]
+		for _, r := range rules {
+			// 原词 (typ == 0) 可以匹配任何规则，中间态必须满足规则的入口类别
+			if cur.typ != 0 && cur.typ&r.in == 0 {
+				continue
+			}
+			if len(cur.term) < len(r.from) || (len(cur.term) == len(r.from) && !r.whole) {
+				continue
+			}
+			if !strings.HasSuffix(cur.term, r.from) {
+				continue
+			}
+
+			term := cur.term[:len(cur.term)-len(r.from)] + r.to
+			key := term + "|" + strconv.Itoa(int(r.out))
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			reasons := make([]string, 0, len(r.reasons)+len(cur.reasons))
+			reasons = append(reasons, r.reasons...)
+			reasons = append(reasons, cur.reasons...)
+
+			next := state{term: term, typ: r.out, reasons: reasons}
+			queue = append(queue, next)
+			if r.out&DictionaryForms != 0 && term != word {
+				results = append(results, Result{Base: term, Type: r.out, Reasons: reasons})
+			}
+		}
+	}
+
+	return results
+}
+
+// SuruStem 对サ变动词返回去掉 "する" 后的词干 (例如 勉強する -> 勉強)
+// 词库中サ变动词大多只收录名词形式，分词时需要回退到词干查询
+func (r Result) SuruStem() (string, bool) {
+	if r.Type != TypeVS || !strings.HasSuffix(r.Base, "する") {
+		return "", false
+	}
+	stem := strings.TrimSuffix(r.Base, "する")
+	return stem, stem != ""
+}
@@ -0,0 +1,67 @@
+package deinflect
+
+import (
+	"reflect"
+	"testing"
+)
+
+func find(results []Result, base string) (Result, bool) {
+	for _, r := range results {
+		if r.Base == base {
+			return r, true
+		}
+	}
+	return Result{}, false
+}
+
+func TestDeinflect(t *testing.T) {
+	cases := []struct {
+		word    string
+		base    string
+		reasons []string
+	}{
+		{"食べました", "食べる", []string{ReasonPolite, ReasonPast}},
+		{"食べさせられなかった", "食べる", []string{ReasonCausative, ReasonPassive, ReasonNegative, ReasonPast}},
+		{"食べている", "食べる", []string{ReasonTe, ReasonProgressive}},
+		{"美味しくない", "美味しい", []string{ReasonNegative}},
+		{"美味しかった", "美味しい", []string{ReasonPast}},
+		{"行って", "行く", []string{ReasonTe}},
+		{"書かない", "書く", []string{ReasonNegative}},
+		{"書けば", "書く", []string{ReasonConditional}},
+		{"読もう", "読む", []string{ReasonVolitional}},
+		{"読まれる", "読む", []string{ReasonPassive}},
+		{"話せる", "話す", []string{ReasonPotential}},
+		{"勉強しました", "勉強する", []string{ReasonPolite, ReasonPast}},
+		{"来なかった", "来る", []string{ReasonNegative, ReasonPast}},
+		{"こない", "くる", []string{ReasonNegative}},
+	}
+
+	for _, tc := range cases {
+		got, ok := find(Deinflect(tc.word), tc.base)
+		if !ok {
+			t.Errorf("Deinflect(%q): 未还原出 %q", tc.word, tc.base)
+			continue
+		}
+		if !reflect.DeepEqual(got.Reasons, tc.reasons) {
+			t.Errorf("Deinflect(%q) -> %q reasons = %v, want %v", tc.word, tc.base, got.Reasons, tc.reasons)
+		}
+	}
+}
+
+func TestDeinflectSkipsIdentity(t *testing.T) {
+	for _, r := range Deinflect("捨てる") {
+		if r.Base == "捨てる" {
+			t.Fatalf("原词不应作为还原结果返回: %+v", r)
+		}
+	}
+}
+
+func TestSuruStem(t *testing.T) {
+	got, ok := find(Deinflect("勉強して"), "勉強する")
+	if !ok {
+		t.Fatal("未还原出 勉強する")
+	}
+	if stem, ok := got.SuruStem(); !ok || stem != "勉強" {
+		t.Errorf("SuruStem() = %q, %v", stem, ok)
+	}
+}
@@ -0,0 +1,192 @@
+package deinflect
+
+// rule 一条还原规则：把词尾 from 替换成 to
+// in  为活用形自身可能的类别 (0 表示只能作用于原词，即最外层活用)
+// out 为还原后的类别
+// whole 表示 from 已包含词干 (不规则动词)，允许整词匹配
+type rule struct {
+	from    string
+	to      string
+	in      Type
+	out     Type
+	reasons []string
+	whole   bool
+}
+
+// godanRow 五段动词各段的词尾
+type godanRow struct {
+	u, a, i, e, o string
+	te, ta        string
+}
+
+var godanRows = []godanRow{
+	{"う", "わ", "い", "え", "お", "って", "った"},
+	{"く", "か", "き", "け", "こ", "いて", "いた"},
+	{"ぐ", "が", "ぎ", "げ", "ご", "いで", "いだ"},
+	{"す", "さ", "し", "せ", "そ", "して", "した"},
+	{"つ", "た", "ち", "て", "と", "って", "った"},
+	{"ぬ", "な", "に", "ね", "の", "んで", "んだ"},
+	{"ぶ", "ば", "び", "べ", "ぼ", "んで", "んだ"},
+	{"む", "ま", "み", "め", "も", "んで", "んだ"},
+	{"る", "ら", "り", "れ", "ろ", "って", "った"},
+}
+
+// politeSuffixes ます 系列 (接在连用形之后)
+var politeSuffixes = []struct {
+	suffix  string
+	reasons []string
+}{
+	{"ます", []string{ReasonPolite}},
+	{"ました", []string{ReasonPolite, ReasonPast}},
+	{"ません", []string{ReasonPolite, ReasonNegative}},
+	{"ませんでした", []string{ReasonPolite, ReasonNegative, ReasonPast}},
+	{"ましょう", []string{ReasonPolite, ReasonVolitional}},
+	{"まして", []string{ReasonPolite, ReasonTe}},
+}
+
+var rules = buildRules()
+
+func r(from, to string, in, out Type, reasons ...string) rule {
+	return rule{from: from, to: to, in: in, out: out, reasons: reasons}
+}
+
+// irregular 不规则动词的整词规则
+func irregular(from, to string, in, out Type, reasons ...string) rule {
+	rl := r(from, to, in, out, reasons...)
+	rl.whole = true
+	return rl
+}
+
+func buildRules() []rule {
+	var list []rule
+
+	// --- 五段动词 ---
+	for _, g := range godanRows {
+		list = append(list,
+			r(g.a+"ない", g.u, TypeAdjI, TypeV5, ReasonNegative),
+			r(g.a+"ず", g.u, 0, TypeV5, ReasonClassicalNegative),
+			r(g.a+"れる", g.u, TypeV1, TypeV5, ReasonPassive),
+			r(g.a+"せる", g.u, TypeV1, TypeV5, ReasonCausative),
+			r(g.a+"される", g.u, TypeV1, TypeV5, ReasonCausativePassive),
+			r(g.i+"たい", g.u, TypeAdjI, TypeV5, ReasonTai),
+			r(g.i, g.u, 0, TypeV5, ReasonMasuStem),
+			r(g.e+"る", g.u, TypeV1, TypeV5, ReasonPotential),
+			r(g.e+"ば", g.u, 0, TypeV5, ReasonConditional),
+			r(g.e, g.u, 0, TypeV5, ReasonImperative),
+			r(g.o+"う", g.u, 0, TypeV5, ReasonVolitional),
+			r(g.te, g.u, TypeTe, TypeV5, ReasonTe),
+			r(g.ta, g.u, 0, TypeV5, ReasonPast),
+			r(g.ta+"ら", g.u, 0, TypeV5, ReasonTara),
+			r(g.ta+"り", g.u, 0, TypeV5, ReasonTari),
+		)
+		for _, p := range politeSuffixes {
+			list = append(list, r(g.i+p.suffix, g.u, 0, TypeV5, p.reasons...))
+		}
+	}
+
+	// 行く 的て形、た形是例外 (行って / 行った)
+	for _, stem := range []string{"行", "い"} {
+		list = append(list,
+			irregular(stem+"って", stem+"く", TypeTe, TypeV5, ReasonTe),
+			irregular(stem+"った", stem+"く", 0, TypeV5, ReasonPast),
+			irregular(stem+"ったら", stem+"く", 0, TypeV5, ReasonTara),
+			irregular(stem+"ったり", stem+"く", 0, TypeV5, ReasonTari),
+		)
+	}
+
+	// --- 一段动词 ---
+	list = append(list,
+		r("ない", "る", TypeAdjI, TypeV1, ReasonNegative),
+		r("ず", "る", 0, TypeV1, ReasonClassicalNegative),
+		r("たい", "る", TypeAdjI, TypeV1, ReasonTai),
+		r("て", "る", TypeTe, TypeV1, ReasonTe),
+		r("た", "る", 0, TypeV1, ReasonPast),
+		r("たら", "る", 0, TypeV1, ReasonTara),
+		r("たり", "る", 0, TypeV1, ReasonTari),
+		r("れば", "る", 0, TypeV1, ReasonConditional),
+		r("られる", "る", TypeV1, TypeV1, ReasonPassive),
+		r("られる", "る", TypeV1, TypeV1, ReasonPotential),
+		r("れる", "る", TypeV1, TypeV1, ReasonPotential), // ら抜き言葉
+		r("させる", "る", TypeV1, TypeV1, ReasonCausative),
+		r("よう", "る", 0, TypeV1, ReasonVolitional),
+		r("ろ", "る", 0, TypeV1, ReasonImperative),
+		r("よ", "る", 0, TypeV1, ReasonImperative),
+	)
+	for _, p := range politeSuffixes {
+		list = append(list, r(p.suffix, "る", 0, TypeV1, p.reasons...))
+	}
+
+	// --- サ变动词 ---
+	list = append(list,
+		r("しない", "する", TypeAdjI, TypeVS, ReasonNegative),
+		r("せず", "する", 0, TypeVS, ReasonClassicalNegative),
+		r("したい", "する", TypeAdjI, TypeVS, ReasonTai),
+		r("して", "する", TypeTe, TypeVS, ReasonTe),
+		r("した", "する", 0, TypeVS, ReasonPast),
+		r("したら", "する", 0, TypeVS, ReasonTara),
+		r("したり", "する", 0, TypeVS, ReasonTari),
+		r("すれば", "する", 0, TypeVS, ReasonConditional),
+		r("できる", "する", TypeV1, TypeVS, ReasonPotential),
+		r("される", "する", TypeV1, TypeVS, ReasonPassive),
+		r("させる", "する", TypeV1, TypeVS, ReasonCausative),
+		r("しよう", "する", 0, TypeVS, ReasonVolitional),
+		r("しろ", "する", 0, TypeVS, ReasonImperative),
+		r("せよ", "する", 0, TypeVS, ReasonImperative),
+	)
+	for _, p := range politeSuffixes {
+		list = append(list, r("し"+p.suffix, "する", 0, TypeVS, p.reasons...))
+	}
+
+	// --- カ变动词 (来る / くる) ---
+	for _, k := range []struct{ base, ko, ki, ku string }{
+		{"くる", "こ", "き", "く"},
+		{"来る", "来", "来", "来"},
+	} {
+		list = append(list,
+			irregular(k.ko+"ない", k.base, TypeAdjI, TypeVK, ReasonNegative),
+			irregular(k.ki+"たい", k.base, TypeAdjI, TypeVK, ReasonTai),
+			irregular(k.ki+"て", k.base, TypeTe, TypeVK, ReasonTe),
+			irregular(k.ki+"た", k.base, 0, TypeVK, ReasonPast),
+			irregular(k.ki+"たら", k.base, 0, TypeVK, ReasonTara),
+			irregular(k.ki+"たり", k.base, 0, TypeVK, ReasonTari),
+			irregular(k.ku+"れば", k.base, 0, TypeVK, ReasonConditional),
+			irregular(k.ko+"られる", k.base, TypeV1, TypeVK, ReasonPotential),
+			irregular(k.ko+"させる", k.base, TypeV1, TypeVK, ReasonCausative),
+			irregular(k.ko+"よう", k.base, 0, TypeVK, ReasonVolitional),
+			irregular(k.ko+"い", k.base, 0, TypeVK, ReasonImperative),
+		)
+		for _, p := range politeSuffixes {
+			list = append(list, irregular(k.ki+p.suffix, k.base, 0, TypeVK, p.reasons...))
+		}
+	}
+
+	// --- い形容词 ---
+	list = append(list,
+		r("くない", "い", TypeAdjI, TypeAdjI, ReasonNegative),
+		r("かった", "い", 0, TypeAdjI, ReasonPast),
+		r("かったら", "い", 0, TypeAdjI, ReasonTara),
+		r("くて", "い", 0, TypeAdjI, ReasonTe),
+		r("く", "い", 0, TypeAdjI, ReasonAdverbial),
+		r("ければ", "い", 0, TypeAdjI, ReasonConditional),
+		r("さ", "い", 0, TypeAdjI, ReasonNoun),
+		r("そう", "い", 0, TypeAdjI, ReasonSou),
+		r("すぎる", "い", TypeV1, TypeAdjI, ReasonSugiru),
+		r("くなる", "い", TypeV5, TypeAdjI, ReasonBecome),
+		r("くありません", "い", 0, TypeAdjI, ReasonPolite, ReasonNegative),
+	)
+
+	// --- 补助动词：ている / てしまう ---
+	for _, t := range []string{"て", "で"} {
+		list = append(list,
+			r(t+"いる", t, TypeV1, TypeTe, ReasonProgressive),
+			r(t+"る", t, TypeV1, TypeTe, ReasonProgressive),
+			r(t+"しまう", t, TypeV5, TypeTe, ReasonShimau),
+		)
+	}
+	list = append(list,
+		r("ちゃう", "て", TypeV5, TypeTe, ReasonShimau),
+		r("じゃう", "で", TypeV5, TypeTe, ReasonShimau),
+	)
+
+	return list
+}
@@ -0,0 +1,152 @@
+package disambig
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// LRU 位于持久化存储之前的内存缓存
+// 读取先查内存，未命中再查后端并回填；写入与失效同时作用于两层。
+type LRU struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List               // 最近使用的在前
+	items    map[string]*list.Element // key -> 元素
+	bySense  map[string]map[string]bool
+	backend  Store // 可以为 nil (只用内存)
+}
+
+type lruItem struct {
+	entry Entry
+}
+
+// NewLRU 创建内存缓存，backend 为 nil 时只缓存在内存中
+func NewLRU(capacity int, backend Store) *LRU {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &LRU{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		bySense:  make(map[string]map[string]bool),
+		backend:  backend,
+	}
+}
+
+func (c *LRU) Get(ctx context.Context, keys []string) (map[string]Entry, error) {
+	result := make(map[string]Entry)
+	var missing []string
+
+	c.mu.Lock()
+	for _, k := range keys {
+		if el, ok := c.items[k]; ok {
+			c.ll.MoveToFront(el)
+			result[k] = el.Value.(*lruItem).entry
+		} else {
+			missing = append(missing, k)
+		}
+	}
+	c.mu.Unlock()
+
+	if len(missing) == 0 || c.backend == nil {
+		return result, nil
+	}
+
+	found, err := c.backend.Get(ctx, missing)
+	if err != nil {
+		// 后端不可用时退化为只用内存结果
+		return result, err
+	}
+
+	c.mu.Lock()
+	for k, e := range found {
+		result[k] = e
+		c.add(e)
+	}
+	c.mu.Unlock()
+	return result, nil
+}
+
+func (c *LRU) Put(ctx context.Context, entries []Entry) error {
+	c.mu.Lock()
+	for _, e := range entries {
+		c.add(e)
+	}
+	c.mu.Unlock()
+
+	if c.backend == nil {
+		return nil
+	}
+	return c.backend.Put(ctx, entries)
+}
+
+func (c *LRU) InvalidateSenses(ctx context.Context, senseIDs []string) error {
+	c.mu.Lock()
+	for _, id := range senseIDs {
+		for k := range c.bySense[id] {
+			if el, ok := c.items[k]; ok {
+				c.removeElement(el)
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	if c.backend == nil {
+		return nil
+	}
+	return c.backend.InvalidateSenses(ctx, senseIDs)
+}
+
+// Len 当前内存中的条目数
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// add 写入或刷新一个条目 (调用方需持有 mu)
+func (c *LRU) add(e Entry) {
+	if el, ok := c.items[e.Key]; ok {
+		old := el.Value.(*lruItem)
+		c.unlinkSenses(old.entry)
+		old.entry = e
+		c.linkSenses(e)
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[e.Key] = c.ll.PushFront(&lruItem{entry: e})
+	c.linkSenses(e)
+	for c.ll.Len() > c.capacity {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *LRU) removeElement(el *list.Element) {
+	item := el.Value.(*lruItem)
+	c.ll.Remove(el)
+	delete(c.items, item.entry.Key)
+	c.unlinkSenses(item.entry)
+}
+
+func (c *LRU) linkSenses(e Entry) {
+	for _, id := range e.SenseIDs {
+		keys := c.bySense[id]
+		if keys == nil {
+			keys = make(map[string]bool)
+			c.bySense[id] = keys
+		}
+		keys[e.Key] = true
+	}
+}
+
+func (c *LRU) unlinkSenses(e Entry) {
+	for _, id := range e.SenseIDs {
+		delete(c.bySense[id], e.Key)
+		if len(c.bySense[id]) == 0 {
+			delete(c.bySense, id)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package disambig
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLRUEvictAndInvalidate(t *testing.T) {
+	ctx := context.Background()
+	backend := NewLRU(100, nil) // 用纯内存 LRU 充当持久层
+	c := NewLRU(2, backend)
+
+	c.Put(ctx, []Entry{
+		{Key: "a", Choice: 1, SenseIDs: []string{"s1", "s2"}},
+		{Key: "b", Choice: 0, SenseIDs: []string{"s3", "s4"}},
+		{Key: "c", Choice: 2, SenseIDs: []string{"s2", "s5"}},
+	})
+	if c.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", c.Len())
+	}
+
+	// a 已被淘汰出内存，但仍能从后端取回并回填
+	got, _ := c.Get(ctx, []string{"a", "b", "x"})
+	if got["a"].Choice != 1 || got["b"].Choice != 0 {
+		t.Fatalf("Get = %v", got)
+	}
+	if _, ok := got["x"]; ok {
+		t.Fatal("unexpected hit for x")
+	}
+
+	// 修改 s2 后，a 与 c 在两层中都应失效，b 不受影响
+	c.InvalidateSenses(ctx, []string{"s2"})
+	got, _ = c.Get(ctx, []string{"a", "b", "c"})
+	if _, ok := got["a"]; ok {
+		t.Fatal("a should be invalidated")
+	}
+	if _, ok := got["c"]; ok {
+		t.Fatal("c should be invalidated")
+	}
+	if _, ok := got["b"]; !ok {
+		t.Fatal("b should survive")
+	}
+}
+
+func TestKeyDependsOnOptions(t *testing.T) {
+	k1 := Key("行く", "学校に行く", []string{"s1", "s2"})
+	k2 := Key("行く", "学校に行く", []string{"s2", "s1"})
+	k3 := Key("行く", "学校へ行く", []string{"s1", "s2"})
+	if k1 == k2 || k1 == k3 {
+		t.Fatal("keys should differ")
+	}
+	if k1 != Key("行く", "学校に行く", []string{"s1", "s2"}) {
+		t.Fatal("key should be stable")
+	}
+}
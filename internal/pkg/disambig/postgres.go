@@ -0,0 +1,91 @@
+package disambig
+
+import (
+	"context"
+
+	"dongwai_backend/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresStore 基于数据库表的持久化存储
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresStore 创建数据库存储 (表结构需已迁移)
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Get(ctx context.Context, keys []string) (map[string]Entry, error) {
+	result := make(map[string]Entry)
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	var rows []model.DisambigResult
+	if err := s.db.WithContext(ctx).Where("key IN ?", keys).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return result, nil
+	}
+
+	var links []model.DisambigResultSense
+	if err := s.db.WithContext(ctx).Where("key IN ?", keys).Find(&links).Error; err != nil {
+		return nil, err
+	}
+	senses := make(map[string][]string)
+	for _, l := range links {
+		senses[l.Key] = append(senses[l.Key], l.SenseID)
+	}
+
+	for _, r := range rows {
+		result[r.Key] = Entry{Key: r.Key, Choice: r.Choice, SenseIDs: senses[r.Key], PromptVersion: r.PromptVersion}
+	}
+	return result, nil
+}
+
+func (s *PostgresStore) Put(ctx context.Context, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var rows []model.DisambigResult
+	var links []model.DisambigResultSense
+	for _, e := range entries {
+		rows = append(rows, model.DisambigResult{Key: e.Key, Choice: e.Choice, PromptVersion: e.PromptVersion})
+		for _, id := range e.SenseIDs {
+			links = append(links, model.DisambigResultSense{Key: e.Key, SenseID: id})
+		}
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"choice", "prompt_version", "created_at"}),
+		}).Create(&rows).Error; err != nil {
+			return err
+		}
+		if len(links) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error
+	})
+}
+
+func (s *PostgresStore) InvalidateSenses(ctx context.Context, senseIDs []string) error {
+	if len(senseIDs) == 0 {
+		return nil
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		keys := tx.Model(&model.DisambigResultSense{}).Select("key").Where("sense_id IN ?", senseIDs)
+		if err := tx.Where("key IN (?)", keys).Delete(&model.DisambigResult{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("key IN (?)", keys).Delete(&model.DisambigResultSense{}).Error
+	})
+}
@@ -0,0 +1,52 @@
+package disambig
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Entry 一条消歧结果
+type Entry struct {
+	Key      string
+	Choice   int      // 候选释义下标
+	SenseIDs []string // 候选释义 ID (用于失效)
+
+	PromptVersion string // 产生该结果的提示词版本
+}
+
+// Store 消歧结果存储
+type Store interface {
+	// Get 批量查询，只返回命中的键 (包含候选释义 ID)
+	Get(ctx context.Context, keys []string) (map[string]Entry, error)
+	// Put 批量写入 (已存在的键会被覆盖)
+	Put(ctx context.Context, entries []Entry) error
+	// InvalidateSenses 删除所有以这些释义为候选的条目
+	InvalidateSenses(ctx context.Context, senseIDs []string) error
+}
+
+// Default 全局消歧缓存，未初始化时为 nil (不使用缓存)
+var Default Store
+
+// Key 计算缓存键：单词、上下文与候选释义 ID (按顺序) 的 SHA-256
+// 候选顺序决定了下标的含义，因此不排序
+func Key(text, context string, senseIDs []string) string {
+	h := sha256.New()
+	h.Write([]byte(text))
+	h.Write([]byte{0})
+	h.Write([]byte(context))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Join(senseIDs, ",")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DefaultCapacity 内存缓存的默认条目数
+const DefaultCapacity = 10000
+
+// Init 初始化全局消歧缓存：内存 LRU + 数据库持久层
+func Init(db *gorm.DB) {
+	Default = NewLRU(DefaultCapacity, NewPostgresStore(db))
+}
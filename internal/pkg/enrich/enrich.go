@@ -0,0 +1,242 @@
+// Package enrich 批量 AI 补全：按条件挑选词库中的单词入队，由后台 worker 逐个生成待审核的草稿。
+package enrich
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"dongwai_backend/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// 任务状态
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobCancelled = "cancelled"
+)
+
+// 条目状态
+const (
+	ItemPending   = "pending"
+	ItemRunning   = "running"
+	ItemDone      = "done"
+	ItemSkipped   = "skipped" // AI 结果中没有可补全的内容
+	ItemFailed    = "failed"
+	ItemCancelled = "cancelled"
+)
+
+// 草稿状态
+const (
+	DraftPending  = "pending"
+	DraftApproved = "approved"
+	DraftRejected = "rejected"
+)
+
+// 可筛选的缺失字段
+const (
+	MissingExamples = "examples"
+	MissingPitch    = "pitch"
+	MissingFurigana = "furigana"
+)
+
+// MaxJobItems 单个任务最多包含的单词数
+const MaxJobItems = 5000
+
+// MaxNewWords 一次最多为多少个词库中没有的单词生成草稿
+const MaxNewWords = 200
+
+var (
+	// ErrNoMatch 没有符合条件的单词
+	ErrNoMatch = errors.New("没有符合条件的单词")
+	// ErrJobFinished 任务不存在或已结束
+	ErrJobFinished = errors.New("任务不存在或已结束")
+)
+
+// Filter 挑选单词的条件，各条件之间为 "且"
+// 已有待审核草稿、或正在其他任务中排队的单词会被排除
+type Filter struct {
+	Levels       []string `json:"levels,omitempty"`        // 任一释义属于这些等级
+	Missing      []string `json:"missing,omitempty"`       // 缺少其中任一字段 (没有释义的单词也算)
+	VocabularyID string   `json:"vocabulary_id,omitempty"` // 属于某本词书
+	VocabIDs     []string `json:"vocab_ids,omitempty"`     // 指定单词
+	Limit        int      `json:"limit,omitempty"`         // 最多选取多少个 (默认且最多 MaxJobItems)
+}
+
+// Validate 检查并补全默认值
+func (f *Filter) Validate() error {
+	for _, m := range f.Missing {
+		switch m {
+		case MissingExamples, MissingPitch, MissingFurigana:
+		default:
+			return fmt.Errorf("未知的缺失字段: %s", m)
+		}
+	}
+	if len(f.Levels) == 0 && len(f.Missing) == 0 && f.VocabularyID == "" && len(f.VocabIDs) == 0 {
+		return errors.New("至少需要一个筛选条件 (levels / missing / vocabulary_id / vocab_ids)")
+	}
+	if f.Limit <= 0 || f.Limit > MaxJobItems {
+		f.Limit = MaxJobItems
+	}
+	return nil
+}
+
+// Select 按条件挑选单词 (只取 id, kanji)
+func Select(db *gorm.DB, f Filter) ([]model.Vocab, error) {
+	q := db.Model(&model.Vocab{}).Select("id, kanji")
+
+	if len(f.Levels) > 0 {
+		q = q.Where("EXISTS (SELECT 1 FROM vocab_senses s WHERE s.vocab_id = vocabs.id AND s.level IN ?)", f.Levels)
+	}
+	if len(f.Missing) > 0 {
+		conds := []string{"NOT EXISTS (SELECT 1 FROM vocab_senses s WHERE s.vocab_id = vocabs.id)"}
+		for _, m := range f.Missing {
+			switch m {
+			case MissingExamples:
+				conds = append(conds, "EXISTS (SELECT 1 FROM vocab_senses s WHERE s.vocab_id = vocabs.id AND NOT EXISTS (SELECT 1 FROM sense_examples e WHERE e.sense_id = s.id))")
+			case MissingPitch:
+				conds = append(conds, "EXISTS (SELECT 1 FROM vocab_senses s WHERE s.vocab_id = vocabs.id AND COALESCE(s.pitch, '') = '')")
+			case MissingFurigana:
+				conds = append(conds, "EXISTS (SELECT 1 FROM vocab_senses s WHERE s.vocab_id = vocabs.id AND (s.furigana IS NULL OR s.furigana IN ('null'::jsonb, '[]'::jsonb)))")
+			}
+		}
+		or := db.Where(conds[0])
+		for _, c := range conds[1:] {
+			or = or.Or(c)
+		}
+		q = q.Where(or)
+	}
+	if f.VocabularyID != "" {
+		q = q.Where("id IN (SELECT vocab_id FROM vocabulary_words WHERE vocabulary_id = ?)", f.VocabularyID)
+	}
+	if len(f.VocabIDs) > 0 {
+		q = q.Where("id IN ?", f.VocabIDs)
+	}
+
+	// 排除已有待审核草稿、或已在队列中的单词
+	q = q.Where("NOT EXISTS (SELECT 1 FROM word_drafts d WHERE d.vocab_id = vocabs.id AND d.status = ?)", DraftPending).
+		Where("NOT EXISTS (SELECT 1 FROM enrich_items i WHERE i.vocab_id = vocabs.id AND i.status IN ?)", []string{ItemPending, ItemRunning})
+
+	var vocabs []model.Vocab
+	err := q.Order("id").Limit(f.Limit).Find(&vocabs).Error
+	return vocabs, err
+}
+
+// CreateJob 挑选单词并创建任务 (任务与条目在同一事务中写入)
+func CreateJob(db *gorm.DB, f Filter, userID string) (*model.EnrichJob, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	vocabs, err := Select(db, f)
+	if err != nil {
+		return nil, err
+	}
+	if len(vocabs) == 0 {
+		return nil, ErrNoMatch
+	}
+
+	filter, _ := json.Marshal(f)
+	now := time.Now()
+	job := &model.EnrichJob{
+		ID:        uuid.New().String(),
+		Status:    JobPending,
+		Filter:    datatypes.JSON(filter),
+		Total:     len(vocabs),
+		CreatedBy: userID,
+	}
+	items := make([]model.EnrichItem, 0, len(vocabs))
+	for _, v := range vocabs {
+		items = append(items, model.EnrichItem{
+			ID:        uuid.New().String(),
+			JobID:     job.ID,
+			VocabID:   v.ID,
+			Kanji:     v.Kanji,
+			Status:    ItemPending,
+			NextRunAt: now,
+		})
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(items, 500).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// NewWordsFilter 新词任务的记录 (写入 EnrichJob.Filter)
+type NewWordsFilter struct {
+	NewWords     []string `json:"new_words"`
+	VocabularyID string   `json:"vocabulary_id,omitempty"` // 审核通过后加入的词书
+}
+
+// CreateNewWordJob 为词库中没有的单词创建任务：每个单词从零生成一份草稿，
+// 审核通过后新建单词，并在 vocabularyID 非空时加入该词书
+func CreateNewWordJob(db *gorm.DB, words []string, vocabularyID, userID string) (*model.EnrichJob, error) {
+	if len(words) == 0 {
+		return nil, ErrNoMatch
+	}
+	if len(words) > MaxNewWords {
+		return nil, fmt.Errorf("一次最多生成 %d 个新词", MaxNewWords)
+	}
+
+	filter, _ := json.Marshal(NewWordsFilter{NewWords: words, VocabularyID: vocabularyID})
+	now := time.Now()
+	job := &model.EnrichJob{
+		ID:        uuid.New().String(),
+		Status:    JobPending,
+		Filter:    datatypes.JSON(filter),
+		Total:     len(words),
+		CreatedBy: userID,
+	}
+	items := make([]model.EnrichItem, 0, len(words))
+	for _, w := range words {
+		items = append(items, model.EnrichItem{
+			ID:           uuid.New().String(),
+			JobID:        job.ID,
+			Kanji:        w,
+			Status:       ItemPending,
+			NextRunAt:    now,
+			VocabularyID: vocabularyID,
+		})
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(items, 500).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Cancel 取消任务：尚未开始的条目不再执行，正在执行的条目照常完成
+func Cancel(db *gorm.DB, jobID string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.EnrichJob{}).
+			Where("id = ? AND status IN ?", jobID, []string{JobPending, JobRunning}).
+			Updates(map[string]any{"status": JobCancelled, "finished_at": time.Now()})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrJobFinished
+		}
+		return tx.Model(&model.EnrichItem{}).
+			Where("job_id = ? AND status = ?", jobID, ItemPending).
+			Update("status", ItemCancelled).Error
+	})
+}
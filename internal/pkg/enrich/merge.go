@@ -0,0 +1,118 @@
+package enrich
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"dongwai_backend/internal/dto"
+	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/ai"
+)
+
+// Merge 把 AI 生成的结果合并进已有单词，只填补空缺的字段，不改动已有内容
+// 已有释义按读音与生成的释义配对；单词没有任何释义时直接采用生成的全部释义。
+// 返回合并后的完整单词 (已有释义保留 ID，新增释义 ID 为空) 与补全的字段列表。
+func Merge(vocab model.Vocab, gen *ai.GeneratedWordData) (dto.WordDTO, []string) {
+	word := dto.ToWordDTO(vocab)
+	var changes []string
+
+	if len(word.Senses) == 0 {
+		for i, s := range gen.Senses {
+			word.Senses = append(word.Senses, generatedSense(s))
+			changes = append(changes, fmt.Sprintf("senses[%d]", i))
+		}
+		word.IsMulti = len(word.Senses) > 1
+		return word, changes
+	}
+
+	used := make([]bool, len(gen.Senses))
+	for i := range word.Senses {
+		s := &word.Senses[i]
+		j := matchSense(s, gen.Senses, used)
+		if j < 0 {
+			continue
+		}
+		used[j] = true
+		g := gen.Senses[j]
+		field := func(name string) { changes = append(changes, fmt.Sprintf("senses[%d].%s", i, name)) }
+
+		if s.Pitch == "" && g.Pitch != "" {
+			s.Pitch = g.Pitch
+			field("pitch")
+		}
+		if isEmptyJSON(s.Furigana) && len(g.Furigana) > 0 {
+			s.Furigana = g.Furigana
+			field("furigana")
+		}
+		if s.Pos == "" && g.Pos != "" {
+			s.Pos = g.Pos
+			field("pos")
+		}
+		if s.Level == "" && g.Level != "" {
+			s.Level = g.Level
+			field("level")
+		}
+		if s.Def == "" && g.Def != "" {
+			s.Def = g.Def
+			field("def")
+		}
+		if len(s.Examples) == 0 && len(g.Examples) > 0 {
+			s.Examples = generatedExamples(g.Examples)
+			field("examples")
+		}
+	}
+	return word, changes
+}
+
+// matchSense 为已有释义挑选读音相同、尚未使用的生成释义；读音相同的有多个时优先词性也相同的
+func matchSense(s *dto.SenseDTO, gen []ai.GeneratedSense, used []bool) int {
+	best := -1
+	for j, g := range gen {
+		if used[j] || g.Reading != s.Reading {
+			continue
+		}
+		if s.Pos != "" && g.Pos == s.Pos {
+			return j
+		}
+		if best < 0 {
+			best = j
+		}
+	}
+	return best
+}
+
+func generatedSense(g ai.GeneratedSense) dto.SenseDTO {
+	return dto.SenseDTO{
+		Level:    g.Level,
+		Reading:  g.Reading,
+		Pos:      g.Pos,
+		Def:      g.Def,
+		Pitch:    g.Pitch,
+		Furigana: g.Furigana,
+		Examples: generatedExamples(g.Examples),
+	}
+}
+
+func generatedExamples(examples []ai.GeneratedExample) []dto.ExampleDTO {
+	out := make([]dto.ExampleDTO, 0, len(examples))
+	for _, ex := range examples {
+		out = append(out, dto.ExampleDTO{Kanji: ex.Kanji, Def: ex.Def, Furigana: ex.Furigana})
+	}
+	return out
+}
+
+// isEmptyJSON 振假名字段为空 (nil、null、[] 或空字符串)
+func isEmptyJSON(v any) bool {
+	if v == nil {
+		return true
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return true
+	}
+	switch string(raw) {
+	case "null", "[]", `""`:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package enrich
+
+import (
+	"reflect"
+	"testing"
+
+	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/ai"
+
+	"gorm.io/datatypes"
+)
+
+func generated() *ai.GeneratedWordData {
+	return &ai.GeneratedWordData{
+		Kanji: "掛ける",
+		Senses: []ai.GeneratedSense{
+			{Level: "N4", Reading: "かける", Pos: "动词", Pitch: "②", Def: "悬挂",
+				Furigana: [][]string{{"掛", "か"}, {"ける", ""}},
+				Examples: []ai.GeneratedExample{{Kanji: "絵を掛ける", Def: "挂画"}}},
+			{Level: "N4", Reading: "かける", Pos: "动词", Pitch: "②", Def: "打电话"},
+		},
+	}
+}
+
+func TestMergeFillsOnlyMissingFields(t *testing.T) {
+	vocab := model.Vocab{
+		ID:    "w_1",
+		Kanji: "掛ける",
+		Senses: []model.VocabSense{{
+			ID: "s_1", Reading: "かける", Def: "挂", Level: "N3",
+			Furigana: datatypes.JSON("null"),
+		}},
+	}
+
+	word, changes := Merge(vocab, generated())
+
+	want := []string{"senses[0].pitch", "senses[0].furigana", "senses[0].pos", "senses[0].examples"}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("changes = %v, want %v", changes, want)
+	}
+	s := word.Senses[0]
+	if s.ID != "s_1" || s.Def != "挂" || s.Level != "N3" {
+		t.Errorf("existing fields overwritten: %+v", s)
+	}
+	if s.Pitch != "②" || len(s.Examples) != 1 {
+		t.Errorf("missing fields not filled: %+v", s)
+	}
+	if len(word.Senses) != 1 {
+		t.Errorf("unmatched generated senses must not be added, got %d senses", len(word.Senses))
+	}
+}
+
+func TestMergeBareWord(t *testing.T) {
+	word, changes := Merge(model.Vocab{ID: "w_1", Kanji: "掛ける"}, generated())
+	if len(word.Senses) != 2 || !word.IsMulti || len(changes) != 2 {
+		t.Fatalf("bare word should take all generated senses: %+v %v", word, changes)
+	}
+	if word.Senses[0].ID != "" {
+		t.Errorf("new sense should have no ID, got %q", word.Senses[0].ID)
+	}
+}
+
+func TestMergeNothingToFill(t *testing.T) {
+	vocab := model.Vocab{Senses: []model.VocabSense{{
+		ID: "s_1", Reading: "かける", Pos: "动词", Pitch: "②", Def: "挂", Level: "N4",
+		Furigana: datatypes.JSON(`[["掛","か"]]`),
+		Examples: []model.SenseExample{{Kanji: "絵を掛ける"}},
+	}}}
+	if _, changes := Merge(vocab, generated()); len(changes) != 0 {
+		t.Errorf("changes = %v, want none", changes)
+	}
+}
+
+func TestFilterValidate(t *testing.T) {
+	if err := (&Filter{}).Validate(); err == nil {
+		t.Error("empty filter should be rejected")
+	}
+	if err := (&Filter{Missing: []string{"audio"}}).Validate(); err == nil {
+		t.Error("unknown missing field should be rejected")
+	}
+	f := Filter{Levels: []string{"N1"}, Limit: MaxJobItems + 1}
+	if err := f.Validate(); err != nil || f.Limit != MaxJobItems {
+		t.Errorf("Validate() = %v, limit %d", err, f.Limit)
+	}
+}
@@ -0,0 +1,281 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/ai"
+	"dongwai_backend/internal/pkg/usage"
+	"dongwai_backend/internal/pkg/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Config worker 参数，零值字段使用默认值
+type Config struct {
+	Workers      int           // 并发的 worker 数 (默认 2)
+	MaxAttempts  int           // 每个单词最多尝试次数 (默认 3)
+	RetryDelay   time.Duration // 首次重试前的等待，之后翻倍 (默认 1 分钟)
+	PollInterval time.Duration // 队列为空时的轮询间隔 (默认 5 秒)
+	Lease        time.Duration // 执行中的条目超过该时间视为 worker 已退出，可被重新领取 (默认 10 分钟)
+	ItemTimeout  time.Duration // 单个单词的处理超时 (默认 3 分钟)
+}
+
+func (c Config) withDefaults() Config {
+	if c.Workers <= 0 {
+		c.Workers = 2
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 3
+	}
+	if c.RetryDelay <= 0 {
+		c.RetryDelay = time.Minute
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = 5 * time.Second
+	}
+	if c.Lease <= 0 {
+		c.Lease = 10 * time.Minute
+	}
+	if c.ItemTimeout <= 0 {
+		c.ItemTimeout = 3 * time.Minute
+	}
+	return c
+}
+
+// Runner 从数据库队列中领取条目并执行
+// 领取使用 SELECT ... FOR UPDATE SKIP LOCKED，多个实例可以同时运行。
+type Runner struct {
+	db       *gorm.DB
+	provider ai.Provider
+	cfg      Config
+	wake     chan struct{}
+}
+
+// NewRunner 创建 Runner (调用 Start 后开始工作)
+func NewRunner(db *gorm.DB, provider ai.Provider, cfg Config) *Runner {
+	return &Runner{db: db, provider: provider, cfg: cfg.withDefaults(), wake: make(chan struct{}, 1)}
+}
+
+// Start 启动 worker，ctx 取消后退出
+func (r *Runner) Start(ctx context.Context) {
+	for i := 0; i < r.cfg.Workers; i++ {
+		go r.loop(ctx)
+	}
+}
+
+// Notify 有新任务时唤醒空闲的 worker (r 为 nil 时忽略)
+func (r *Runner) Notify() {
+	if r == nil {
+		return
+	}
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Runner) loop(ctx context.Context) {
+	for {
+		item, err := r.claim(ctx)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("领取补全任务失败: %v", err)
+		}
+		if item != nil {
+			r.process(ctx, item)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+		case <-time.After(r.cfg.PollInterval):
+		}
+	}
+}
+
+// errLeaseLost 条目的租约已过期并被其他 worker 重新领取，本次结果作废
+var errLeaseLost = errors.New("租约已失效")
+
+// claim 领取一个到期的条目 (包括租约已过期的执行中条目)
+// locked_at 即本次租约的标识，结束条目时据此确认租约仍属于自己
+func (r *Runner) claim(ctx context.Context) (*model.EnrichItem, error) {
+	var item model.EnrichItem
+	// 截断到数据库时间戳的精度 (微秒)，之后才能按 locked_at 精确匹配
+	now := time.Now().Truncate(time.Microsecond)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND next_run_at <= ?) OR (status = ? AND locked_at < ?)",
+				ItemPending, now, ItemRunning, now.Add(-r.cfg.Lease)).
+			Order("next_run_at").
+			First(&item).Error
+		if err != nil {
+			return err
+		}
+
+		item.Status = ItemRunning
+		item.Attempts++
+		item.LockedAt = &now
+		if err := tx.Model(&item).Updates(map[string]any{
+			"status":    item.Status,
+			"attempts":  item.Attempts,
+			"locked_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.EnrichJob{}).
+			Where("id = ? AND status = ?", item.JobID, JobPending).
+			Updates(map[string]any{"status": JobRunning, "started_at": now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// process 补全一个单词并写入草稿；可重试的失败会推迟后重新排队
+func (r *Runner) process(ctx context.Context, item *model.EnrichItem) {
+	var job model.EnrichJob
+	if err := r.db.WithContext(ctx).First(&job, "id = ?", item.JobID).Error; err != nil {
+		r.fail(ctx, item, err, false)
+		return
+	}
+	if job.Status == JobCancelled {
+		r.finish(ctx, item, ItemCancelled, nil, "")
+		return
+	}
+
+	// 新词：以空单词为基础，采用生成的全部释义
+	vocab := model.Vocab{Kanji: item.Kanji}
+	if item.VocabID != "" {
+		err := r.db.WithContext(ctx).Preload("Senses").Preload("Senses.Examples").First(&vocab, "id = ?", item.VocabID).Error
+		if err != nil {
+			r.fail(ctx, item, errors.New("单词不存在"), false)
+			return
+		}
+	}
+
+	itemCtx, cancel := context.WithTimeout(usage.WithUser(ctx, job.CreatedBy), r.cfg.ItemTimeout)
+	defer cancel()
+	gen, warnings, err := ai.GenerateWordInfo(itemCtx, r.provider, vocab.Kanji)
+	if ctx.Err() != nil {
+		// 服务正在退出：保持执行中状态，租约过期后由其他 worker 重新领取
+		return
+	}
+	if err != nil {
+		r.fail(ctx, item, err, retryable(err))
+		return
+	}
+
+	word, changes := Merge(vocab, gen)
+	if len(changes) == 0 {
+		if item.VocabID == "" {
+			r.finish(ctx, item, ItemFailed, nil, "AI 没有生成任何释义")
+			return
+		}
+		r.finish(ctx, item, ItemSkipped, nil, "")
+		return
+	}
+
+	draft := &model.WordDraft{
+		ID:            uuid.New().String(),
+		VocabID:       vocab.ID,
+		Kanji:         vocab.Kanji,
+		VocabularyID:  item.VocabularyID,
+		JobID:         job.ID,
+		Status:        DraftPending,
+		Data:          datatypes.JSON(utils.ToJSON(word)),
+		Changes:       datatypes.JSON(utils.ToJSON(changes)),
+		Warnings:      datatypes.JSON(utils.ToJSON(warnings)),
+		PromptVersion: gen.PromptVersion,
+		BaseUpdatedAt: vocab.UpdataAt,
+		CreatedBy:     job.CreatedBy,
+	}
+	r.finish(ctx, item, ItemDone, draft, "")
+}
+
+// retryable 值得稍后重试的错误：AI 服务的临时故障与熔断
+func retryable(err error) bool {
+	return ai.Classify(err) == ai.ErrorRetryable || errors.Is(err, ai.ErrCircuitOpen)
+}
+
+// fail 记录失败；可重试且未超过次数时推迟后重新排队
+func (r *Runner) fail(ctx context.Context, item *model.EnrichItem, err error, retry bool) {
+	if retry && item.Attempts < r.cfg.MaxAttempts {
+		// 租约已被他人接手时什么也不做
+		if err := r.db.WithContext(ctx).Model(item).Scopes(leased(item)).Updates(map[string]any{
+			"status":      ItemPending,
+			"error":       err.Error(),
+			"next_run_at": time.Now().Add(r.retryDelay(item.Attempts)),
+			"locked_at":   nil,
+		}).Error; err != nil {
+			log.Printf("补全任务重新排队失败: %v", err)
+		}
+		return
+	}
+	r.finish(ctx, item, ItemFailed, nil, err.Error())
+}
+
+// retryDelay 第 attempt 次尝试失败后的等待：RetryDelay 起每次翻倍，另加至多一半的随机抖动
+func (r *Runner) retryDelay(attempt int) time.Duration {
+	delay := r.cfg.RetryDelay << (attempt - 1)
+	return delay + time.Duration(rand.Int64N(int64(delay)/2+1))
+}
+
+// leased 只匹配仍由本次租约持有的条目 (执行中且 locked_at 未变)
+func leased(item *model.EnrichItem) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("status = ? AND locked_at = ?", ItemRunning, item.LockedAt)
+	}
+}
+
+// finish 结束条目：更新条目状态、写入草稿 (如有) 与任务进度，并在全部结束时完成任务
+// 租约已被其他 worker 接手时放弃本次结果，不写草稿也不计入进度 (由接手的 worker 负责)
+func (r *Runner) finish(ctx context.Context, item *model.EnrichItem, status string, draft *model.WordDraft, errMsg string) {
+	counter := map[string]string{ItemDone: "succeeded", ItemSkipped: "skipped", ItemFailed: "failed"}[status]
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		updates := map[string]any{"status": status, "error": errMsg, "locked_at": nil}
+		if draft != nil {
+			updates["draft_id"] = draft.ID
+		}
+		res := tx.Model(item).Scopes(leased(item)).Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errLeaseLost
+		}
+		if draft != nil {
+			if err := tx.Create(draft).Error; err != nil {
+				return err
+			}
+		}
+
+		progress := map[string]any{"processed": gorm.Expr("processed + 1")}
+		if counter != "" {
+			progress[counter] = gorm.Expr(counter + " + 1")
+		}
+		if err := tx.Model(&model.EnrichJob{}).Where("id = ?", item.JobID).Updates(progress).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.EnrichJob{}).
+			Where("id = ? AND status = ? AND processed >= total", item.JobID, JobRunning).
+			Updates(map[string]any{"status": JobDone, "finished_at": time.Now()}).Error
+	})
+	if errors.Is(err, errLeaseLost) {
+		log.Printf("补全结果已作废 (%s): 条目已被重新领取", item.Kanji)
+		return
+	}
+	if err != nil {
+		log.Printf("保存补全结果失败 (%s): %v", item.Kanji, err)
+	}
+}
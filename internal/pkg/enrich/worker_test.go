@@ -0,0 +1,305 @@
+package enrich
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"dongwai_backend/internal/model"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// --- 测试用的数据库驱动 ---
+// 查询按 SQL 片段返回预置的行；写操作的影响行数由 affected 决定；所有语句都会记录下来。
+
+type fakeTable struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+type fakeStmtLog struct {
+	query string
+	args  []driver.Value
+}
+
+type fakeDB struct {
+	mu       sync.Mutex
+	queries  map[string]fakeTable // SQL 片段 -> 结果
+	affected func(query string, args []driver.Value) int64
+	log      []fakeStmtLog
+}
+
+func (f *fakeDB) stmts(match string) []fakeStmtLog {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []fakeStmtLog
+	for _, l := range f.log {
+		if strings.Contains(l.query, match) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+var (
+	fakeDBsMu    sync.Mutex
+	fakeDBs      = map[string]*fakeDB{}
+	registerOnce sync.Once
+)
+
+func newFakeDB(t *testing.T, queries map[string]fakeTable) (*gorm.DB, *fakeDB) {
+	t.Helper()
+	registerOnce.Do(func() { sql.Register("enrich_fakedb", fakeDriver{}) })
+
+	fake := &fakeDB{queries: queries, affected: func(string, []driver.Value) int64 { return 1 }}
+	fakeDBsMu.Lock()
+	fakeDBs[t.Name()] = fake
+	fakeDBsMu.Unlock()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DriverName: "enrich_fakedb", DSN: t.Name()}), &gorm.Config{
+		Logger:                 logger.Discard,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("open fake db: %v", err)
+	}
+	return db, fake
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+	return &fakeConn{db: fakeDBs[dsn]}, nil
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.record(query, args)
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	for match, table := range c.db.queries {
+		if strings.Contains(query, match) {
+			return &fakeRows{table: table}, nil
+		}
+	}
+	return &fakeRows{}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	values := c.record(query, args)
+	return driver.RowsAffected(c.db.affected(query, values)), nil
+}
+
+func (c *fakeConn) record(query string, args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+	c.db.mu.Lock()
+	c.db.log = append(c.db.log, fakeStmtLog{query: query, args: values})
+	c.db.mu.Unlock()
+	return values
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	table fakeTable
+	pos   int
+}
+
+func (r *fakeRows) Columns() []string { return r.table.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.table.rows) {
+		return io.EOF
+	}
+	copy(dest, r.table.rows[r.pos])
+	r.pos++
+	return nil
+}
+
+// --- worker ---
+
+var itemColumns = []string{"id", "job_id", "kanji", "status", "attempts", "locked_at"}
+
+// timeArgs 语句参数中的时间
+func timeArgs(args []driver.Value) []time.Time {
+	var out []time.Time
+	for _, a := range args {
+		if t, ok := a.(time.Time); ok {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func TestClaimLeasesItem(t *testing.T) {
+	db, fake := newFakeDB(t, map[string]fakeTable{
+		`FROM "enrich_items"`: {columns: itemColumns, rows: [][]driver.Value{{"i1", "j1", "猫", ItemPending, int64(0), nil}}},
+	})
+	r := NewRunner(db, nil, Config{Lease: 10 * time.Minute})
+
+	before := time.Now()
+	item, err := r.claim(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Status != ItemRunning || item.Attempts != 1 || item.LockedAt == nil {
+		t.Fatalf("item = %+v", item)
+	}
+	if !item.LockedAt.Equal(item.LockedAt.Truncate(time.Microsecond)) {
+		t.Errorf("lease %v is finer than the database precision", item.LockedAt)
+	}
+
+	// 待执行且到期的条目，或租约已过期的执行中条目；跳过其他 worker 锁住的行
+	sel := fake.stmts(`SELECT * FROM "enrich_items"`)
+	if len(sel) != 1 || !strings.Contains(sel[0].query, "FOR UPDATE SKIP LOCKED") {
+		t.Fatalf("select = %+v", sel)
+	}
+	times := timeArgs(sel[0].args)
+	if len(times) != 2 || !times[0].Equal(*item.LockedAt) || !times[1].Equal(item.LockedAt.Add(-10*time.Minute)) {
+		t.Errorf("select times = %v, lease = %v", times, item.LockedAt)
+	}
+	if item.LockedAt.Before(before.Truncate(time.Microsecond)) {
+		t.Errorf("lease = %v, claimed at %v", item.LockedAt, before)
+	}
+
+	upd := fake.stmts(`UPDATE "enrich_items"`)
+	if len(upd) != 1 {
+		t.Fatalf("updates = %+v", upd)
+	}
+	if times := timeArgs(upd[0].args); len(times) == 0 || !containsTime(times, *item.LockedAt) {
+		t.Errorf("update does not store the lease: %v", upd[0].args)
+	}
+}
+
+func containsTime(times []time.Time, want time.Time) bool {
+	for _, t := range times {
+		if t.Equal(want) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFinishAfterLeaseReclaimed(t *testing.T) {
+	staleLease := time.Now().Add(-15 * time.Minute).Truncate(time.Microsecond)
+	db, fake := newFakeDB(t, map[string]fakeTable{
+		`FROM "enrich_items"`: {columns: itemColumns, rows: [][]driver.Value{{"i1", "j1", "猫", ItemRunning, int64(1), staleLease}}},
+	})
+	r := NewRunner(db, nil, Config{Lease: 10 * time.Minute})
+
+	// 慢 worker 手上的条目，租约过期后被重新领取
+	stale := &model.EnrichItem{ID: "i1", JobID: "j1", Kanji: "猫", Status: ItemRunning, Attempts: 1, LockedAt: &staleLease}
+	fresh, err := r.claim(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.Attempts != 2 || fresh.LockedAt.Equal(staleLease) {
+		t.Fatalf("reclaimed item = %+v", fresh)
+	}
+
+	// 模拟数据库：只有带着当前租约的条件更新才能匹配到条目
+	var mu sync.Mutex
+	holder := *fresh.LockedAt
+	fake.affected = func(query string, args []driver.Value) int64 {
+		if !strings.HasPrefix(query, `UPDATE "enrich_items"`) {
+			return 1
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if containsTime(timeArgs(args), holder) {
+			return 1
+		}
+		return 0
+	}
+
+	claimed := len(fake.stmts(`UPDATE "enrich_jobs"`)) // 领取时把任务标记为执行中
+	r.finish(context.Background(), stale, ItemDone, &model.WordDraft{ID: "draft_stale", JobID: "j1"}, "")
+	if n := len(fake.stmts(`INSERT INTO "word_drafts"`)); n != 0 {
+		t.Errorf("stale worker created %d drafts", n)
+	}
+	if n := len(fake.stmts(`UPDATE "enrich_jobs"`)) - claimed; n != 0 {
+		t.Errorf("stale worker updated job progress %d times", n)
+	}
+	// 条件更新带上了原租约
+	upd := fake.stmts(`UPDATE "enrich_items"`)
+	last := upd[len(upd)-1]
+	if !strings.Contains(last.query, "locked_at =") || !containsTime(timeArgs(last.args), staleLease) {
+		t.Errorf("finish is not guarded by the lease: %s %v", last.query, last.args)
+	}
+
+	r.finish(context.Background(), fresh, ItemDone, &model.WordDraft{ID: "draft_fresh", JobID: "j1"}, "")
+	drafts := fake.stmts(`INSERT INTO "word_drafts"`)
+	if len(drafts) != 1 || drafts[0].args[0] != "draft_fresh" {
+		t.Errorf("drafts = %+v", drafts)
+	}
+	// 进度 +1，然后检查任务是否全部结束
+	if n := len(fake.stmts(`UPDATE "enrich_jobs"`)) - claimed; n != 2 {
+		t.Errorf("job updates = %d, want 2", n)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	r := NewRunner(nil, nil, Config{RetryDelay: time.Minute})
+	for attempt, base := range map[int]time.Duration{1: time.Minute, 2: 2 * time.Minute, 3: 4 * time.Minute} {
+		for i := 0; i < 50; i++ {
+			if d := r.retryDelay(attempt); d < base || d > base+base/2 {
+				t.Fatalf("attempt %d: delay %v outside [%v, %v]", attempt, d, base, base+base/2)
+			}
+		}
+	}
+}
+
+func TestFailRequeuesUntilMaxAttempts(t *testing.T) {
+	db, fake := newFakeDB(t, nil)
+	r := NewRunner(db, nil, Config{MaxAttempts: 3, RetryDelay: time.Minute})
+	lease := time.Now().Truncate(time.Microsecond)
+
+	item := &model.EnrichItem{ID: "i1", JobID: "j1", Kanji: "猫", Status: ItemRunning, Attempts: 2, LockedAt: &lease}
+	r.fail(context.Background(), item, io.ErrUnexpectedEOF, true)
+
+	upd := fake.stmts(`UPDATE "enrich_items"`)
+	if len(upd) != 1 || !strings.Contains(upd[0].query, "locked_at =") {
+		t.Fatalf("requeue = %+v", upd)
+	}
+	var nextRun time.Time
+	for _, tm := range timeArgs(upd[0].args) {
+		if tm.After(nextRun) {
+			nextRun = tm
+		}
+	}
+	if wait := time.Until(nextRun); wait < time.Minute+50*time.Second || wait > 3*time.Minute {
+		t.Errorf("next run in %v, want about 2-3 minutes", wait)
+	}
+	if n := len(fake.stmts(`UPDATE "enrich_jobs"`)); n != 0 {
+		t.Errorf("requeue touched job progress")
+	}
+
+	// 最后一次尝试失败后记为失败并计入进度
+	item.Attempts = 3
+	r.fail(context.Background(), item, io.ErrUnexpectedEOF, true)
+	jobs := fake.stmts(`UPDATE "enrich_jobs"`)
+	if len(jobs) == 0 || !strings.Contains(jobs[0].query, `"failed"=failed + 1`) {
+		t.Errorf("job progress = %+v", jobs)
+	}
+}
@@ -0,0 +1,98 @@
+// Package gloss 逐句翻译结果的缓存，以句子原文的哈希为键
+package gloss
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"dongwai_backend/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Entry 一个句子的翻译
+type Entry struct {
+	Hash          string
+	Text          string
+	Translation   string
+	Grammar       string
+	PromptVersion string
+}
+
+// Store 翻译缓存
+type Store interface {
+	// Get 批量查询，只返回命中的哈希
+	Get(ctx context.Context, hashes []string) (map[string]Entry, error)
+	// Put 批量写入 (已存在的哈希会被覆盖)
+	Put(ctx context.Context, entries []Entry) error
+}
+
+// Default 全局翻译缓存，未初始化时为 nil (不使用缓存)
+var Default Store
+
+// Hash 句子的缓存键：去掉首尾空白后的原文的 SHA-256
+func Hash(text string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(text)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Init 初始化全局翻译缓存 (数据库表)
+func Init(db *gorm.DB) {
+	Default = NewPostgresStore(db)
+}
+
+// PostgresStore 基于数据库表的存储
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresStore 创建数据库存储 (表结构需已迁移)
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Get(ctx context.Context, hashes []string) (map[string]Entry, error) {
+	result := make(map[string]Entry)
+	if len(hashes) == 0 {
+		return result, nil
+	}
+
+	var rows []model.SentenceGloss
+	if err := s.db.WithContext(ctx).Where("hash IN ?", hashes).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		result[r.Hash] = Entry{
+			Hash:          r.Hash,
+			Text:          r.Text,
+			Translation:   r.Translation,
+			Grammar:       r.Grammar,
+			PromptVersion: r.PromptVersion,
+		}
+	}
+	return result, nil
+}
+
+func (s *PostgresStore) Put(ctx context.Context, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	rows := make([]model.SentenceGloss, 0, len(entries))
+	for _, e := range entries {
+		rows = append(rows, model.SentenceGloss{
+			Hash:          e.Hash,
+			Text:          e.Text,
+			Translation:   e.Translation,
+			Grammar:       e.Grammar,
+			PromptVersion: e.PromptVersion,
+		})
+	}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"translation", "grammar", "prompt_version", "created_at"}),
+	}).Create(&rows).Error
+}
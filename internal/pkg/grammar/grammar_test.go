@@ -0,0 +1,85 @@
+package grammar
+
+import (
+	"reflect"
+	"testing"
+)
+
+// tokens 按 "|" 切分文本构造 Token (同一句子)，带 * 前缀的为单词
+func tokens(spec ...string) []Token {
+	var out []Token
+	for _, s := range spec {
+		t := Token{Text: s}
+		if len(s) > 0 && s[0] == '*' {
+			t = Token{Text: s[1:], IsWord: true, Pos: "名词"}
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+func mustMatch(t *testing.T, pattern string, toks []Token) []Match {
+	t.Helper()
+	p, err := Compile(pattern)
+	if err != nil {
+		t.Fatalf("Compile(%q): %v", pattern, err)
+	}
+	return NewMatcher([]Entry{{ID: "g1", Name: pattern, Pattern: p}}).Match(toks)
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, src := range []string{"", "〜", "<名词>", "a||b", "<名词"} {
+		if _, err := Compile(src); err == nil {
+			t.Errorf("Compile(%q) should fail", src)
+		}
+	}
+}
+
+func TestMatchAcrossTokens(t *testing.T) {
+	// 彼|が|*学生|わ|け|で|は|な|い
+	toks := tokens("彼", "が", "*学生", "な", "わ", "け", "で", "は", "な", "い")
+	got := mustMatch(t, "〜わけ では|じゃ ない", toks)
+	if len(got) != 1 {
+		t.Fatalf("matches = %+v", got)
+	}
+	want := []Span{{4, 6}, {6, 8}, {8, 10}}
+	if !reflect.DeepEqual(got[0].Spans, want) || got[0].TokenStart != 4 || got[0].TokenEnd != 10 {
+		t.Errorf("match = %+v, want spans %v", got[0], want)
+	}
+
+	if got := mustMatch(t, "わけじゃない", toks); len(got) != 0 {
+		t.Errorf("unexpected match: %+v", got)
+	}
+}
+
+func TestMatchGapAndPos(t *testing.T) {
+	toks := tokens("も", "し", "*雨", "が", "降", "っ", "た", "ら")
+	got := mustMatch(t, "もし 〜 たら", toks)
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Spans, []Span{{0, 2}, {6, 8}}) {
+		t.Fatalf("gap match = %+v", got)
+	}
+
+	got = mustMatch(t, "<名词> が", toks)
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Spans, []Span{{2, 3}, {3, 4}}) {
+		t.Fatalf("pos match = %+v", got)
+	}
+	if got := mustMatch(t, "<动词> が", toks); len(got) != 0 {
+		t.Errorf("pos should not match: %+v", got)
+	}
+}
+
+func TestMatchStaysInSentence(t *testing.T) {
+	toks := tokens("も", "し", "。", "た", "ら")
+	toks[3].Sentence, toks[4].Sentence = 1, 1
+	if got := mustMatch(t, "もし〜たら", toks); len(got) != 0 {
+		t.Errorf("match crossed sentence: %+v", got)
+	}
+}
+
+func TestMatchNonOverlapping(t *testing.T) {
+	toks := tokens("ば", "か", "り", "ば", "か", "り")
+	got := mustMatch(t, "〜ばかり", toks)
+	if len(got) != 2 || got[1].TokenStart != 3 {
+		t.Errorf("matches = %+v", got)
+	}
+}
@@ -0,0 +1,254 @@
+package grammar
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/textnorm"
+
+	"gorm.io/gorm"
+)
+
+// Token 匹配所需的 Token 信息 (对应分析结果中的 Token)
+type Token struct {
+	Text     string
+	IsWord   bool
+	Pos      string // 选中释义的词性
+	Sentence int
+}
+
+// Entry 一个可匹配的语法点
+type Entry struct {
+	ID      string
+	Name    string
+	Level   string
+	Meaning string
+	Pattern *Pattern
+}
+
+// Span Token 区间 [TokenStart, TokenEnd)
+type Span struct {
+	TokenStart int `json:"token_start"`
+	TokenEnd   int `json:"token_end"`
+}
+
+// Match 文章中的一处语法点
+type Match struct {
+	PatternID  string `json:"pattern_id"`
+	Name       string `json:"name"`
+	Level      string `json:"level"`
+	Meaning    string `json:"meaning"`
+	Sentence   int    `json:"sentence"`
+	TokenStart int    `json:"token_start"` // 整体范围 (包含间隔)
+	TokenEnd   int    `json:"token_end"`
+	Spans      []Span `json:"spans"` // 规则中每一段 (不含间隔) 对应的 Token 区间
+}
+
+// Matcher 一组语法点的匹配器，创建后只读
+type Matcher struct {
+	entries []Entry
+}
+
+// NewMatcher 创建匹配器
+func NewMatcher(entries []Entry) *Matcher {
+	return &Matcher{entries: entries}
+}
+
+// Len 语法点数量
+func (m *Matcher) Len() int {
+	return len(m.entries)
+}
+
+var current atomic.Pointer[Matcher]
+
+// Default 当前生效的匹配器 (未加载时为空)
+func Default() *Matcher {
+	if m := current.Load(); m != nil {
+		return m
+	}
+	return &Matcher{}
+}
+
+// SetDefault 替换全局匹配器
+func SetDefault(m *Matcher) {
+	current.Store(m)
+}
+
+// Reload 从数据库加载全部语法点并替换全局匹配器；规则无法解析的语法点会被跳过
+func Reload(db *gorm.DB) error {
+	var patterns []model.GrammarPattern
+	if err := db.Select("id, name, pattern, level, meaning").Find(&patterns).Error; err != nil {
+		return err
+	}
+
+	entries := make([]Entry, 0, len(patterns))
+	for _, p := range patterns {
+		compiled, err := Compile(p.Pattern)
+		if err != nil {
+			log.Printf("跳过语法点 %s: %v", p.Name, err)
+			continue
+		}
+		entries = append(entries, Entry{ID: p.ID, Name: p.Name, Level: p.Level, Meaning: p.Meaning, Pattern: compiled})
+	}
+	SetDefault(NewMatcher(entries))
+	return nil
+}
+
+// Match 在 Token 序列中查找所有语法点，结果按位置排序
+// 匹配不跨越句子；同一语法点的多处命中互不重叠。
+func (m *Matcher) Match(tokens []Token) []Match {
+	var matches []Match
+	if len(m.entries) == 0 {
+		return matches
+	}
+
+	for start := 0; start < len(tokens); {
+		end := start + 1
+		for end < len(tokens) && tokens[end].Sentence == tokens[start].Sentence {
+			end++
+		}
+		buf := newSentenceBuf(tokens, start, end)
+		for i := range m.entries {
+			matches = append(matches, buf.find(&m.entries[i])...)
+		}
+		start = end
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].TokenStart != matches[j].TokenStart {
+			return matches[i].TokenStart < matches[j].TokenStart
+		}
+		return matches[i].TokenEnd > matches[j].TokenEnd
+	})
+	return matches
+}
+
+// sentenceBuf 一个句子的规范化文本，以及 rune 与 Token 的对应关系
+type sentenceBuf struct {
+	tokens   []Token
+	first    int    // 第一个 Token 的全局下标
+	runes    []rune // 规范化后的句子文本
+	tokOf    []int  // rune -> 句内 Token 下标
+	tokEnd   []int  // 句内 Token 下标 -> 结束 rune 位置
+	boundary []bool // 该 rune 位置是否为 Token 起点 (长度 len(runes)+1，末尾为 true)
+}
+
+func newSentenceBuf(tokens []Token, start, end int) *sentenceBuf {
+	b := &sentenceBuf{tokens: tokens, first: start}
+	for k := start; k < end; k++ {
+		text := []rune(textnorm.String(tokens[k].Text))
+		for range text {
+			b.tokOf = append(b.tokOf, k-start)
+		}
+		b.runes = append(b.runes, text...)
+		b.tokEnd = append(b.tokEnd, len(b.runes))
+	}
+
+	b.boundary = make([]bool, len(b.runes)+1)
+	b.boundary[0] = true
+	for _, e := range b.tokEnd {
+		b.boundary[e] = true
+	}
+	return b
+}
+
+type runeSpan struct{ start, end int }
+
+// find 在句子中查找一个语法点的全部 (互不重叠的) 命中
+func (b *sentenceBuf) find(e *Entry) []Match {
+	var out []Match
+	for pos := 0; pos < len(b.runes); pos++ {
+		if !b.boundary[pos] {
+			continue
+		}
+		spans, ok := b.match(e.Pattern.elems, pos, true, nil)
+		if !ok || len(spans) == 0 {
+			continue
+		}
+
+		m := Match{
+			PatternID: e.ID,
+			Name:      e.Name,
+			Level:     e.Level,
+			Meaning:   e.Meaning,
+			Sentence:  b.tokens[b.first].Sentence,
+		}
+		for _, sp := range spans {
+			m.Spans = append(m.Spans, Span{
+				TokenStart: b.first + b.tokOf[sp.start],
+				TokenEnd:   b.first + b.tokOf[sp.end-1] + 1,
+			})
+		}
+		m.TokenStart = m.Spans[0].TokenStart
+		m.TokenEnd = m.Spans[len(m.Spans)-1].TokenEnd
+		out = append(out, m)
+
+		pos = spans[len(spans)-1].end - 1
+	}
+	return out
+}
+
+// match 从 pos 开始匹配剩余的规则元素 (回溯)，needBoundary 表示当前位置必须是 Token 起点
+func (b *sentenceBuf) match(elems []element, pos int, needBoundary bool, spans []runeSpan) ([]runeSpan, bool) {
+	if len(elems) == 0 {
+		return spans, true
+	}
+	el, rest := elems[0], elems[1:]
+
+	switch el.kind {
+	case elemLiteral:
+		if needBoundary && !b.boundary[pos] {
+			return nil, false
+		}
+		for _, alt := range el.alts {
+			if hasPrefix(b.runes[pos:], alt) {
+				next := append(spans[:len(spans):len(spans)], runeSpan{pos, pos + len(alt)})
+				if out, ok := b.match(rest, pos+len(alt), false, next); ok {
+					return out, true
+				}
+			}
+		}
+
+	case elemPos:
+		if pos >= len(b.runes) || !b.boundary[pos] {
+			return nil, false
+		}
+		k := b.tokOf[pos]
+		tok := b.tokens[b.first+k]
+		if tok.IsWord && strings.Contains(tok.Pos, el.pos) {
+			next := append(spans[:len(spans):len(spans)], runeSpan{pos, b.tokEnd[k]})
+			return b.match(rest, b.tokEnd[k], false, next)
+		}
+
+	case elemGap:
+		// 从最短的间隔开始尝试，只能停在 Token 起点
+		skipped := 0
+		for q := pos; q <= len(b.runes) && skipped <= MaxGap; q++ {
+			if !b.boundary[q] {
+				continue
+			}
+			if out, ok := b.match(rest, q, true, spans); ok {
+				return out, true
+			}
+			if q > pos {
+				skipped++
+			}
+		}
+	}
+	return nil, false
+}
+
+func hasPrefix(s, prefix []rune) bool {
+	if len(prefix) > len(s) {
+		return false
+	}
+	for i, r := range prefix {
+		if s[i] != r {
+			return false
+		}
+	}
+	return true
+}
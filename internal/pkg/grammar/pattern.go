@@ -0,0 +1,128 @@
+// Package grammar 语法点匹配：在分词结果上查找可跨越多个 Token、中间允许间隔的语法结构。
+//
+// 匹配规则的写法:
+//
+//	〜 (或 ...)  间隔：跳过同一句子内的若干 Token (最多 MaxGap 个)；规则首尾的 〜 只是习惯写法，会被忽略
+//	空白        仅用于分隔，前后两部分必须紧邻
+//	a|b        候选：匹配其中任意一个
+//	<名词>      一个单词 Token，其选中释义的词性包含该文字
+//	其他文字     按字面匹配，可以跨越 Token 边界 (未登录的假名通常被切成单字)
+//
+// 例如 "〜わけ では|じゃ ない"、"<名词> にもかかわらず"、"もし 〜 たら|ば"。
+package grammar
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"dongwai_backend/internal/pkg/textnorm"
+)
+
+// MaxGap 间隔最多跳过的 Token 数
+const MaxGap = 20
+
+type elemKind int
+
+const (
+	elemLiteral elemKind = iota
+	elemPos
+	elemGap
+)
+
+type element struct {
+	kind elemKind
+	alts [][]rune // 字面候选 (已规范化)
+	pos  string   // 词性
+}
+
+// Pattern 编译后的匹配规则
+type Pattern struct {
+	source string
+	elems  []element
+}
+
+// String 原始规则
+func (p *Pattern) String() string { return p.source }
+
+// Compile 解析匹配规则
+func Compile(source string) (*Pattern, error) {
+	var elems []element
+	var lit strings.Builder
+
+	flush := func() error {
+		if lit.Len() == 0 {
+			return nil
+		}
+		var alts [][]rune
+		for _, alt := range strings.Split(lit.String(), "|") {
+			if alt == "" {
+				return fmt.Errorf("规则 %q 中有空的候选", source)
+			}
+			alts = append(alts, []rune(textnorm.String(alt)))
+		}
+		lit.Reset()
+		elems = append(elems, element{kind: elemLiteral, alts: alts})
+		return nil
+	}
+
+	runes := []rune(source)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		case r == '〜' || r == '～' || r == '~' || (r == '.' && strings.HasPrefix(string(runes[i:]), "...")):
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			if r == '.' {
+				i += 2
+			}
+			if len(elems) > 0 && elems[len(elems)-1].kind != elemGap {
+				elems = append(elems, element{kind: elemGap})
+			}
+		case r == '<':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			end := i + 1
+			for end < len(runes) && runes[end] != '>' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("规则 %q 中的 < 没有闭合", source)
+			}
+			pos := strings.TrimSpace(string(runes[i+1 : end]))
+			if pos == "" {
+				return nil, fmt.Errorf("规则 %q 中有空的词性", source)
+			}
+			elems = append(elems, element{kind: elemPos, pos: pos})
+			i = end
+		default:
+			lit.WriteRune(r)
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	// 去掉末尾的间隔 (开头的间隔在上面已被忽略)
+	for len(elems) > 0 && elems[len(elems)-1].kind == elemGap {
+		elems = elems[:len(elems)-1]
+	}
+
+	hasLiteral := false
+	for _, e := range elems {
+		if e.kind == elemLiteral {
+			hasLiteral = true
+		}
+	}
+	if !hasLiteral {
+		return nil, errors.New("匹配规则至少需要包含一段文字")
+	}
+	return &Pattern{source: source, elems: elems}, nil
+}
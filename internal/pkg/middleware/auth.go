@@ -19,16 +19,7 @@ func JWTAuth() gin.HandlerFunc {
 			return
 		}
 
-		// 支持 "Bearer <token>" 格式
-		parts := strings.SplitN(tokenHeader, " ", 2)
-		var tokenStr string
-		if len(parts) == 2 && parts[0] == "Bearer" {
-			tokenStr = parts[1]
-		} else {
-			tokenStr = tokenHeader
-		}
-
-		claims, err := auth.ParseToken(tokenStr)
+		claims, err := auth.ParseToken(bearerToken(tokenHeader))
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token 无效或已过期"})
 			c.Abort()
@@ -42,3 +33,40 @@ func JWTAuth() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalJWT 可选鉴权：携带有效 Token 时写入用户信息，否则按匿名用户放行
+func OptionalJWT() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tokenHeader := c.GetHeader("Authorization"); tokenHeader != "" {
+			if claims, err := auth.ParseToken(bearerToken(tokenHeader)); err == nil {
+				c.Set("userID", claims.UserID)
+				c.Set("role", claims.Role)
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireRole 要求当前用户 (需先经过 JWTAuth) 属于指定角色之一
+func RequireRole(roles ...auth.AuthRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "权限不足"})
+		c.Abort()
+	}
+}
+
+// bearerToken 支持 "Bearer <token>" 格式
+func bearerToken(header string) string {
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) == 2 && parts[0] == "Bearer" {
+		return parts[1]
+	}
+	return header
+}
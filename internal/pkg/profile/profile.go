@@ -0,0 +1,166 @@
+// Package profile 文章难度画像：JLPT 等级覆盖率、未知内容占比、汉字密度、平均句长与估计等级
+// 只依赖分词与查库结果，不需要 AI
+package profile
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Levels 从易到难的 JLPT 等级
+var Levels = []string{"N5", "N4", "N3", "N2", "N1"}
+
+// 估计等级的参数
+const (
+	// TargetCoverage 读懂文章所需的词汇覆盖率：掌握到某一等级时覆盖率达到该值，即估计为该等级
+	TargetCoverage = 0.9
+	// LongSentence 平均句长 (字符) 超过该值时，估计等级再提高一级
+	LongSentence = 40
+)
+
+// Token 画像所需的 Token 信息 (对应分析结果中的 Token)
+type Token struct {
+	Text     string
+	IsWord   bool
+	Level    string // 选中释义的等级，未标注时为空
+	Sentence int
+}
+
+// LevelCoverage 某一等级的词数及覆盖率
+type LevelCoverage struct {
+	Level      string  `json:"level"`
+	Words      int     `json:"words"`
+	Ratio      float64 `json:"ratio"`      // 占全部词 (含未知) 的比例
+	Cumulative float64 `json:"cumulative"` // 掌握到该等级 (含更简单的等级) 时的覆盖率
+}
+
+// Profile 文章难度画像
+type Profile struct {
+	Characters int `json:"characters"` // 正文字符数 (不含空白、标点与符号)
+	Sentences  int `json:"sentences"`  // 含正文的句子数
+	Words      int `json:"words"`      // 命中词典的词数
+
+	Coverage []LevelCoverage `json:"coverage"` // N5 → N1
+
+	UnleveledWords    int     `json:"unleveled_words"`    // 命中词典但没有等级的词数
+	UnmatchedSegments int     `json:"unmatched_segments"` // 未命中词典的连续片段数
+	UnknownRatio      float64 `json:"unknown_ratio"`      // 无等级的词与未命中片段在正文字符中的占比
+
+	KanjiDensity      float64 `json:"kanji_density"`       // 汉字在正文字符中的占比
+	AvgSentenceLength float64 `json:"avg_sentence_length"` // 平均每句正文字符数
+	AvgSentenceWords  float64 `json:"avg_sentence_words"`  // 平均每句词数
+
+	EstimatedLevel string `json:"estimated_level"` // 估计等级 (N5-N1)，没有正文时为空
+}
+
+// Build 计算文章画像
+// 覆盖率按词次统计，未命中词典的连续字符算作一个未知词；
+// 估计等级为覆盖率首次达到 TargetCoverage 的等级 (始终达不到时为 N1)，句子偏长时再提高一级。
+func Build(tokens []Token) Profile {
+	var p Profile
+	levelWords := make(map[string]int)
+	sentences := make(map[int]bool)
+	unknownChars, kanji := 0, 0
+	inUnmatched := false
+
+	for i, t := range tokens {
+		chars, kanjiChars := countChars(t.Text)
+		p.Characters += chars
+		kanji += kanjiChars
+		if chars > 0 {
+			sentences[t.Sentence] = true
+		}
+
+		if t.IsWord {
+			inUnmatched = false
+			p.Words++
+			if lv := normalizeLevel(t.Level); lv != "" {
+				levelWords[lv]++
+			} else {
+				p.UnleveledWords++
+				unknownChars += chars
+			}
+			continue
+		}
+
+		if chars == 0 {
+			inUnmatched = false
+			continue
+		}
+		unknownChars += chars
+		if !inUnmatched || tokens[i-1].Sentence != t.Sentence {
+			p.UnmatchedSegments++
+		}
+		inUnmatched = true
+	}
+
+	p.Sentences = len(sentences)
+	total := p.Words + p.UnmatchedSegments
+	covered := 0
+	p.Coverage = make([]LevelCoverage, 0, len(Levels))
+	for _, lv := range Levels {
+		n := levelWords[lv]
+		covered += n
+		p.Coverage = append(p.Coverage, LevelCoverage{
+			Level:      lv,
+			Words:      n,
+			Ratio:      ratio(n, total),
+			Cumulative: ratio(covered, total),
+		})
+	}
+
+	p.UnknownRatio = ratio(unknownChars, p.Characters)
+	p.KanjiDensity = ratio(kanji, p.Characters)
+	p.AvgSentenceLength = ratio(p.Characters, p.Sentences)
+	p.AvgSentenceWords = ratio(p.Words, p.Sentences)
+	if total > 0 {
+		p.EstimatedLevel = estimateLevel(p)
+	}
+	return p
+}
+
+func estimateLevel(p Profile) string {
+	idx := len(Levels) - 1
+	for i, c := range p.Coverage {
+		if c.Cumulative >= TargetCoverage {
+			idx = i
+			break
+		}
+	}
+	if p.AvgSentenceLength > LongSentence && idx < len(Levels)-1 {
+		idx++
+	}
+	return Levels[idx]
+}
+
+// normalizeLevel 统一等级写法 ("n3" / " N3 " → "N3")，无法识别时返回空
+func normalizeLevel(level string) string {
+	level = strings.ToUpper(strings.TrimSpace(level))
+	for _, lv := range Levels {
+		if level == lv {
+			return lv
+		}
+	}
+	return ""
+}
+
+// countChars 统计正文字符数 (不含空白、标点与符号) 及其中的汉字数
+func countChars(s string) (chars, kanji int) {
+	for _, r := range s {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		chars++
+		if unicode.Is(unicode.Han, r) {
+			kanji++
+		}
+	}
+	return chars, kanji
+}
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
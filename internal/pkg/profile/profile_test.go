@@ -0,0 +1,86 @@
+package profile
+
+import (
+	"math"
+	"testing"
+)
+
+func word(text, level string, sentence int) Token {
+	return Token{Text: text, IsWord: true, Level: level, Sentence: sentence}
+}
+
+func char(text string, sentence int) Token {
+	return Token{Text: text, Sentence: sentence}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestBuild(t *testing.T) {
+	// 日本語を勉強する。ぽち る。
+	toks := []Token{
+		word("日本語", "N5", 0),
+		word("を", "n5", 0),
+		word("勉強", "N4", 0),
+		word("する", "", 0),
+		char("。", 0),
+		char("ぽ", 1),
+		char("ち", 1),
+		char(" ", 1),
+		char("る", 1),
+		char("。", 1),
+	}
+	p := Build(toks)
+
+	if p.Characters != 11 || p.Sentences != 2 || p.Words != 4 {
+		t.Fatalf("counts = %d chars, %d sentences, %d words", p.Characters, p.Sentences, p.Words)
+	}
+	// "ぽち" 与 "る" 被空白隔开，算两个片段
+	if p.UnleveledWords != 1 || p.UnmatchedSegments != 2 {
+		t.Errorf("unleveled = %d, unmatched = %d", p.UnleveledWords, p.UnmatchedSegments)
+	}
+	// する (2) + ぽちる (3)
+	if !near(p.UnknownRatio, 5.0/11) {
+		t.Errorf("unknown_ratio = %v", p.UnknownRatio)
+	}
+	if !near(p.KanjiDensity, 5.0/11) {
+		t.Errorf("kanji_density = %v", p.KanjiDensity)
+	}
+	if !near(p.AvgSentenceLength, 5.5) || !near(p.AvgSentenceWords, 2) {
+		t.Errorf("avg = %v chars, %v words", p.AvgSentenceLength, p.AvgSentenceWords)
+	}
+
+	if len(p.Coverage) != 5 || p.Coverage[0].Words != 2 || !near(p.Coverage[1].Cumulative, 3.0/6) {
+		t.Errorf("coverage = %+v", p.Coverage)
+	}
+	// 覆盖率始终达不到目标
+	if p.EstimatedLevel != "N1" {
+		t.Errorf("estimated_level = %q", p.EstimatedLevel)
+	}
+}
+
+func TestEstimateLevel(t *testing.T) {
+	var toks []Token
+	for i := 0; i < 9; i++ {
+		toks = append(toks, word("猫", "N5", 0))
+	}
+	toks = append(toks, word("哲学", "N2", 0))
+	if got := Build(toks).EstimatedLevel; got != "N5" {
+		t.Errorf("estimated_level = %q, want N5", got)
+	}
+
+	// 一句超过 LongSentence 个字符时提高一级
+	long := append([]Token{}, toks...)
+	for i := 0; i < LongSentence; i++ {
+		long = append(long, char("x", 0))
+	}
+	// 未知片段算一个词：9/11 < 0.9，N2 时 10/11 ≥ 0.9，再因句长提高到 N1
+	if got := Build(long).EstimatedLevel; got != "N1" {
+		t.Errorf("estimated_level = %q, want N1", got)
+	}
+
+	if got := Build([]Token{char("。", 0)}).EstimatedLevel; got != "" {
+		t.Errorf("empty article estimated_level = %q", got)
+	}
+}
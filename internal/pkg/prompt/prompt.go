@@ -0,0 +1,269 @@
+package prompt
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// 提示词文件命名: <名称>.v<版本>.tmpl，例如 disambiguate.v2.tmpl
+// 每个文件用 {{define}} 定义若干块: system、user (必需)，以及功能自定义的块 (如 repair)。
+
+//go:embed templates/*.tmpl
+var embedded embed.FS
+
+var fileName = regexp.MustCompile(`^([a-z_]+)\.v(\d+)\.tmpl$`)
+
+// Source 提示词来源
+const (
+	SourceEmbedded = "embedded"
+	SourceOverride = "override"
+)
+
+// Template 一个版本的提示词
+type Template struct {
+	Name    string
+	Version string // 如 "v2"
+	Source  string
+	Path    string
+	num     int
+	tmpl    *template.Template
+}
+
+// TemplateInfo 模板列表中的一项
+type TemplateInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Source  string `json:"source"`
+	Path    string `json:"path"`
+	Active  bool   `json:"active"`
+}
+
+// Rendered 渲染结果
+type Rendered struct {
+	Name    string
+	Version string
+	System  string
+	User    string
+}
+
+// Library 提示词库：内置默认模板 + 覆盖目录 (同名同版本的文件覆盖内置，新版本号直接生效)
+type Library struct {
+	dir  string
+	pins map[string]string // 名称 -> 固定使用的版本
+
+	mu        sync.RWMutex
+	templates map[string][]*Template // 名称 -> 按版本升序
+}
+
+// Default 全局提示词库 (仅内置模板)，启动时可用 SetDefault 替换
+var Default = mustLoad("", nil)
+
+// SetDefault 替换全局提示词库
+func SetDefault(lib *Library) {
+	Default = lib
+}
+
+// Load 加载提示词库；dir 为空时只使用内置模板，pins 指定某些名称固定使用的版本
+func Load(dir string, pins map[string]string) (*Library, error) {
+	lib := &Library{dir: dir, pins: pins}
+	if err := lib.Reload(); err != nil {
+		return nil, err
+	}
+	return lib, nil
+}
+
+func mustLoad(dir string, pins map[string]string) *Library {
+	lib, err := Load(dir, pins)
+	if err != nil {
+		panic(err)
+	}
+	return lib
+}
+
+// ParsePins 解析 "disambiguate=v1,generate_word=v2"
+func ParsePins(spec string) map[string]string {
+	pins := make(map[string]string)
+	for _, item := range strings.Split(spec, ",") {
+		name, version, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if ok && name != "" && version != "" {
+			pins[name] = version
+		}
+	}
+	return pins
+}
+
+// Reload 重新读取内置模板与覆盖目录；出错时保留原有模板
+func (l *Library) Reload() error {
+	byKey := make(map[string]*Template)
+	if err := loadFS(embedded, "templates", SourceEmbedded, byKey); err != nil {
+		return err
+	}
+	if l.dir != "" {
+		if err := loadFS(os.DirFS(l.dir), ".", SourceOverride, byKey); err != nil {
+			return err
+		}
+	}
+
+	templates := make(map[string][]*Template)
+	for _, t := range byKey {
+		templates[t.Name] = append(templates[t.Name], t)
+	}
+	for _, list := range templates {
+		sort.Slice(list, func(i, j int) bool { return list[i].num < list[j].num })
+	}
+	for name, version := range l.pins {
+		if _, err := find(templates, name, version); err != nil {
+			return err
+		}
+	}
+
+	l.mu.Lock()
+	l.templates = templates
+	l.mu.Unlock()
+	return nil
+}
+
+// List 列出所有模板 (按名称、版本排序)，active 标记当前生效的版本
+func (l *Library) List() []TemplateInfo {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	names := make([]string, 0, len(l.templates))
+	for name := range l.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out []TemplateInfo
+	for _, name := range names {
+		active, _ := l.active(name)
+		for _, t := range l.templates[name] {
+			out = append(out, TemplateInfo{
+				Name:    t.Name,
+				Version: t.Version,
+				Source:  t.Source,
+				Path:    t.Path,
+				Active:  t == active,
+			})
+		}
+	}
+	return out
+}
+
+// Render 用当前生效的版本渲染 system 与 user 块
+func (l *Library) Render(name string, data any) (Rendered, error) {
+	return l.RenderVersion(name, "", data)
+}
+
+// RenderVersion 用指定版本渲染 (version 为空时使用生效版本)
+func (l *Library) RenderVersion(name, version string, data any) (Rendered, error) {
+	t, err := l.lookup(name, version)
+	if err != nil {
+		return Rendered{}, err
+	}
+
+	r := Rendered{Name: name, Version: t.Version}
+	if r.System, err = execute(t, "system", data); err != nil {
+		return Rendered{}, err
+	}
+	if r.User, err = execute(t, "user", data); err != nil {
+		return Rendered{}, err
+	}
+	return r, nil
+}
+
+// RenderBlock 渲染某个版本中的自定义块 (例如 repair)
+func (l *Library) RenderBlock(name, version, block string, data any) (string, error) {
+	t, err := l.lookup(name, version)
+	if err != nil {
+		return "", err
+	}
+	return execute(t, block, data)
+}
+
+func (l *Library) lookup(name, version string) (*Template, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if version == "" {
+		return l.active(name)
+	}
+	return find(l.templates, name, version)
+}
+
+// active 生效版本：固定的版本优先，否则取最高版本 (调用方需持有读锁)
+func (l *Library) active(name string) (*Template, error) {
+	if version, ok := l.pins[name]; ok {
+		return find(l.templates, name, version)
+	}
+	list := l.templates[name]
+	if len(list) == 0 {
+		return nil, fmt.Errorf("提示词 %s 不存在", name)
+	}
+	return list[len(list)-1], nil
+}
+
+func find(templates map[string][]*Template, name, version string) (*Template, error) {
+	for _, t := range templates[name] {
+		if t.Version == version {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("提示词 %s 没有版本 %s", name, version)
+}
+
+func execute(t *Template, block string, data any) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.ExecuteTemplate(&b, block, data); err != nil {
+		return "", fmt.Errorf("渲染提示词 %s.%s/%s 失败: %w", t.Name, t.Version, block, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// loadFS 读取目录中符合命名规则的模板 (同名同版本的后加载者覆盖先加载者)
+func loadFS(fsys fs.FS, dir, source string, byKey map[string]*Template) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("读取提示词目录失败: %w", err)
+	}
+
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		path := filepath.ToSlash(filepath.Join(dir, e.Name()))
+		raw, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.New(e.Name()).Option("missingkey=error").Parse(string(raw))
+		if err != nil {
+			return fmt.Errorf("解析提示词 %s 失败: %w", e.Name(), err)
+		}
+		for _, block := range []string{"system", "user"} {
+			if tmpl.Lookup(block) == nil {
+				return fmt.Errorf("提示词 %s 缺少 %q 块", e.Name(), block)
+			}
+		}
+
+		num, _ := strconv.Atoi(m[2])
+		byKey[m[1]+"."+m[2]] = &Template{
+			Name:    m[1],
+			Version: "v" + m[2],
+			Source:  source,
+			Path:    path,
+			num:     num,
+			tmpl:    tmpl,
+		}
+	}
+	return nil
+}
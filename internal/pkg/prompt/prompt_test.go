@@ -0,0 +1,83 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTemplate(t *testing.T, dir, name, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEmbeddedTemplates(t *testing.T) {
+	lib, err := Load("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := lib.Render("generate_word", struct{ Word string }{"猫"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version != "v1" || !strings.Contains(r.User, "猫") || r.System == "" {
+		t.Fatalf("unexpected render: %+v", r)
+	}
+	if strings.Contains(r.System, "→") {
+		t.Errorf("system prompt contains line-number artifact: %q", r.System)
+	}
+
+	// 缺少数据字段时报错而不是渲染出 <no value>
+	if _, err := lib.Render("generate_word", struct{}{}); err == nil {
+		t.Error("expected error for missing data field")
+	}
+}
+
+func TestOverrideAndPins(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "generate_word.v2.tmpl", `{{define "system"}}sys v2{{end}}{{define "user"}}词: {{.Word}}{{end}}`)
+	writeTemplate(t, dir, "README.md", "ignored")
+
+	lib, err := Load(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := lib.Render("generate_word", struct{ Word string }{"猫"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version != "v2" || r.User != "词: 猫" {
+		t.Fatalf("override not active: %+v", r)
+	}
+
+	pinned, err := Load(dir, ParsePins("generate_word=v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := pinned.Render("generate_word", struct{ Word string }{"猫"}); r.Version != "v1" {
+		t.Errorf("pinned version = %s, want v1", r.Version)
+	}
+
+	if _, err := Load(dir, ParsePins("generate_word=v9")); err == nil {
+		t.Error("expected error for unknown pinned version")
+	}
+}
+
+func TestReloadKeepsTemplatesOnError(t *testing.T) {
+	dir := t.TempDir()
+	lib, err := Load(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTemplate(t, dir, "disambiguate.v2.tmpl", `{{define "system"}}only system{{end}}`)
+	if err := lib.Reload(); err == nil {
+		t.Fatal("expected error for template without user block")
+	}
+	if _, err := lib.RenderVersion("disambiguate", "v1", struct{ Candidates []any }{}); err != nil {
+		t.Errorf("previous templates lost after failed reload: %v", err)
+	}
+}
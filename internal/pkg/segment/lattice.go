@@ -0,0 +1,94 @@
+package segment
+
+import "math"
+
+// CostModel 最小代价分词的打分参数 (代价越低越好)
+type CostModel struct {
+	UnigramCost    int            // 每个词的基础代价 (一元语法，词数越少越好)
+	UnknownCost    int            // 每个未登录字符的代价
+	LengthBonus    int            // 词长每多一个字符减少的代价
+	InflectionCost int            // 每层活用增加的代价，原形命中优先
+	ReadingCost    int            // 按读音命中的附加代价，词面命中优先
+	LevelCost      map[string]int // JLPT 等级附加代价，常用词 (N5) 更便宜
+	NoLevelCost    int            // 没有等级信息的词条
+}
+
+// DefaultCostModel 默认打分参数
+var DefaultCostModel = CostModel{
+	UnigramCost:    100,
+	UnknownCost:    120,
+	LengthBonus:    10,
+	InflectionCost: 3,
+	ReadingCost:    25,
+	LevelCost: map[string]int{
+		"N5": 0,
+		"N4": 4,
+		"N3": 8,
+		"N2": 12,
+		"N1": 16,
+	},
+	NoLevelCost: 20,
+}
+
+// Cost 计算一个词的代价
+func (cm CostModel) Cost(m Match) int {
+	cost := cm.UnigramCost - cm.LengthBonus*(m.Len()-1) + cm.InflectionCost*len(m.Inflection)
+	if m.Source == SourceReading {
+		cost += cm.ReadingCost
+	}
+	if lc, ok := cm.LevelCost[m.Level]; ok {
+		cost += lc
+	} else {
+		cost += cm.NoLevelCost
+	}
+	return cost
+}
+
+// Lattice 基于词图的最小代价分词 (Viterbi)
+type Lattice struct {
+	model CostModel
+}
+
+func NewLattice(model CostModel) Lattice {
+	return Lattice{model: model}
+}
+
+func (Lattice) Name() string { return StrategyLattice }
+
+func (l Lattice) Segment(lex *Lexicon, runes []rune) []Piece {
+	n := len(runes)
+	edges := buildLattice(lex, runes)
+
+	best := make([]int, n+1)
+	back := make([]Piece, n+1)
+	for i := 1; i <= n; i++ {
+		best[i] = math.MaxInt
+	}
+
+	for i := 0; i < n; i++ {
+		if best[i] == math.MaxInt {
+			continue
+		}
+		// 未登录字符总是可以作为一条边，保证路径连通
+		if c := best[i] + l.model.UnknownCost; c < best[i+1] {
+			best[i+1] = c
+			back[i+1] = unknown(i)
+		}
+		for _, m := range edges[i] {
+			if c := best[i] + l.model.Cost(m); c < best[m.End] {
+				best[m.End] = c
+				back[m.End] = wordPiece(m)
+			}
+		}
+	}
+
+	var reversed []Piece
+	for j := n; j > 0; j = back[j].Start {
+		reversed = append(reversed, back[j])
+	}
+	pieces := make([]Piece, len(reversed))
+	for i, p := range reversed {
+		pieces[len(reversed)-1-i] = p
+	}
+	return pieces
+}
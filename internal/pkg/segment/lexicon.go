@@ -0,0 +1,160 @@
+package segment
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"dongwai_backend/internal/pkg/deinflect"
+)
+
+// Dictionary 分词依赖的词典能力 (由 cache.DictCache 实现)
+type Dictionary interface {
+	Get(word string) ([]string, bool)
+	GetReading(reading string) ([]string, bool)
+	// PrefixSearch 一次遍历回调所有从 runes[start] 开始的命中，返回能走到的最远字符数
+	PrefixSearch(runes []rune, start int, fn func(end int, ids []string)) int
+	ReadingPrefixSearch(runes []rune, start int, fn func(end int, ids []string)) int
+	Level(id string) string
+}
+
+// 命中来源
+const (
+	SourceSurface = "surface" // 词面 (Vocab.Kanji)
+	SourceReading = "reading" // 读音 (VocabSense.Reading)
+)
+
+// MinReadingLen 读音命中的最短长度；单个假名多为助词，按读音匹配噪音太大
+const MinReadingLen = 2
+
+// Match 词典在某个位置上的一次命中
+type Match struct {
+	Start      int      // 起始位置 (rune 下标)
+	End        int      // 结束位置 (rune 下标，不含)
+	IDs        []string // 命中的 Vocab ID
+	Base       string   // 词典原形
+	Inflection []string // 活用链 (原形命中时为空)
+	Level      string   // 命中词条中最简单的 JLPT 等级
+	Source     string   // 命中来源: surface / reading
+}
+
+// Len 命中的字符数
+func (m Match) Len() int {
+	return m.End - m.Start
+}
+
+// Lexicon 在词典上做位置匹配，负责原形命中与活用形还原
+type Lexicon struct {
+	dict Dictionary
+}
+
+func NewLexicon(dict Dictionary) *Lexicon {
+	return &Lexicon{dict: dict}
+}
+
+// MatchesAt 返回所有从 runes[i] 开始的命中，按结束位置升序排列
+// 同一结束位置只保留一个命中，优先级：词面原形 > 读音原形 > 活用形还原
+func (l *Lexicon) MatchesAt(runes []rune, i int) []Match {
+	found := make(map[int]Match)
+	depth := l.dict.PrefixSearch(runes, i, func(end int, ids []string) {
+		found[end] = Match{Start: i, End: end, IDs: ids, Base: string(runes[i:end]), Level: l.level(ids), Source: SourceSurface}
+	})
+	readingDepth := l.dict.ReadingPrefixSearch(runes, i, func(end int, ids []string) {
+		if _, ok := found[end]; ok || end-i < MinReadingLen {
+			return
+		}
+		found[end] = Match{Start: i, End: end, IDs: ids, Base: string(runes[i:end]), Level: l.level(ids), Source: SourceReading}
+	})
+	if readingDepth > depth {
+		depth = readingDepth
+	}
+
+	// 活用形的词干一定是索引中的某个前缀，走不动说明不可能有活用命中
+	if depth > 0 {
+		limit := i + depth + deinflect.MaxSuffixLen
+		if limit > len(runes) {
+			limit = len(runes)
+		}
+		for j := i + 2; j <= limit; j++ {
+			// 活用词尾必然以假名结尾
+			if _, ok := found[j]; ok || !isKana(runes[j-1]) {
+				continue
+			}
+			if m, ok := l.matchInflected(string(runes[i:j])); ok {
+				m.Start, m.End = i, j
+				found[j] = m
+			}
+		}
+	}
+
+	matches := make([]Match, 0, len(found))
+	for _, m := range found {
+		matches = append(matches, m)
+	}
+	sort.Slice(matches, func(a, b int) bool { return matches[a].End < matches[b].End })
+	return matches
+}
+
+// matchInflected 将 word 还原为原形后查词典，合并所有命中的原形
+// 先查词面，词面全部落空时再查读音
+func (l *Lexicon) matchInflected(word string) (Match, bool) {
+	results := deinflect.Deinflect(word)
+	if m, ok := l.collectInflected(results, SourceSurface, l.dict.Get); ok {
+		return m, true
+	}
+	if utf8.RuneCountInString(word) < MinReadingLen {
+		return Match{}, false
+	}
+	return l.collectInflected(results, SourceReading, l.dict.GetReading)
+}
+
+func (l *Lexicon) collectInflected(results []deinflect.Result, source string, get func(string) ([]string, bool)) (Match, bool) {
+	m := Match{Source: source}
+	seen := make(map[string]bool)
+
+	for _, r := range results {
+		keys := []string{r.Base}
+		// サ变动词：词库通常只收录名词词干 (勉強する -> 勉強)
+		if stem, ok := r.SuruStem(); ok {
+			keys = append(keys, stem)
+		}
+
+		for _, key := range keys {
+			ids, exists := get(key)
+			if !exists {
+				continue
+			}
+			if m.Base == "" {
+				m.Base = key
+				m.Inflection = r.Reasons
+			}
+			for _, id := range ids {
+				if !seen[id] {
+					seen[id] = true
+					m.IDs = append(m.IDs, id)
+				}
+			}
+		}
+	}
+	if len(m.IDs) == 0 {
+		return m, false
+	}
+	m.Level = l.level(m.IDs)
+	return m, true
+}
+
+// level 取一组词条中最简单的等级 (N5 > N4 > ... > N1)
+func (l *Lexicon) level(ids []string) string {
+	best := ""
+	for _, id := range ids {
+		lv := l.dict.Level(id)
+		if lv != "" && (best == "" || lv > best) {
+			best = lv
+		}
+	}
+	return best
+}
+
+// isKana 判断是否为平假名或片假名
+func isKana(r rune) bool {
+	return (r >= 0x3041 && r <= 0x309F) || (r >= 0x30A0 && r <= 0x30FF)
+}
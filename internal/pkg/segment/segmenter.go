@@ -0,0 +1,163 @@
+package segment
+
+// Piece 分词结果中的一段；Match 为 nil 表示未登录的单个字符
+type Piece struct {
+	Start int
+	End   int
+	Match *Match
+}
+
+// IsWord 是否命中词典
+func (p Piece) IsWord() bool {
+	return p.Match != nil
+}
+
+// Segmenter 分词策略
+type Segmenter interface {
+	Name() string
+	Segment(lex *Lexicon, runes []rune) []Piece
+}
+
+// 策略名称 (对应请求中的 strategy 字段)
+const (
+	StrategyForward       = "forward"
+	StrategyBackward      = "backward"
+	StrategyBidirectional = "bidirectional"
+	StrategyLattice       = "lattice"
+)
+
+// DefaultStrategy 未指定策略时使用的分词方式
+const DefaultStrategy = StrategyLattice
+
+var registry = map[string]Segmenter{
+	StrategyForward:       Forward{},
+	StrategyBackward:      Backward{},
+	StrategyBidirectional: Bidirectional{},
+	StrategyLattice:       NewLattice(DefaultCostModel),
+}
+
+// ByName 按名称获取分词策略，空字符串返回默认策略
+func ByName(name string) (Segmenter, bool) {
+	if name == "" {
+		name = DefaultStrategy
+	}
+	s, ok := registry[name]
+	return s, ok
+}
+
+// buildLattice 预先计算每个起点上的全部命中
+func buildLattice(lex *Lexicon, runes []rune) [][]Match {
+	edges := make([][]Match, len(runes))
+	for i := range runes {
+		edges[i] = lex.MatchesAt(runes, i)
+	}
+	return edges
+}
+
+// unknown 构造未登录字符
+func unknown(i int) Piece {
+	return Piece{Start: i, End: i + 1}
+}
+
+func wordPiece(m Match) Piece {
+	mm := m
+	return Piece{Start: m.Start, End: m.End, Match: &mm}
+}
+
+// ========================================
+// 正向最大匹配
+// ========================================
+
+type Forward struct{}
+
+func (Forward) Name() string { return StrategyForward }
+
+func (Forward) Segment(lex *Lexicon, runes []rune) []Piece {
+	var pieces []Piece
+	for i := 0; i < len(runes); {
+		matches := lex.MatchesAt(runes, i)
+		if len(matches) == 0 {
+			pieces = append(pieces, unknown(i))
+			i++
+			continue
+		}
+		longest := matches[len(matches)-1]
+		pieces = append(pieces, wordPiece(longest))
+		i = longest.End
+	}
+	return pieces
+}
+
+// ========================================
+// 逆向最大匹配
+// ========================================
+
+type Backward struct{}
+
+func (Backward) Name() string { return StrategyBackward }
+
+func (Backward) Segment(lex *Lexicon, runes []rune) []Piece {
+	// 按结束位置索引命中，起点越靠前 (越长) 越优先
+	ending := make([]*Match, len(runes)+1)
+	for _, list := range buildLattice(lex, runes) {
+		for k := range list {
+			m := list[k]
+			if cur := ending[m.End]; cur == nil || m.Start < cur.Start {
+				ending[m.End] = &m
+			}
+		}
+	}
+
+	var reversed []Piece
+	for j := len(runes); j > 0; {
+		if m := ending[j]; m != nil {
+			reversed = append(reversed, wordPiece(*m))
+			j = m.Start
+			continue
+		}
+		reversed = append(reversed, unknown(j-1))
+		j--
+	}
+
+	pieces := make([]Piece, len(reversed))
+	for i, p := range reversed {
+		pieces[len(reversed)-1-i] = p
+	}
+	return pieces
+}
+
+// ========================================
+// 双向最大匹配
+// ========================================
+
+// Bidirectional 同时执行正向与逆向匹配：
+// 词数少者优先；词数相同则单字 (含未登录字) 少者优先；仍相同取逆向结果
+type Bidirectional struct{}
+
+func (Bidirectional) Name() string { return StrategyBidirectional }
+
+func (Bidirectional) Segment(lex *Lexicon, runes []rune) []Piece {
+	fwd := Forward{}.Segment(lex, runes)
+	bwd := Backward{}.Segment(lex, runes)
+
+	if len(fwd) != len(bwd) {
+		if len(fwd) < len(bwd) {
+			return fwd
+		}
+		return bwd
+	}
+	if singles(fwd) < singles(bwd) {
+		return fwd
+	}
+	return bwd
+}
+
+func singles(pieces []Piece) int {
+	n := 0
+	for _, p := range pieces {
+		if p.End-p.Start == 1 {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,162 @@
+package segment
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeDict 测试用的内存词典
+type fakeDict struct {
+	words    map[string][]string
+	readings map[string][]string
+	levels   map[string]string
+}
+
+func newFakeDict(words ...string) *fakeDict {
+	d := &fakeDict{words: map[string][]string{}, readings: map[string][]string{}, levels: map[string]string{}}
+	for _, w := range words {
+		d.words[w] = []string{"id_" + w}
+		d.levels["id_"+w] = "N3"
+	}
+	return d
+}
+
+func (d *fakeDict) Get(word string) ([]string, bool) {
+	ids, ok := d.words[word]
+	return ids, ok
+}
+
+// withReading 为已有词条登记读音
+func (d *fakeDict) withReading(word, reading string) *fakeDict {
+	d.readings[reading] = append(d.readings[reading], d.words[word]...)
+	return d
+}
+
+func (d *fakeDict) GetReading(reading string) ([]string, bool) {
+	ids, ok := d.readings[reading]
+	return ids, ok
+}
+
+func (d *fakeDict) PrefixSearch(runes []rune, start int, fn func(end int, ids []string)) int {
+	return prefixSearch(d.words, runes, start, fn)
+}
+
+func (d *fakeDict) ReadingPrefixSearch(runes []rune, start int, fn func(end int, ids []string)) int {
+	return prefixSearch(d.readings, runes, start, fn)
+}
+
+func prefixSearch(words map[string][]string, runes []rune, start int, fn func(end int, ids []string)) int {
+	depth := 0
+	for end := start + 1; end <= len(runes); end++ {
+		prefix := string(runes[start:end])
+		found := false
+		for w := range words {
+			if strings.HasPrefix(w, prefix) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+		depth = end - start
+		if ids, ok := words[prefix]; ok {
+			fn(end, ids)
+		}
+	}
+	return depth
+}
+
+func (d *fakeDict) Level(id string) string { return d.levels[id] }
+
+func texts(runes []rune, pieces []Piece) []string {
+	var out []string
+	for _, p := range pieces {
+		out = append(out, string(runes[p.Start:p.End]))
+	}
+	return out
+}
+
+func TestStrategies(t *testing.T) {
+	// 正向会贪心地吃掉 "日本語学"，逆向与词图能找到 日本語 / 学校
+	lex := NewLexicon(newFakeDict("日本", "日本語", "日本語学", "語学", "学校", "校"))
+	runes := []rune("日本語学校")
+
+	cases := map[string][]string{
+		StrategyForward:       {"日本語学", "校"},
+		StrategyBackward:      {"日本語", "学校"},
+		StrategyBidirectional: {"日本語", "学校"},
+		StrategyLattice:       {"日本語", "学校"},
+	}
+	for name, want := range cases {
+		seg, ok := ByName(name)
+		if !ok {
+			t.Fatalf("策略 %s 未注册", name)
+		}
+		if got := texts(runes, seg.Segment(lex, runes)); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestLatticeInflectionAndUnknown(t *testing.T) {
+	lex := NewLexicon(newFakeDict("食べる", "寿司"))
+	runes := []rune("寿司を食べました")
+
+	seg, _ := ByName("")
+	pieces := seg.Segment(lex, runes)
+	if got, want := texts(runes, pieces), []string{"寿司", "を", "食べました"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if pieces[1].IsWord() {
+		t.Errorf("を 不应命中词典")
+	}
+	if m := pieces[2].Match; m == nil || m.Base != "食べる" {
+		t.Errorf("食べました 应还原为 食べる, got %+v", m)
+	}
+}
+
+func TestByNameUnknown(t *testing.T) {
+	if _, ok := ByName("nope"); ok {
+		t.Error("未知策略应返回 false")
+	}
+}
+
+func TestReadingMatch(t *testing.T) {
+	lex := NewLexicon(newFakeDict("寿司", "食べる", "を").withReading("寿司", "すし").withReading("食べる", "たべる"))
+	runes := []rune("すしをたべた")
+
+	seg, _ := ByName(StrategyLattice)
+	pieces := seg.Segment(lex, runes)
+	if got, want := texts(runes, pieces), []string{"すし", "を", "たべた"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if src := pieces[0].Match.Source; src != SourceReading {
+		t.Errorf("すし Source = %q, want reading", src)
+	}
+	if src := pieces[1].Match.Source; src != SourceSurface {
+		t.Errorf("を Source = %q, want surface", src)
+	}
+	if m := pieces[2].Match; m.Source != SourceReading || m.Base != "たべる" || !reflect.DeepEqual(m.IDs, []string{"id_食べる"}) {
+		t.Errorf("たべた 应按读音还原为 たべる, got %+v", m)
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	text := []rune("今日は晴れ。「いい天気だね！」と言った。\n\n明日は雨かな？」")
+	spans := SplitSentences(text)
+
+	var got []string
+	var paragraphs []int
+	for _, s := range spans {
+		got = append(got, string(text[s.Start:s.End]))
+		paragraphs = append(paragraphs, s.Paragraph)
+	}
+	if want := []string{"今日は晴れ。", "「いい天気だね！」と言った。", "明日は雨かな？」"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sentences = %v, want %v", got, want)
+	}
+	if want := []int{0, 0, 1}; !reflect.DeepEqual(paragraphs, want) {
+		t.Errorf("paragraphs = %v, want %v", paragraphs, want)
+	}
+}
@@ -0,0 +1,100 @@
+package segment
+
+import "unicode"
+
+// SentenceSpan 一个句子在原文中的区间 (rune 下标，不含首尾空白)
+type SentenceSpan struct {
+	Start     int
+	End       int
+	Paragraph int
+}
+
+// SplitSentences 按句末标点与换行切分句子
+// 引号/括号内的句末标点不断句 (「いい天気だね！」と言った 为一句)；
+// 句末标点后紧跟的闭合引号/括号归入当前句；换行既结束当前句，也开启新段落
+func SplitSentences(runes []rune) []SentenceSpan {
+	var spans []SentenceSpan
+	start := -1
+	paragraph := 0
+	depth := 0
+	lineBreak := false
+
+	closeAt := func(end int) {
+		// 去掉句尾空白
+		for end > start && unicode.IsSpace(runes[end-1]) {
+			end--
+		}
+		if end > start {
+			spans = append(spans, SentenceSpan{Start: start, End: end, Paragraph: paragraph})
+		}
+		start = -1
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '\n' || r == '\r' {
+			if start >= 0 {
+				closeAt(i)
+			}
+			depth = 0
+			lineBreak = true
+			continue
+		}
+		if start < 0 {
+			if unicode.IsSpace(r) {
+				continue
+			}
+			if lineBreak && len(spans) > 0 {
+				paragraph++
+			}
+			lineBreak = false
+			start = i
+		}
+
+		switch {
+		case isOpener(r):
+			depth++
+			continue
+		case isCloser(r) && depth > 0:
+			depth--
+			continue
+		}
+
+		if depth == 0 && isSentenceEnd(r) {
+			j := i + 1
+			for j < len(runes) && (isSentenceEnd(runes[j]) || isCloser(runes[j])) {
+				j++
+			}
+			closeAt(j)
+			i = j - 1
+		}
+	}
+	if start >= 0 {
+		closeAt(len(runes))
+	}
+	return spans
+}
+
+func isSentenceEnd(r rune) bool {
+	switch r {
+	case '。', '！', '？', '!', '?', '…', '｡':
+		return true
+	}
+	return false
+}
+
+func isOpener(r rune) bool {
+	switch r {
+	case '「', '『', '（', '(', '【', '〈', '《', '“', '‘':
+		return true
+	}
+	return false
+}
+
+func isCloser(r rune) bool {
+	switch r {
+	case '」', '』', '）', ')', '】', '〉', '》', '”', '’':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package stream
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// EventID 组合对外的事件 ID: "<streamID>:<序号>"
+func EventID(streamID string, seq int) string {
+	return fmt.Sprintf("%s:%d", streamID, seq)
+}
+
+// ParseEventID 解析 Last-Event-ID，返回流 ID 与序号
+func ParseEventID(raw string) (string, int, bool) {
+	idx := strings.LastIndex(raw, ":")
+	if idx <= 0 {
+		return "", 0, false
+	}
+	seq, err := strconv.Atoi(raw[idx+1:])
+	if err != nil || seq < 0 {
+		return "", 0, false
+	}
+	return raw[:idx], seq, true
+}
+
+// WriteHeaders 设置 SSE 响应头
+func WriteHeaders(c *gin.Context, s *Stream) {
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+	c.Writer.Header().Set("X-Stream-ID", s.ID)
+}
+
+// Serve 将序号 after 之后的事件推送给客户端，直到流结束或客户端断开
+func Serve(c *gin.Context, s *Stream, after int) {
+	detach := s.Attach()
+	defer detach()
+
+	WriteHeaders(c, s)
+	for {
+		events, closed, changed := s.Since(after)
+		for _, e := range events {
+			c.Render(-1, sse.Event{Id: EventID(s.ID, e.ID), Event: e.Name, Data: e.Data})
+			after = e.ID
+		}
+		c.Writer.Flush()
+		if closed {
+			return
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package stream
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseEventID(t *testing.T) {
+	id := EventID("3f2a-stream", 12)
+	if streamID, seq, ok := ParseEventID(id); !ok || streamID != "3f2a-stream" || seq != 12 {
+		t.Errorf("ParseEventID(%q) = %q, %d, %v", id, streamID, seq, ok)
+	}
+	// 流 ID 本身含冒号时按最后一个冒号切分
+	if streamID, seq, ok := ParseEventID("a:b:3"); !ok || streamID != "a:b" || seq != 3 {
+		t.Errorf("got %q, %d, %v", streamID, seq, ok)
+	}
+
+	for _, bad := range []string{"", "abc", ":3", "abc:", "abc:x", "abc:-1", "abc:1.5", "abc: 2"} {
+		if _, _, ok := ParseEventID(bad); ok {
+			t.Errorf("ParseEventID(%q) should fail", bad)
+		}
+	}
+}
+
+// serve 以 Last-Event-ID 序号 after 订阅，返回响应内容 (流结束前会阻塞)
+func serve(t *testing.T, ctx context.Context, s *Stream, after int) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	Serve(c, s, after)
+	return w.Body.String()
+}
+
+// eventIDs 响应中的事件 ID (按出现顺序)
+func eventIDs(body string) []string {
+	var ids []string
+	for _, line := range strings.Split(body, "\n") {
+		if id, ok := strings.CutPrefix(line, "id:"); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func TestServeReplaysAfterSequence(t *testing.T) {
+	s, _ := newTestHub(time.Minute, time.Minute).Create(context.Background())
+	s.Publish("initial", "a")
+	s.Publish("progress", "b")
+	s.Publish("done", "c")
+	s.Close()
+
+	body := serve(t, context.Background(), s, 1)
+	ids := eventIDs(body)
+	if len(ids) != 2 || ids[0] != EventID(s.ID, 2) || ids[1] != EventID(s.ID, 3) {
+		t.Errorf("ids = %v\n%s", ids, body)
+	}
+	if strings.Contains(body, "event:initial") {
+		t.Errorf("replayed event before Last-Event-ID:\n%s", body)
+	}
+}
+
+func TestServeAfterCloseGetsBacklogThenDone(t *testing.T) {
+	s, _ := newTestHub(time.Minute, time.Minute).Create(context.Background())
+	s.Publish("initial", "a")
+	s.Publish("ai_update", "b")
+	s.Publish("done", "c")
+	s.Close()
+
+	finished := make(chan string)
+	go func() { finished <- serve(t, context.Background(), s, 0) }()
+
+	select {
+	case body := <-finished:
+		initial := strings.Index(body, "event:initial")
+		update := strings.Index(body, "event:ai_update")
+		done := strings.Index(body, "event:done")
+		if initial < 0 || update < initial || done < update {
+			t.Errorf("events out of order:\n%s", body)
+		}
+		if !strings.HasSuffix(strings.TrimSpace(body), "data:c") {
+			t.Errorf("done should be the last event:\n%s", body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return for a closed stream")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscribers != 0 {
+		t.Errorf("subscribers = %d after Serve returned", s.subscribers)
+	}
+}
+
+func TestServeFollowsLiveStream(t *testing.T) {
+	s, _ := newTestHub(time.Minute, time.Minute).Create(context.Background())
+	s.Publish("initial", "a")
+
+	finished := make(chan string)
+	go func() { finished <- serve(t, context.Background(), s, 0) }()
+
+	// 等订阅者登记后再继续发布
+	for deadline := time.Now().Add(time.Second); ; {
+		s.mu.Lock()
+		n := s.subscribers
+		s.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("subscriber never attached")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.Publish("ai_update", "b")
+	s.Publish("done", "c")
+	s.Close()
+
+	select {
+	case body := <-finished:
+		if ids := eventIDs(body); len(ids) != 3 {
+			t.Errorf("ids = %v\n%s", ids, body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return after Close")
+	}
+}
+
+func TestServeReturnsWhenClientLeaves(t *testing.T) {
+	s, _ := newTestHub(time.Minute, time.Minute).Create(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+
+	finished := make(chan struct{})
+	go func() {
+		serve(t, ctx, s, 0)
+		close(finished)
+	}()
+	cancel()
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return after client disconnect")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscribers != 0 || s.detachedAt.IsZero() {
+		t.Errorf("subscribers = %d, detachedAt = %v", s.subscribers, s.detachedAt)
+	}
+}
@@ -0,0 +1,174 @@
+package stream
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event 一条已发布的事件，ID 在同一个 Stream 内从 1 开始递增
+type Event struct {
+	ID   int
+	Name string
+	Data any
+}
+
+// Stream 可重放的事件流
+// 生产者通过 Publish 追加事件，消费者可以从任意序号之后开始读取，
+// 因此客户端断线后可以凭最后收到的事件 ID 续传。
+type Stream struct {
+	ID string
+
+	mu          sync.Mutex
+	events      []Event
+	closed      bool
+	changed     chan struct{} // 每次发布或关闭时 close 并替换，用于唤醒等待者
+	subscribers int
+	detachedAt  time.Time // 最后一个订阅者离开的时间
+	finishedAt  time.Time
+	cancel      context.CancelFunc
+}
+
+// Publish 追加一条事件；流关闭后的发布会被忽略
+func (s *Stream) Publish(name string, data any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.events = append(s.events, Event{ID: len(s.events) + 1, Name: name, Data: data})
+	s.wake()
+}
+
+// Close 结束事件流，之后的订阅者读完已有事件即返回
+func (s *Stream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.finishedAt = time.Now()
+	s.wake()
+}
+
+// Since 返回序号大于 after 的事件、流是否已结束，以及下一次变化时会被关闭的 channel
+func (s *Stream) Since(after int) ([]Event, bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if after < 0 {
+		after = 0
+	}
+	var events []Event
+	if after < len(s.events) {
+		events = append(events, s.events[after:]...)
+	}
+	return events, s.closed, s.changed
+}
+
+// Attach 登记一个订阅者，返回的函数用于注销
+func (s *Stream) Attach() (detach func()) {
+	s.mu.Lock()
+	s.subscribers++
+	s.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.subscribers--
+			if s.subscribers == 0 {
+				s.detachedAt = time.Now()
+			}
+			s.mu.Unlock()
+		})
+	}
+}
+
+// wake 唤醒所有等待者 (调用方需持有 mu)
+func (s *Stream) wake() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Hub 管理进行中与刚结束的事件流
+type Hub struct {
+	mu      sync.Mutex
+	streams map[string]*Stream
+
+	// retention 流结束后保留多久以便续传
+	retention time.Duration
+	// grace 未结束的流在没有任何订阅者时最多保留多久，超时后取消生产者
+	grace time.Duration
+}
+
+// NewHub 创建 Hub 并启动后台清理
+func NewHub(retention, grace time.Duration) *Hub {
+	h := &Hub{
+		streams:   make(map[string]*Stream),
+		retention: retention,
+		grace:     grace,
+	}
+	go h.janitor()
+	return h
+}
+
+// Create 新建事件流，返回的 ctx 会在流被放弃 (无人订阅超过 grace) 时取消
+func (h *Hub) Create(parent context.Context) (*Stream, context.Context) {
+	ctx, cancel := context.WithCancel(parent)
+	s := &Stream{
+		ID:         uuid.New().String(),
+		changed:    make(chan struct{}),
+		cancel:     cancel,
+		detachedAt: time.Now(),
+	}
+
+	h.mu.Lock()
+	h.streams[s.ID] = s
+	h.mu.Unlock()
+	return s, ctx
+}
+
+// Get 按 ID 查找事件流
+func (h *Hub) Get(id string) (*Stream, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.streams[id]
+	return s, ok
+}
+
+func (h *Hub) janitor() {
+	interval := h.grace / 2
+	if interval <= 0 || interval > time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		h.sweep(time.Now())
+	}
+}
+
+// sweep 取消被放弃的流，删除过期的流
+func (h *Hub) sweep(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for id, s := range h.streams {
+		s.mu.Lock()
+		abandoned := !s.closed && s.subscribers == 0 && now.Sub(s.detachedAt) > h.grace
+		expired := s.closed && now.Sub(s.finishedAt) > h.retention
+		s.mu.Unlock()
+
+		if abandoned {
+			s.cancel()
+		}
+		if expired {
+			s.cancel()
+			delete(h.streams, id)
+		}
+	}
+}
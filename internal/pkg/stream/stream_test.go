@@ -0,0 +1,163 @@
+package stream
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestHub 不启动后台清理，由测试直接调用 sweep
+func newTestHub(retention, grace time.Duration) *Hub {
+	return &Hub{streams: make(map[string]*Stream), retention: retention, grace: grace}
+}
+
+func eventNames(events []Event) []string {
+	names := make([]string, 0, len(events))
+	for _, e := range events {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+func TestStreamSince(t *testing.T) {
+	s, _ := newTestHub(time.Minute, time.Minute).Create(context.Background())
+	s.Publish("initial", 1)
+	s.Publish("progress", 2)
+	s.Publish("done", 3)
+
+	cases := []struct {
+		after int
+		want  []int
+	}{
+		{-1, []int{1, 2, 3}},
+		{0, []int{1, 2, 3}},
+		{1, []int{2, 3}},
+		{3, nil},
+		{10, nil}, // 来自更长的流的序号，不会越界
+	}
+	for _, tc := range cases {
+		events, closed, _ := s.Since(tc.after)
+		if closed {
+			t.Errorf("after %d: closed before Close", tc.after)
+		}
+		if len(events) != len(tc.want) {
+			t.Errorf("after %d: events = %v", tc.after, events)
+			continue
+		}
+		for i, e := range events {
+			if e.ID != tc.want[i] {
+				t.Errorf("after %d: ids = %v", tc.after, events)
+				break
+			}
+		}
+	}
+}
+
+func TestStreamCloseWakesWaiters(t *testing.T) {
+	s, _ := newTestHub(time.Minute, time.Minute).Create(context.Background())
+	_, _, changed := s.Since(0)
+
+	s.Publish("done", nil)
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("publish did not wake waiter")
+	}
+
+	_, _, changed = s.Since(1)
+	s.Close()
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("close did not wake waiter")
+	}
+
+	// 关闭后的发布被忽略，序号不再增长
+	s.Publish("late", nil)
+	events, closed, _ := s.Since(0)
+	if !closed || len(events) != 1 || events[0].Name != "done" {
+		t.Errorf("events = %v, closed = %v", events, closed)
+	}
+	s.Close() // 重复关闭无副作用
+}
+
+func TestStreamConcurrentPublish(t *testing.T) {
+	s, _ := newTestHub(time.Minute, time.Minute).Create(context.Background())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				s.Publish("ai_update", j)
+			}
+		}()
+	}
+	wg.Wait()
+	s.Close()
+
+	events, _, _ := s.Since(0)
+	if len(events) != 400 {
+		t.Fatalf("len = %d", len(events))
+	}
+	for i, e := range events {
+		if e.ID != i+1 {
+			t.Fatalf("event %d has id %d", i, e.ID)
+		}
+	}
+}
+
+func TestHubSweep(t *testing.T) {
+	h := newTestHub(10*time.Minute, 30*time.Second)
+	now := time.Now()
+
+	expired, expiredCtx := h.Create(context.Background())
+	expired.Close()
+	expired.finishedAt = now.Add(-11 * time.Minute)
+
+	retained, retainedCtx := h.Create(context.Background())
+	retained.Close()
+
+	abandoned, abandonedCtx := h.Create(context.Background())
+	abandoned.Attach()()
+	abandoned.detachedAt = now.Add(-time.Minute)
+
+	watched, watchedCtx := h.Create(context.Background())
+	detach := watched.Attach()
+	defer detach()
+	watched.detachedAt = now.Add(-time.Minute) // 仍有订阅者，不算放弃
+
+	fresh, freshCtx := h.Create(context.Background()) // 还没人来得及订阅
+
+	h.sweep(now)
+
+	if _, ok := h.Get(expired.ID); ok || expiredCtx.Err() == nil {
+		t.Error("expired stream should be removed and cancelled")
+	}
+	if _, ok := h.Get(retained.ID); !ok || retainedCtx.Err() != nil {
+		t.Error("recently finished stream should be kept for resume")
+	}
+	// 被放弃的流取消生产者，但保留已有事件，等生产者 Close 后按 retention 过期
+	if _, ok := h.Get(abandoned.ID); !ok || abandonedCtx.Err() == nil {
+		t.Error("abandoned stream should be cancelled but kept")
+	}
+	if watchedCtx.Err() != nil || freshCtx.Err() != nil {
+		t.Error("streams with subscribers or within grace should not be cancelled")
+	}
+	if _, ok := h.Get(fresh.ID); !ok {
+		t.Error("fresh stream removed")
+	}
+
+	// 生产者收到取消后关闭，过了 retention 才删除
+	abandoned.Close()
+	h.sweep(now.Add(5 * time.Minute))
+	if _, ok := h.Get(abandoned.ID); !ok {
+		t.Error("closed stream removed before retention")
+	}
+	h.sweep(time.Now().Add(11 * time.Minute))
+	if _, ok := h.Get(abandoned.ID); ok {
+		t.Error("closed stream kept after retention")
+	}
+}
@@ -0,0 +1,94 @@
+package textnorm
+
+// halfKana 半角片假名及标点 -> 全角
+var halfKana = map[rune]rune{
+	'ｦ': 'ヲ', 'ｧ': 'ァ', 'ｨ': 'ィ', 'ｩ': 'ゥ', 'ｪ': 'ェ', 'ｫ': 'ォ', 'ｬ': 'ャ', 'ｭ': 'ュ',
+	'ｮ': 'ョ', 'ｯ': 'ッ', 'ｰ': 'ー', 'ｱ': 'ア', 'ｲ': 'イ', 'ｳ': 'ウ', 'ｴ': 'エ', 'ｵ': 'オ',
+	'ｶ': 'カ', 'ｷ': 'キ', 'ｸ': 'ク', 'ｹ': 'ケ', 'ｺ': 'コ', 'ｻ': 'サ', 'ｼ': 'シ', 'ｽ': 'ス',
+	'ｾ': 'セ', 'ｿ': 'ソ', 'ﾀ': 'タ', 'ﾁ': 'チ', 'ﾂ': 'ツ', 'ﾃ': 'テ', 'ﾄ': 'ト', 'ﾅ': 'ナ',
+	'ﾆ': 'ニ', 'ﾇ': 'ヌ', 'ﾈ': 'ネ', 'ﾉ': 'ノ', 'ﾊ': 'ハ', 'ﾋ': 'ヒ', 'ﾌ': 'フ', 'ﾍ': 'ヘ',
+	'ﾎ': 'ホ', 'ﾏ': 'マ', 'ﾐ': 'ミ', 'ﾑ': 'ム', 'ﾒ': 'メ', 'ﾓ': 'モ', 'ﾔ': 'ヤ', 'ﾕ': 'ユ',
+	'ﾖ': 'ヨ', 'ﾗ': 'ラ', 'ﾘ': 'リ', 'ﾙ': 'ル', 'ﾚ': 'レ', 'ﾛ': 'ロ', 'ﾜ': 'ワ', 'ﾝ': 'ン',
+	'｡': '。', '｢': '「', '｣': '」', '､': '、', '･': '・',
+}
+
+// kanjiVariants 旧字体 / 异体字 -> 新字体 (常用字)
+var kanjiVariants = map[rune]rune{
+	'國': '国', '學': '学', '體': '体', '會': '会', '來': '来', '氣': '気', '圖': '図', '廣': '広',
+	'實': '実', '當': '当', '舊': '旧', '關': '関', '發': '発', '變': '変', '聲': '声', '賣': '売',
+	'讀': '読', '數': '数', '樂': '楽', '藝': '芸', '黑': '黒', '戰': '戦', '團': '団', '萬': '万',
+	'與': '与', '澤': '沢', '廳': '庁', '鐵': '鉄', '佛': '仏', '櫻': '桜', '驛': '駅', '龍': '竜',
+	'邊': '辺', '邉': '辺', '髙': '高', '﨑': '崎', '壽': '寿', '檢': '検', '驗': '験', '險': '険',
+	'鹽': '塩', '寫': '写', '畫': '画', '兒': '児', '齒': '歯', '應': '応', '擧': '挙', '價': '価',
+	'醫': '医', '區': '区', '縣': '県', '廢': '廃', '恆': '恒', '惠': '恵', '徑': '径', '莖': '茎',
+	'經': '経', '繼': '継', '輕': '軽', '缺': '欠', '將': '将', '獎': '奨', '條': '条', '狀': '状',
+	'亞': '亜', '惡': '悪', '圍': '囲', '爲': '為', '榮': '栄', '營': '営', '衞': '衛', '圓': '円',
+	'假': '仮', '勞': '労', '壓': '圧', '單': '単', '傳': '伝', '轉': '転', '黨': '党', '燈': '灯',
+	'稻': '稲', '德': '徳', '獨': '独', '讓': '譲', '觸': '触', '寢': '寝', '眞': '真', '盡': '尽',
+	'靜': '静', '攝': '摂', '專': '専', '淺': '浅', '錢': '銭', '雙': '双', '總': '総', '藏': '蔵',
+	'續': '続', '對': '対', '臺': '台', '擇': '択', '斷': '断', '遲': '遅', '晝': '昼', '蟲': '虫',
+	'鑄': '鋳', '聽': '聴', '鎭': '鎮', '遞': '逓', '點': '点', '嶋': '島', '劍': '剣', '拔': '抜',
+	'髮': '髪', '拂': '払', '辯': '弁', '瓣': '弁', '辨': '弁', '寶': '宝', '豐': '豊', '滿': '満',
+	'默': '黙', '譯': '訳', '藥': '薬', '豫': '予', '餘': '余', '搖': '揺', '樣': '様', '謠': '謡',
+	'亂': '乱', '覽': '覧', '兩': '両', '獵': '猟', '綠': '緑', '壘': '塁', '勵': '励', '禮': '礼',
+	'靈': '霊', '齡': '齢', '爐': '炉', '樓': '楼', '灣': '湾',
+}
+
+// voiceable 可以加浊点的假名 (浊音 = 清音 + 1)
+func voiceable(r rune) bool {
+	switch r {
+	case 'か', 'き', 'く', 'け', 'こ', 'さ', 'し', 'す', 'せ', 'そ',
+		'た', 'ち', 'つ', 'て', 'と', 'は', 'ひ', 'ふ', 'へ', 'ほ',
+		'カ', 'キ', 'ク', 'ケ', 'コ', 'サ', 'シ', 'ス', 'セ', 'ソ',
+		'タ', 'チ', 'ツ', 'テ', 'ト', 'ハ', 'ヒ', 'フ', 'ヘ', 'ホ':
+		return true
+	}
+	return false
+}
+
+// semiVoiceable 可以加半浊点的假名 (半浊音 = 清音 + 2)
+func semiVoiceable(r rune) bool {
+	switch r {
+	case 'は', 'ひ', 'ふ', 'へ', 'ほ', 'ハ', 'ヒ', 'フ', 'ヘ', 'ホ':
+		return true
+	}
+	return false
+}
+
+// voiced 清音 -> 浊音
+func voiced(r rune) (rune, bool) {
+	if r == 'ウ' {
+		return 'ヴ', true
+	}
+	if voiceable(r) {
+		return r + 1, true
+	}
+	return r, false
+}
+
+// unvoiced 浊音 / 半浊音 -> 清音，其余原样返回
+func unvoiced(r rune) rune {
+	if r == 'ヴ' {
+		return 'ウ'
+	}
+	if voiceable(r - 1) {
+		return r - 1
+	}
+	if semiVoiceable(r - 2) {
+		return r - 2
+	}
+	return r
+}
+
+// combineMark 全角假名与其后的半角浊点 (ﾞ) / 半浊点 (ﾟ) 合成
+func combineMark(r, mark rune) (rune, bool) {
+	switch mark {
+	case 'ﾞ':
+		return voiced(r)
+	case 'ﾟ':
+		if semiVoiceable(r) {
+			return r + 2, true
+		}
+	}
+	return r, false
+}
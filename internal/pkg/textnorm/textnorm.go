@@ -0,0 +1,191 @@
+package textnorm
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// Options 规范化开关
+type Options struct {
+	Width          bool // 全角 ASCII -> 半角，半角片假名 (含浊点合成) -> 全角
+	IterationMarks bool // 展开踊り字：々 ゝ ゞ ヽ ヾ
+	KanjiVariants  bool // 旧字体 / 异体字 -> 新字体
+	LongVowel      bool // 假名后的 - － ‐ ― ─ 〜 ～ 等统一为长音符 ー
+}
+
+// 配置项名称 (TEXT_NORMALIZE 环境变量，逗号分隔)
+const (
+	OptWidth     = "width"
+	OptIteration = "iteration"
+	OptVariant   = "variant"
+	OptLongVowel = "longvowel"
+)
+
+// All 开启全部规范化
+var All = Options{Width: true, IterationMarks: true, KanjiVariants: true, LongVowel: true}
+
+var current atomic.Pointer[Options]
+
+func init() {
+	opts := All
+	current.Store(&opts)
+}
+
+// SetDefault 设置全局默认选项 (词典索引与文章分析共用，需在加载词典前调用)
+func SetDefault(opts Options) {
+	current.Store(&opts)
+}
+
+// Default 当前全局默认选项
+func Default() Options {
+	return *current.Load()
+}
+
+// ParseOptions 解析配置字符串
+// 空字符串或 "all" 表示全部开启，"none" 表示全部关闭，否则按逗号分隔的名称开启
+func ParseOptions(spec string) Options {
+	spec = strings.TrimSpace(strings.ToLower(spec))
+	switch spec {
+	case "", "all":
+		return All
+	case "none":
+		return Options{}
+	}
+
+	var opts Options
+	for _, name := range strings.Split(spec, ",") {
+		switch strings.TrimSpace(name) {
+		case OptWidth:
+			opts.Width = true
+		case OptIteration:
+			opts.IterationMarks = true
+		case OptVariant:
+			opts.KanjiVariants = true
+		case OptLongVowel:
+			opts.LongVowel = true
+		}
+	}
+	return opts
+}
+
+// Result 规范化结果
+type Result struct {
+	Text  string
+	Runes []rune
+	// Offsets[i] 为规范化后第 i 个字符在原文中的起始 rune 下标，
+	// 末尾额外多一项等于原文长度，因此 [s, e) 对应原文 [Offsets[s], Offsets[e])
+	Offsets []int
+}
+
+// Span 将规范化文本中的区间映射回原文区间 (rune 下标)
+func (r Result) Span(start, end int) (int, int) {
+	return r.Offsets[start], r.Offsets[end]
+}
+
+// Normalize 使用全局默认选项规范化
+func Normalize(s string) Result {
+	return Default().Normalize(s)
+}
+
+// String 使用全局默认选项规范化，只返回文本
+func String(s string) string {
+	return Default().Normalize(s).Text
+}
+
+// Normalize 规范化文本并记录到原文的偏移映射
+func (o Options) Normalize(s string) Result {
+	src := []rune(s)
+	out := make([]rune, 0, len(src))
+	offsets := make([]int, 0, len(src)+1)
+
+	for i := 0; i < len(src); i++ {
+		r := src[i]
+		start := i
+
+		if o.Width {
+			if full, ok := halfKana[r]; ok {
+				r = full
+				// 半角浊点/半浊点与前一个假名合成为一个字符
+				if i+1 < len(src) {
+					if v, ok := combineMark(r, src[i+1]); ok {
+						r = v
+						i++
+					}
+				}
+			} else if r >= 0xFF01 && r <= 0xFF5E {
+				r -= 0xFEE0
+			}
+		}
+
+		if o.LongVowel && isLongVowelVariant(r) && prevIsKana(out) {
+			r = 'ー'
+		}
+
+		if o.IterationMarks && len(out) > 0 {
+			r = expandIteration(r, out[len(out)-1])
+		}
+
+		if o.KanjiVariants {
+			if v, ok := kanjiVariants[r]; ok {
+				r = v
+			}
+		}
+
+		out = append(out, r)
+		offsets = append(offsets, start)
+	}
+	offsets = append(offsets, len(src))
+
+	return Result{Text: string(out), Runes: out, Offsets: offsets}
+}
+
+func prevIsKana(out []rune) bool {
+	if len(out) == 0 {
+		return false
+	}
+	p := out[len(out)-1]
+	return (p >= 0x3041 && p <= 0x3096) || (p >= 0x30A1 && p <= 0x30FC)
+}
+
+func isLongVowelVariant(r rune) bool {
+	switch r {
+	case '-', '－', '‐', '‑', '―', '—', '─', '〜', '～', 'ｰ', '~':
+		return true
+	}
+	return false
+}
+
+// expandIteration 展开踊り字，prev 为前一个已规范化的字符
+func expandIteration(r, prev rune) rune {
+	switch r {
+	case '々':
+		if isKanji(prev) {
+			return prev
+		}
+	case 'ゝ':
+		if prev >= 0x3041 && prev <= 0x3096 {
+			return unvoiced(prev)
+		}
+	case 'ゞ':
+		if prev >= 0x3041 && prev <= 0x3096 {
+			if v, ok := voiced(unvoiced(prev)); ok {
+				return v
+			}
+		}
+	case 'ヽ':
+		if prev >= 0x30A1 && prev <= 0x30FA {
+			return unvoiced(prev)
+		}
+	case 'ヾ':
+		if prev >= 0x30A1 && prev <= 0x30FA {
+			if v, ok := voiced(unvoiced(prev)); ok {
+				return v
+			}
+		}
+	}
+	return r
+}
+
+func isKanji(r rune) bool {
+	return (r >= 0x4E00 && r <= 0x9FFF) || (r >= 0x3400 && r <= 0x4DBF) || (r >= 0xF900 && r <= 0xFAFF)
+}
@@ -0,0 +1,55 @@
+package textnorm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{"ＡＢＣ１２３", "ABC123"},
+		{"ｶﾞｯｺｳ", "ガッコウ"},
+		{"ﾊﾟﾝ", "パン"},
+		{"人々", "人人"},
+		{"いすゞ", "いすず"},
+		{"こゝろ", "こころ"},
+		{"國語", "国語"},
+		{"ラーメン", "ラーメン"},
+		{"ラ－メン", "ラーメン"},
+		{"すご〜い", "すごーい"},
+		{"-1", "-1"},
+	}
+	for _, tc := range cases {
+		if got := All.Normalize(tc.in).Text; got != tc.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestNormalizeOffsets(t *testing.T) {
+	// "ｶﾞ" 两个原文字符合成为一个 "ガ"
+	res := All.Normalize("ｶﾞｯｺｳに行く")
+	if res.Text != "ガッコウに行く" {
+		t.Fatalf("Text = %q", res.Text)
+	}
+	if want := []int{0, 2, 3, 4, 5, 6, 7, 8}; !reflect.DeepEqual(res.Offsets, want) {
+		t.Errorf("Offsets = %v, want %v", res.Offsets, want)
+	}
+	if s, e := res.Span(0, 4); s != 0 || e != 5 {
+		t.Errorf("Span(0,4) = %d,%d want 0,5", s, e)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	if got := ParseOptions(""); got != All {
+		t.Errorf("空配置应全部开启: %+v", got)
+	}
+	if got := ParseOptions("none"); got != (Options{}) {
+		t.Errorf("none 应全部关闭: %+v", got)
+	}
+	if got := ParseOptions("width, variant"); got != (Options{Width: true, KanjiVariants: true}) {
+		t.Errorf("ParseOptions(width, variant) = %+v", got)
+	}
+}
@@ -0,0 +1,131 @@
+package usage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"dongwai_backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// 报表可用的分组维度
+const (
+	GroupDay     = "day"
+	GroupUser    = "user"
+	GroupFeature = "feature"
+)
+
+var groupColumns = map[string]string{
+	GroupDay:     "TO_CHAR(ai_usages.created_at, 'YYYY-MM-DD')",
+	GroupUser:    "ai_usages.user_id",
+	GroupFeature: "ai_usages.feature",
+}
+
+// Pricing 每百万 token 的价格 (用于估算费用)
+type Pricing struct {
+	PromptPerM     float64
+	CompletionPerM float64
+}
+
+// Cost 估算费用
+func (p Pricing) Cost(promptTokens, completionTokens int64) float64 {
+	return (float64(promptTokens)*p.PromptPerM + float64(completionTokens)*p.CompletionPerM) / 1e6
+}
+
+// ReportQuery 报表查询条件
+type ReportQuery struct {
+	From    time.Time // 包含
+	To      time.Time // 不包含，零值表示至今
+	GroupBy []string  // day / user / feature 的任意组合，为空时按天
+	UserID  string    // 只看某个用户
+	Feature string    // 只看某个功能
+}
+
+// ReportRow 一行汇总
+type ReportRow struct {
+	Day              string  `json:"day,omitempty"`
+	UserID           string  `json:"user_id,omitempty"`
+	Username         string  `json:"username,omitempty"`
+	Feature          string  `json:"feature,omitempty"`
+	Calls            int64   `json:"calls"`
+	Errors           int64   `json:"errors"`
+	Rejected         int64   `json:"rejected"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+	Cost             float64 `json:"cost"`
+}
+
+// ParseGroupBy 解析逗号分隔的分组维度
+func ParseGroupBy(spec string) ([]string, error) {
+	var groups []string
+	for _, g := range strings.Split(spec, ",") {
+		g = strings.TrimSpace(g)
+		if g == "" {
+			continue
+		}
+		if _, ok := groupColumns[g]; !ok {
+			return nil, fmt.Errorf("不支持的分组维度: %s", g)
+		}
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+// Report 按维度汇总用量
+func Report(db *gorm.DB, q ReportQuery, pricing Pricing) ([]ReportRow, error) {
+	if len(q.GroupBy) == 0 {
+		q.GroupBy = []string{GroupDay}
+	}
+
+	selects := []string{
+		"COUNT(*) AS calls",
+		"SUM(CASE WHEN ai_usages.outcome = 'error' THEN 1 ELSE 0 END) AS errors",
+		"SUM(CASE WHEN ai_usages.outcome = 'rejected' THEN 1 ELSE 0 END) AS rejected",
+		"COALESCE(SUM(ai_usages.prompt_tokens), 0) AS prompt_tokens",
+		"COALESCE(SUM(ai_usages.completion_tokens), 0) AS completion_tokens",
+		"COALESCE(SUM(ai_usages.total_tokens), 0) AS total_tokens",
+		"COALESCE(AVG(ai_usages.latency_ms), 0) AS avg_latency_ms",
+	}
+	var groups []string
+	tx := db.Model(&model.AIUsage{})
+	for _, g := range q.GroupBy {
+		col, ok := groupColumns[g]
+		if !ok {
+			return nil, fmt.Errorf("不支持的分组维度: %s", g)
+		}
+		groups = append(groups, col)
+		switch g {
+		case GroupDay:
+			selects = append(selects, col+" AS day")
+		case GroupUser:
+			selects = append(selects, col+" AS user_id", "MAX(user_roles.username) AS username")
+			tx = tx.Joins("LEFT JOIN user_roles ON user_roles.id = ai_usages.user_id")
+		case GroupFeature:
+			selects = append(selects, col+" AS feature")
+		}
+	}
+
+	tx = tx.Select(strings.Join(selects, ", ")).Where("ai_usages.created_at >= ?", q.From)
+	if !q.To.IsZero() {
+		tx = tx.Where("ai_usages.created_at < ?", q.To)
+	}
+	if q.UserID != "" {
+		tx = tx.Where("ai_usages.user_id = ?", q.UserID)
+	}
+	if q.Feature != "" {
+		tx = tx.Where("ai_usages.feature = ?", q.Feature)
+	}
+
+	var rows []ReportRow
+	if err := tx.Group(strings.Join(groups, ", ")).Order(strings.Join(groups, ", ")).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].Cost = pricing.Cost(rows[i].PromptTokens, rows[i].CompletionTokens)
+	}
+	return rows, nil
+}
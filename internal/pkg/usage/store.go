@@ -0,0 +1,81 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"dongwai_backend/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DBStore 基于数据库的用量存储
+type DBStore struct {
+	db *gorm.DB
+}
+
+// NewDBStore 创建数据库存储 (表结构需已迁移)
+func NewDBStore(db *gorm.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+func (s *DBStore) Record(ctx context.Context, rec model.AIUsage) error {
+	if err := s.db.WithContext(ctx).Create(&rec).Error; err != nil {
+		log.Printf("写入 AI 用量记录失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Reserve 先确保当日计数行存在，再用条件 UPDATE 预留：并发的预留在同一行上排队，
+// 每个都基于前一个提交后的值判断，不会一起越过配额
+func (s *DBStore) Reserve(ctx context.Context, userID, day string, tokens, quota int64) (bool, error) {
+	db := s.db.WithContext(ctx)
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.AIDailyUsage{UserID: userID, Day: day}).Error
+	if err != nil {
+		return false, err
+	}
+	result := db.Model(&model.AIDailyUsage{}).
+		Where("user_id = ? AND day = ? AND tokens + ? <= ?", userID, day, tokens, quota).
+		Update("tokens", gorm.Expr("tokens + ?", tokens))
+	return result.RowsAffected == 1, result.Error
+}
+
+func (s *DBStore) Adjust(ctx context.Context, userID, day string, delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"tokens": gorm.Expr("ai_daily_usages.tokens + excluded.tokens")}),
+	}).Create(&model.AIDailyUsage{UserID: userID, Day: day, Tokens: delta}).Error
+}
+
+func (s *DBStore) Quota(ctx context.Context, userID string) (int64, bool, error) {
+	var q model.AIQuota
+	err := s.db.WithContext(ctx).First(&q, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return q.DailyTokens, true, nil
+}
+
+// SetQuota 设置用户的每日配额 (0 表示不限)
+func SetQuota(db *gorm.DB, userID string, dailyTokens int64) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"daily_tokens", "updated_at"}),
+	}).Create(&model.AIQuota{UserID: userID, DailyTokens: dailyTokens, UpdatedAt: time.Now()}).Error
+}
+
+// ClearQuota 删除用户的单独配额，恢复使用全局默认值
+func ClearQuota(db *gorm.DB, userID string) error {
+	return db.Where("user_id = ?", userID).Delete(&model.AIQuota{}).Error
+}
@@ -0,0 +1,153 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/ai"
+
+	"github.com/google/uuid"
+)
+
+// 调用结果
+const (
+	OutcomeOK       = "ok"
+	OutcomeError    = "error"
+	OutcomeRejected = "rejected" // 超出配额，未发出请求
+)
+
+// ErrQuotaExceeded 用户当日 token 用量已达配额 (与 ai.ErrQuotaExceeded 相同，重试与熔断层据此识别)
+var ErrQuotaExceeded = ai.ErrQuotaExceeded
+
+type userKey struct{}
+
+// WithUser 在 ctx 中记录发起调用的用户 (JWT 中的用户 ID，匿名访问用 AnonymousUser)
+func WithUser(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+// AnonymousUser 匿名访问按客户端 IP 分别记账与限额
+func AnonymousUser(ip string) string {
+	return "ip:" + ip
+}
+
+// UserFrom 取出 ctx 中的用户 ID
+func UserFrom(ctx context.Context) string {
+	id, _ := ctx.Value(userKey{}).(string)
+	return id
+}
+
+// Store 用量记录与配额的存储
+type Store interface {
+	Record(ctx context.Context, rec model.AIUsage) error
+	// Quota 用户的每日配额，ok 为 false 表示没有单独设置
+	Quota(ctx context.Context, userID string) (int64, bool, error)
+	// Reserve 原子地检查并预留：用户 day 当天的用量加上 tokens 不超过 quota 时计入并返回 true
+	Reserve(ctx context.Context, userID, day string, tokens, quota int64) (bool, error)
+	// Adjust 修正用户 day 当天的用量 (实际用量与预留之差，可为负)
+	Adjust(ctx context.Context, userID, day string, delta int64) error
+}
+
+// completionReserve 预留配额时为模型输出估计的 token 数
+const completionReserve = 1024
+
+// Meter 记录每次调用的用量，并在调用前检查用户的每日配额
+type Meter struct {
+	next         ai.Provider
+	store        Store
+	defaultQuota int64 // 未单独设置配额的用户使用该值，0 表示不限
+
+	now func() time.Time
+}
+
+// NewMeter 包装一个 Provider
+func NewMeter(next ai.Provider, store Store, defaultQuota int64) *Meter {
+	return &Meter{next: next, store: store, defaultQuota: defaultQuota, now: time.Now}
+}
+
+func (m *Meter) Chat(ctx context.Context, req ai.ChatRequest) (*ai.ChatResponse, error) {
+	userID := UserFrom(ctx)
+	rec := model.AIUsage{Feature: req.Feature, UserID: userID, PromptVersion: req.PromptVersion}
+	day := m.now().Format(time.DateOnly)
+
+	reserved, err := m.reserve(ctx, userID, day, req)
+	if err != nil {
+		if errors.Is(err, ErrQuotaExceeded) {
+			rec.Outcome = OutcomeRejected
+			rec.Error = err.Error()
+			m.record(ctx, rec)
+		}
+		return nil, err
+	}
+
+	start := m.now()
+	resp, err := m.next.Chat(ctx, req)
+	rec.LatencyMs = m.now().Sub(start).Milliseconds()
+	if err != nil {
+		rec.Outcome = OutcomeError
+		rec.Error = err.Error()
+	} else {
+		rec.Outcome = OutcomeOK
+		rec.Model = resp.Model
+		rec.PromptTokens = resp.Usage.PromptTokens
+		rec.CompletionTokens = resp.Usage.CompletionTokens
+		rec.TotalTokens = resp.Usage.TotalTokens
+		if rec.TotalTokens == 0 {
+			rec.TotalTokens = rec.PromptTokens + rec.CompletionTokens
+		}
+	}
+	m.record(ctx, rec)
+
+	// 用实际用量替换预留 (不限额的用户也计入，之后设置配额时当天的用量仍然准确)
+	if err := m.store.Adjust(context.WithoutCancel(ctx), userID, day, int64(rec.TotalTokens)-reserved); err != nil {
+		log.Printf("修正 AI 用量失败: %v", err)
+	}
+	return resp, err
+}
+
+// reserve 按估计用量预留配额，当日用量加上预留会超过配额时拒绝
+// 检查与计入是原子的，同一用户的并发调用 (如分块消歧) 不会一起越过配额
+// 返回预留的 token 数 (不限额时为 0)
+func (m *Meter) reserve(ctx context.Context, userID, day string, req ai.ChatRequest) (int64, error) {
+	quota, ok, err := m.store.Quota(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		quota = m.defaultQuota
+	}
+	if quota <= 0 {
+		return 0, nil
+	}
+
+	// 单次估计超过整个配额时按配额预留：当天还没用过才放行
+	tokens := min(estimateTokens(req), quota)
+	ok, err = m.store.Reserve(ctx, userID, day, tokens, quota)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrQuotaExceeded
+	}
+	return tokens, nil
+}
+
+// estimateTokens 粗略估计一次调用的 token 数：提示词按每个字符一个 token (偏保守)，加上为输出预留的部分
+func estimateTokens(req ai.ChatRequest) int64 {
+	var n int
+	for _, msg := range req.Messages {
+		n += utf8.RuneCountInString(msg.Content)
+	}
+	return int64(n) + completionReserve
+}
+
+// record 写入用量记录；调用方取消不影响记账
+func (m *Meter) record(ctx context.Context, rec model.AIUsage) {
+	rec.ID = uuid.New().String()
+	rec.CreatedAt = m.now()
+	_ = m.store.Record(context.WithoutCancel(ctx), rec)
+}
@@ -0,0 +1,169 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"dongwai_backend/internal/model"
+	"dongwai_backend/internal/pkg/ai"
+)
+
+type memStore struct {
+	mu      sync.Mutex
+	records []model.AIUsage
+	quotas  map[string]int64
+	used    map[string]int64 // user_id|day -> tokens
+}
+
+func (s *memStore) Record(_ context.Context, rec model.AIUsage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, rec)
+	return nil
+}
+
+func (s *memStore) Quota(_ context.Context, userID string) (int64, bool, error) {
+	q, ok := s.quotas[userID]
+	return q, ok, nil
+}
+
+func (s *memStore) Reserve(_ context.Context, userID, day string, tokens, quota int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.used[userID+"|"+day]+tokens > quota {
+		return false, nil
+	}
+	s.used[userID+"|"+day] += tokens
+	return true, nil
+}
+
+func (s *memStore) Adjust(_ context.Context, userID, day string, delta int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.used[userID+"|"+day] += delta
+	return nil
+}
+
+func newMemStore(quotas map[string]int64) *memStore {
+	return &memStore{quotas: quotas, used: map[string]int64{}}
+}
+
+// gateProvider 在 release 关闭前阻塞所有调用，模拟并发中的请求
+type gateProvider struct {
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (p *gateProvider) Chat(ctx context.Context, _ ai.ChatRequest) (*ai.ChatResponse, error) {
+	p.calls.Add(1)
+	<-p.release
+	return &ai.ChatResponse{Content: "{}", Usage: ai.Usage{TotalTokens: 700}}, nil
+}
+
+func TestMeterRecordsAndEnforcesQuota(t *testing.T) {
+	store := newMemStore(map[string]int64{"vip": 0})
+	fake := ai.NewFake(
+		ai.FakeReply{Content: "{}", Usage: ai.Usage{PromptTokens: 60, CompletionTokens: 40, TotalTokens: 100}},
+		ai.FakeReply{Err: errors.New("boom")},
+		ai.FakeReply{Content: "{}", Usage: ai.Usage{PromptTokens: 500, CompletionTokens: 500}},
+	)
+	m := NewMeter(fake, store, 100)
+
+	ctx := WithUser(context.Background(), "u1")
+	if _, err := m.Chat(ctx, ai.ChatRequest{Feature: ai.FeatureGenerateWord}); err != nil {
+		t.Fatal(err)
+	}
+
+	// u1 已用满默认配额 100，请求不会发出
+	if _, err := m.Chat(ctx, ai.ChatRequest{Feature: ai.FeatureGenerateWord}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("err = %v, want ErrQuotaExceeded", err)
+	}
+	if n := len(fake.Requests()); n != 1 {
+		t.Fatalf("provider calls = %d, want 1", n)
+	}
+
+	// 单独设置为不限的用户不受影响
+	vip := WithUser(context.Background(), "vip")
+	m.Chat(vip, ai.ChatRequest{Feature: ai.FeatureDisambiguate})
+	m.Chat(vip, ai.ChatRequest{Feature: ai.FeatureDisambiguate})
+
+	want := []struct {
+		user, outcome string
+		tokens        int
+	}{
+		{"u1", OutcomeOK, 100},
+		{"u1", OutcomeRejected, 0},
+		{"vip", OutcomeError, 0},
+		{"vip", OutcomeOK, 1000}, // 未返回 total_tokens 时按输入 + 输出计算
+	}
+	if len(store.records) != len(want) {
+		t.Fatalf("records = %+v", store.records)
+	}
+	for i, w := range want {
+		r := store.records[i]
+		if r.UserID != w.user || r.Outcome != w.outcome || r.TotalTokens != w.tokens || r.ID == "" {
+			t.Errorf("record %d = %+v, want %+v", i, r, w)
+		}
+	}
+}
+
+func TestMeterReservesAtomically(t *testing.T) {
+	store := newMemStore(nil)
+	gate := &gateProvider{release: make(chan struct{})}
+	// 每次预留 1024 (无提示词时只有输出预留)，配额只够同时进行两次
+	m := NewMeter(gate, store, 3000)
+	ctx := WithUser(context.Background(), AnonymousUser("203.0.113.7"))
+
+	var wg sync.WaitGroup
+	var rejected atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := m.Chat(ctx, ai.ChatRequest{Feature: ai.FeatureDisambiguate}); errors.Is(err, ErrQuotaExceeded) {
+				rejected.Add(1)
+			}
+		}()
+	}
+	// 被拒绝的调用不等待 gate，等它们全部返回后再放行
+	for rejected.Load() < 8 {
+		time.Sleep(time.Millisecond)
+	}
+	close(gate.release)
+	wg.Wait()
+
+	if n := gate.calls.Load(); n != 2 {
+		t.Fatalf("provider calls = %d, want 2", n)
+	}
+	// 预留被实际用量替换
+	day := time.Now().Format(time.DateOnly)
+	if used := store.used[AnonymousUser("203.0.113.7")+"|"+day]; used != 1400 {
+		t.Errorf("used = %d, want 1400", used)
+	}
+	// 其他 IP 的匿名用户不受影响
+	other := WithUser(context.Background(), AnonymousUser("198.51.100.1"))
+	gate.release = make(chan struct{})
+	close(gate.release)
+	if _, err := m.Chat(other, ai.ChatRequest{Feature: ai.FeatureDisambiguate}); err != nil {
+		t.Errorf("other client: %v", err)
+	}
+}
+
+func TestMeterReleasesReservationOnError(t *testing.T) {
+	store := newMemStore(nil)
+	m := NewMeter(ai.NewFake(ai.FakeReply{Err: errors.New("boom")}), store, 5000)
+	ctx := WithUser(context.Background(), "u1")
+	req := ai.ChatRequest{Feature: ai.FeatureGenerateWord, Messages: []ai.Message{{Role: "user", Content: "猫について"}}}
+	if got := estimateTokens(req); got != 5+completionReserve {
+		t.Errorf("estimate = %d", got)
+	}
+
+	m.Chat(ctx, req)
+	if used := store.used["u1|"+time.Now().Format(time.DateOnly)]; used != 0 {
+		t.Errorf("used = %d after failed call, want 0", used)
+	}
+}